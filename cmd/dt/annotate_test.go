@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
 
 	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
@@ -81,6 +83,26 @@ func (suite *CmdSuite) TestAnnotateCommand() {
 			tu.AssertChartAnnotations(t, chartDir, key, expectedImages)
 		})
 	}
+	t.Run("Logs JSON events", func(t *testing.T) {
+		chartDir := sb.TempFile()
+
+		require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+			map[string]interface{}{"ServerURL": serverURL, "ValuesImages": images},
+		))
+		res := dt("--log-format", "json", "charts", "annotate", chartDir)
+		res.AssertSuccess(t)
+
+		types := make([]string, 0)
+		for _, line := range strings.Split(strings.TrimSpace(res.stdout), "\n") {
+			var event struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			}
+			require.NoError(json.Unmarshal([]byte(line), &event), "invalid JSON line %q", line)
+			types = append(types, event.Type+":"+event.Status)
+		}
+		assert.Equal([]string{"step_start:", "step_end:success", "log:success"}, types)
+	})
 	t.Run("Corner cases", func(t *testing.T) {
 		t.Run("Handle empty image list case", func(t *testing.T) {
 			chartDir := sb.TempFile()
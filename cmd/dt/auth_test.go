@@ -1,11 +1,17 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
 
 	"helm.sh/helm/v3/pkg/repo/repotest"
 )
@@ -37,4 +43,68 @@ func TestLoginLogout(t *testing.T) {
 		_, err = crane.Catalog(ociSrv.RegistryURL)
 		require.ErrorContains(t, err, "UNAUTHORIZED")
 	})
+
+	t.Run("can login with a password file", func(t *testing.T) {
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("password\n"), 0600))
+		dt("auth", "login", ociSrv.RegistryURL, "-u", "username", "--password-file", passwordFile).AssertSuccessMatch(t, "logged in via")
+		_, err := crane.Catalog(ociSrv.RegistryURL)
+		require.NoError(t, err)
+
+		dt("auth", "logout", ociSrv.RegistryURL).AssertSuccessMatch(t, "logged out via")
+	})
+
+	t.Run("can login with credentials from the environment", func(t *testing.T) {
+		t.Setenv("DT_USERNAME", "username")
+		t.Setenv("DT_PASSWORD", "password")
+		dt("auth", "login", ociSrv.RegistryURL).AssertSuccessMatch(t, "logged in via")
+		_, err := crane.Catalog(ociSrv.RegistryURL)
+		require.NoError(t, err)
+
+		dt("auth", "logout", ociSrv.RegistryURL).AssertSuccessMatch(t, "logged out via")
+	})
+
+	t.Run("prefers a password file over the password in the environment", func(t *testing.T) {
+		t.Setenv("DT_PASSWORD", "wrong-password")
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("password\n"), 0600))
+		dt("auth", "login", ociSrv.RegistryURL, "-u", "username", "--password-file", passwordFile).AssertSuccessMatch(t, "logged in via")
+		_, err := crane.Catalog(ociSrv.RegistryURL)
+		require.NoError(t, err)
+
+		dt("auth", "logout", ociSrv.RegistryURL).AssertSuccessMatch(t, "logged out via")
+	})
+
+	t.Run("can pull and push charts with a credentials file", func(t *testing.T) {
+		dir := t.TempDir()
+		credentialsFile := filepath.Join(dir, "credentials.yaml")
+		require.NoError(t, os.WriteFile(credentialsFile, []byte(fmt.Sprintf(
+			"credentials:\n  - registry: %q\n    username: username\n    password: password\n", ociSrv.RegistryURL,
+		)), 0600))
+		chartDir := filepath.Join(dir, "chart")
+		require.NoError(t, testutil.RenderScenario("../../testdata/scenarios/no-images-chart", chartDir,
+			map[string]interface{}{"Name": "test", "Version": "1.0.0"},
+		))
+		chartFile := filepath.Join(dir, "test-1.0.0.tgz")
+		require.NoError(t, utils.Tar(chartDir, chartFile, utils.TarConfig{Prefix: "test"}))
+		chartsURL := fmt.Sprintf("oci://%s/charts", ociSrv.RegistryURL)
+
+		require.Error(t, artifacts.PushChart(chartFile, chartsURL, artifacts.WithPlainHTTP(true)))
+		require.NoError(t, artifacts.PushChart(chartFile, chartsURL,
+			artifacts.WithPlainHTTP(true), artifacts.WithRegistryCredentialsFile(credentialsFile),
+		))
+
+		wrapFile := filepath.Join(dir, "test.wrap.tgz")
+		dt("wrap", chartsURL+"/test", "--version", "1.0.0", "--use-plain-http", "--output-file", wrapFile).AssertError(t)
+		dt("wrap", chartsURL+"/test", "--version", "1.0.0", "--use-plain-http", "--output-file", wrapFile,
+			"--credentials-file", credentialsFile,
+		).AssertSuccess(t)
+
+		dt("unwrap", wrapFile, ociSrv.RegistryURL+"/unwrapped", "--yes", "--use-plain-http",
+			"--push-chart-url", chartsURL+"/unwrapped", "--credentials-file", credentialsFile,
+		).AssertSuccess(t)
+		assert.True(t, artifacts.RemoteChartExist(chartsURL+"/unwrapped/test", "1.0.0",
+			artifacts.WithPlainHTTP(true), artifacts.WithRegistryCredentialsFile(credentialsFile),
+		))
+	})
 }
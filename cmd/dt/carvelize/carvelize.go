@@ -59,6 +59,9 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 					func() error {
 						return lock.Create(chartPath,
 							lockFile, silentLog, imagelock.WithAnnotationsKey(cfg.AnnotationsKey), imagelock.WithInsecure(cfg.Insecure),
+							imagelock.WithCredentialsFile(cfg.CredentialsFile),
+							imagelock.WithRegistriesFile(cfg.RegistriesFile),
+							imagelock.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 						)
 					},
 				)
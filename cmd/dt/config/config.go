@@ -10,12 +10,21 @@ import (
 	"syscall"
 
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+	jl "github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/json"
 	ll "github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 
 	pl "github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/pterm"
 )
 
+const (
+	// TextLogFormat logs human readable messages
+	TextLogFormat = "text"
+	// JSONLogFormat logs one JSON event per line
+	JSONLogFormat = "json"
+)
+
 // Config defines the configuration of the dt tool
 type Config struct {
 	Insecure       bool
@@ -24,9 +33,23 @@ type Config struct {
 	AnnotationsKey string
 	TempDirectory  string
 	UsePlainHTTP   bool
+	// CredentialsFile is the per-registry credentials file used to access container registries
+	CredentialsFile string
+	// RegistriesFile defines the mirrors to pull images from
+	RegistriesFile string
+	// TLS defines the certificate authorities and client certificate used to access registries
+	TLS registries.TLS
+	// ConfigFile is the configuration file defining the profiles
+	ConfigFile string
+	// Profile is the profile of the configuration file applied to the flags not explicitly set
+	Profile string
 
 	LogLevel    string
 	UsePlainLog bool
+	// LogFormat is the format of the log messages: text or json
+	LogFormat string
+	// TelemetryFile is the file the traces and metrics are written to, instead of exporting them
+	TelemetryFile string
 }
 
 // NewConfig returns a new Config
@@ -36,6 +59,7 @@ func NewConfig() *Config {
 		AnnotationsKey: imagelock.DefaultAnnotationsKey,
 		LogLevel:       "info",
 		UsePlainLog:    false,
+		LogFormat:      TextLogFormat,
 	}
 }
 
@@ -44,11 +68,17 @@ func (c *Config) Logger() dtlog.SectionLogger {
 	if c.logger == nil {
 
 		var l dtlog.SectionLogger
-		if c.UsePlainLog {
+		switch {
+		case c.LogFormat == JSONLogFormat:
+			l = jl.NewSectionLogger()
+		case c.UsePlainLog:
 			l = ll.NewSectionLogger()
-		} else {
+		default:
 			l = pl.NewSectionLogger()
 		}
+		if c.LogFormat != TextLogFormat && c.LogFormat != JSONLogFormat {
+			l.Warnf("Invalid log format %s: expected %s or %s", c.LogFormat, TextLogFormat, JSONLogFormat)
+		}
 		lvl, err := dtlog.ParseLevel(c.LogLevel)
 
 		if err != nil {
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// EnvPrefix is the prefix of the environment variables providing the values of the flags
+const EnvPrefix = "DT_"
+
+// EnvVarName returns the name of the environment variable bound to the flag name, e.g. DT_LOG_LEVEL for --log-level
+func EnvVarName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// bindable returns true if the flag can be set from the environment
+func bindable(f *pflag.Flag) bool {
+	return f.Name != "help"
+}
+
+// mutuallyExclusiveAnnotation is the annotation cobra stores the mutually exclusive groups of a flag in
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// excludedByFlags returns true if f belongs to a mutually exclusive group with any of the set flags
+func excludedByFlags(f *pflag.Flag, set map[string]bool) bool {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Split(group, " ") {
+			if set[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ApplyEnv sets the flags not explicitly set to the values of their environment variables, if defined.
+// Flags set this way are considered explicitly set, so they take precedence over the profile settings.
+// The environment is ignored for flags mutually exclusive with any flag explicitly set, e.g. DT_PASSWORD
+// is not used when --password-file is provided
+func ApplyEnv(flags *pflag.FlagSet) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *pflag.Flag) {
+		explicit[f.Name] = true
+	})
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || !bindable(f) || excludedByFlags(f, explicit) {
+			return
+		}
+		name := EnvVarName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value for %s: %w", name, setErr)
+		}
+	})
+	return err
+}
+
+// DocumentEnv appends the name of their environment variable to the usage of the flags of cmd and its subcommands
+func DocumentEnv(cmd *cobra.Command) {
+	documented := make(map[*pflag.Flag]bool)
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, flags := range []*pflag.FlagSet{c.PersistentFlags(), c.Flags()} {
+			flags.VisitAll(func(f *pflag.Flag) {
+				if documented[f] || !bindable(f) {
+					return
+				}
+				documented[f] = true
+				f.Usage = fmt.Sprintf("%s [$%s]", f.Usage, EnvVarName(f.Name))
+			})
+		}
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+}
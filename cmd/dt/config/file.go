@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// File defines the dt configuration file
+type File struct {
+	// Profile is the profile applied when none is selected with the --profile flag
+	Profile string `yaml:"profile,omitempty"`
+	// Profiles maps the profile names to their settings
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile maps flag names to the values applied when the flags are not explicitly set
+type Profile map[string]interface{}
+
+// DefaultConfigFile returns the path of the default configuration file, $XDG_CONFIG_HOME/dt/config.yaml
+func DefaultConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "dt", "config.yaml")
+}
+
+// LoadFile reads the configuration file filename
+func LoadFile(filename string) (*File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	f := &File{}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file %q: %w", filename, err)
+	}
+	return f, nil
+}
+
+// Apply sets the flags not explicitly set to the values of the profile. Settings of flags not
+// defined in flags are ignored, as a profile is shared by all the commands
+func (p Profile) Apply(flags *pflag.FlagSet) error {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := flags.Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+		if err := setFlag(f, p[name]); err != nil {
+			return fmt.Errorf("invalid value for %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setFlag sets the flag f to the value v, a scalar or a list for the flags accepting multiple values
+func setFlag(f *pflag.Flag, v interface{}) error {
+	list, isList := v.([]interface{})
+	if !isList {
+		if v == nil {
+			return nil
+		}
+		return f.Value.Set(fmt.Sprint(v))
+	}
+	sv, ok := f.Value.(pflag.SliceValue)
+	if !ok {
+		return fmt.Errorf("the flag does not accept a list")
+	}
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, fmt.Sprint(item))
+	}
+	return sv.Replace(values)
+}
+
+// ApplyProfile sets the flags not explicitly set to the values of the selected profile, if any, of
+// the configuration file, which defaults to DefaultConfigFile when it exists
+func (c *Config) ApplyProfile(flags *pflag.FlagSet) error {
+	filename := c.ConfigFile
+	if filename == "" {
+		filename = DefaultConfigFile()
+		if _, err := os.Stat(filename); err != nil {
+			filename = ""
+		}
+	}
+	if filename == "" {
+		if c.Profile != "" {
+			return fmt.Errorf("cannot use profile %q: no configuration file found", c.Profile)
+		}
+		return nil
+	}
+	f, err := LoadFile(filename)
+	if err != nil {
+		return err
+	}
+	name := c.Profile
+	if name == "" {
+		name = f.Profile
+	}
+	if name == "" {
+		return nil
+	}
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found in configuration file %q", name, filename)
+	}
+	return profile.Apply(flags)
+}
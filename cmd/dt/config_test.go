@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
+)
+
+func (suite *CmdSuite) TestConfigProfilesAndEnvironment() {
+	sb := suite.sb
+	t := suite.T()
+	require := suite.Require()
+
+	scenarioDir := "../../testdata/scenarios/plain-chart"
+	images := []testImage{
+		{
+			Name:       "wordpress",
+			Registry:   "docker.io",
+			Repository: "bitnami/wordpress",
+			Tag:        "latest",
+		},
+	}
+	expectedImages := []tu.AnnotationEntry{{Name: "wordpress", Image: images[0].URL()}}
+
+	configFile := filepath.Join(sb.TempFile(), "config.yaml")
+	require.NoError(os.MkdirAll(filepath.Dir(configFile), 0755))
+	require.NoError(os.WriteFile(configFile, []byte(`
+profiles:
+  artifacthub:
+    annotations-key: artifacthub.io/images
+    log-level: debug
+  broken:
+    annotations-key: [a, b]
+`), 0644))
+
+	renderChart := func() string {
+		chartDir := sb.TempFile()
+		require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+			map[string]interface{}{"ServerURL": "localhost", "ValuesImages": images},
+		))
+		return chartDir
+	}
+
+	t.Run("Applies the profile settings", func(t *testing.T) {
+		chartDir := renderChart()
+		dt("--config", configFile, "--profile", "artifacthub", "charts", "annotate", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "artifacthub.io/images", expectedImages)
+	})
+	t.Run("Flags take precedence over the profile", func(t *testing.T) {
+		chartDir := renderChart()
+		dt("--config", configFile, "--profile", "artifacthub", "charts", "annotate", "--annotations-key", "custom/images", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "custom/images", expectedImages)
+	})
+	t.Run("Applies the default profile of the configuration file", func(t *testing.T) {
+		defaultConfigFile := filepath.Join(sb.TempFile(), "config.yaml")
+		require.NoError(os.MkdirAll(filepath.Dir(defaultConfigFile), 0755))
+		require.NoError(os.WriteFile(defaultConfigFile, []byte(`
+profile: artifacthub
+profiles:
+  artifacthub:
+    annotations-key: artifacthub.io/images
+`), 0644))
+		chartDir := renderChart()
+		dt("--config", defaultConfigFile, "charts", "annotate", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "artifacthub.io/images", expectedImages)
+	})
+	t.Run("Applies the environment variables", func(t *testing.T) {
+		t.Setenv("DT_ANNOTATIONS_KEY", "env/images")
+
+		chartDir := renderChart()
+		dt("charts", "annotate", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "env/images", expectedImages)
+
+		// The environment takes precedence over the profile
+		chartDir = renderChart()
+		dt("--config", configFile, "--profile", "artifacthub", "charts", "annotate", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "env/images", expectedImages)
+
+		// Flags take precedence over the environment
+		chartDir = renderChart()
+		dt("charts", "annotate", "--annotations-key", "custom/images", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "custom/images", expectedImages)
+	})
+	t.Run("Selects the profile from the environment", func(t *testing.T) {
+		t.Setenv("DT_PROFILE", "artifacthub")
+
+		chartDir := renderChart()
+		dt("--config", configFile, "charts", "annotate", chartDir).AssertSuccess(t)
+		tu.AssertChartAnnotations(t, chartDir, "artifacthub.io/images", expectedImages)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		chartDir := renderChart()
+		dt("--config", configFile, "--profile", "missing", "charts", "annotate", chartDir).AssertErrorMatch(t, regexp.MustCompile(`profile "missing" not found`))
+		dt("--config", configFile, "--profile", "broken", "charts", "annotate", chartDir).AssertErrorMatch(t, regexp.MustCompile(`invalid value for "annotations-key"`))
+		dt("--config", filepath.Join(sb.TempFile(), "missing.yaml"), "charts", "annotate", chartDir).AssertErrorMatch(t, regexp.MustCompile(`failed to read configuration file`))
+
+		t.Setenv("DT_INSECURE", "maybe")
+		dt("charts", "annotate", chartDir).AssertErrorMatch(t, regexp.MustCompile(`invalid value for DT_INSECURE`))
+	})
+}
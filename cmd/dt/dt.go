@@ -12,7 +12,10 @@ func main() {
 	// Make sure we clean up after ourselves
 	defer config.CleanGlobalTempWorkDir()
 
-	if err := rootCmd.Execute(); err != nil {
+	config.DocumentEnv(rootCmd)
+	err := rootCmd.Execute()
+	stopTelemetry(err)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
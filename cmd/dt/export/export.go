@@ -0,0 +1,145 @@
+// Package export implements the dt images export command
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/config"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/wrap"
+	"github.com/vmware-labs/distribution-tooling-for-helm/internal/widgets"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/silent"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/relocator"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/wrapping"
+)
+
+// loadBundle loads the wrap extracted at dir, whatever its kind
+func loadBundle(dir string) (wrapping.Bundle, bool, error) {
+	switch {
+	case wrapping.IsMultiWrap(dir):
+		w, err := wrapping.LoadMulti(dir)
+		return w, true, err
+	case utils.FileExists(filepath.Join(dir, "chart")):
+		w, err := wrapping.Load(dir)
+		return w, true, err
+	default:
+		w, err := wrapping.LoadContainer(dir)
+		return w, false, err
+	}
+}
+
+// archiveName returns the base name of the archive for platform, such as mariadb-12.2.8-linux-amd64
+func archiveName(name, version, platform string) string {
+	baseName := name
+	if version != "" {
+		baseName = fmt.Sprintf("%s-%s", name, version)
+	}
+	return fmt.Sprintf("%s-%s", baseName, strings.NewReplacer("/", "-", ":", "-").Replace(platform))
+}
+
+// NewCmd builds a new export command
+func NewCmd(cfg *config.Config) *cobra.Command {
+	var (
+		formatName  string
+		platforms   []string
+		outputDir   string
+		registryURL string
+	)
+	formatName = string(chartutils.DockerArchive)
+	cmd := &cobra.Command{
+		Use:   "export FILE",
+		Short: "Exports the images of a wrap as image archives",
+		Long: `Exports the images of a wrap into archives loadable without a registry, one archive per platform.
+Images are tagged with their references in the Images.lock, relocated to the given registry if requested`,
+		Example: `  # Export the linux/amd64 images of a wrap to be loaded with "docker load"
+  $ dt images export mariadb-12.2.8.wrap.tgz --platforms linux/amd64
+
+  # Export the images of a wrap relocated to a private registry, to be imported with "ctr import"
+  $ dt images export mariadb-12.2.8.wrap.tgz --format oci-archive --registry registry.example.com/mirror --output-dir ./archives`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			inputPath := args[0]
+			l := cfg.Logger()
+
+			format, err := chartutils.ParseArchiveFormat(formatName)
+			if err != nil {
+				return err
+			}
+			if !utils.FileExists(inputPath) {
+				return fmt.Errorf("wrap file %q does not exist", inputPath)
+			}
+			ctx, cancel := cfg.ContextWithSigterm()
+			defer cancel()
+
+			tempDir, err := cfg.GetTemporaryDirectory()
+			if err != nil {
+				return fmt.Errorf("failed to create temporary directory: %v", err)
+			}
+			wrapPath, err := wrap.ResolveInputChartPath(inputPath, wrap.NewConfig(
+				wrap.WithLogger(l),
+				wrap.WithTempDirectory(tempDir),
+			))
+			if err != nil {
+				return err
+			}
+			bundle, isChartWrap, err := loadBundle(wrapPath)
+			if err != nil {
+				return l.Failf("Failed to load wrap: %w", err)
+			}
+			lock, err := bundle.GetImagesLock()
+			if err != nil {
+				return l.Failf("Failed to load Images.lock: %v", err)
+			}
+			if len(lock.Images) == 0 {
+				return l.Failf("The wrap does not include any image")
+			}
+			if registryURL != "" {
+				// Container image wraps drop their source repository when relocated, as dt images unwrap does
+				if _, err := relocator.RelocateLock(lock, registryURL, isChartWrap); err != nil {
+					return l.Failf("%w", err)
+				}
+			}
+			if len(platforms) == 0 {
+				platforms = chartutils.LockPlatforms(lock)
+			}
+
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			for _, platform := range platforms {
+				outputFile := filepath.Join(outputDir, archiveName(lock.Chart.Name, lock.Chart.Version, platform)+format.Extension())
+				if err := l.Section(fmt.Sprintf("Exporting %s images into %q", platform, outputFile), func(childLog dtlog.SectionLogger) error {
+					count, err := chartutils.ExportImages(lock, bundle.ImagesDir(), outputFile, platform, format,
+						chartutils.WithLog(silent.NewLogger()),
+						chartutils.WithContext(ctx),
+						chartutils.WithProgressBar(childLog.ProgressBar()),
+					)
+					if err != nil {
+						return childLog.Failf("%v", err)
+					}
+					childLog.Infof("%d images exported successfully", count)
+					return nil
+				}); err != nil {
+					return l.Failf("%w", err)
+				}
+			}
+
+			l.Printf(widgets.TerminalSpacer)
+			l.Successf("All images exported successfully into %q", outputDir)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVar(&formatName, "format", formatName, "format of the image archives: docker-archive (docker load) or oci-archive (ctr import, podman load)")
+	cmd.PersistentFlags().StringSliceVar(&platforms, "platforms", platforms, "platforms to export, one archive each. Defaults to all the platforms in the wrap")
+	cmd.PersistentFlags().StringVar(&outputDir, "output-dir", ".", "directory where the image archives will be written")
+	cmd.PersistentFlags().StringVar(&registryURL, "registry", registryURL, "tag the images relocated to the given registry instead of with their original references")
+	return cmd
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+)
+
+func (suite *CmdSuite) TestExportCommand() {
+	t := suite.T()
+	require := suite.Require()
+	assert := suite.Assert()
+
+	sb := suite.sb
+
+	imageName := "test"
+	imageTag := "mytag"
+	serverURL := "localhost"
+	chartName := "test"
+	version := "1.0.0"
+	scenarioDir := "../../testdata/scenarios/complete-chart"
+
+	wrapDir := sb.TempFile()
+	images, err := writeSampleImages(imageName, imageTag, filepath.Join(wrapDir, "images"))
+	require.NoError(err)
+	require.NoError(tu.RenderScenario(scenarioDir, filepath.Join(wrapDir, "chart"),
+		map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version, "RepositoryURL": serverURL},
+	))
+	wrapFile := filepath.Join(sb.TempFile(), "test.wrap.tgz")
+	require.NoError(utils.Tar(wrapDir, wrapFile, utils.TarConfig{Prefix: chartName}))
+
+	digests := make(map[string]string)
+	for _, dgst := range images[0].Digests {
+		digests[dgst.Arch] = dgst.Digest.String()
+	}
+
+	t.Run("Exports docker archives", func(t *testing.T) {
+		outputDir := sb.TempFile()
+		dt("images", "export", wrapFile, "--output-dir", outputDir).AssertSuccessMatch(t, "")
+
+		ref, err := name.NewTag(fmt.Sprintf("%s/%s:%s", serverURL, imageName, imageTag))
+		require.NoError(err)
+		for _, platform := range []string{"linux-amd64", "linux-arm64"} {
+			archive := filepath.Join(outputDir, fmt.Sprintf("%s-%s-%s.docker.tar", chartName, version, platform))
+			img, err := tarball.ImageFromPath(archive, &ref)
+			require.NoError(err)
+			cf, err := img.ConfigFile()
+			require.NoError(err)
+			assert.Equal(platform, fmt.Sprintf("%s-%s", cf.OS, cf.Architecture))
+		}
+	})
+
+	t.Run("Exports relocated OCI archives", func(t *testing.T) {
+		outputDir := sb.TempFile()
+		dt("images", "export", wrapFile, "--output-dir", outputDir, "--format", "oci-archive",
+			"--platforms", "linux/arm64", "--registry", "registry.example.com/mirror").AssertSuccessMatch(t, "")
+
+		assert.NoFileExists(filepath.Join(outputDir, fmt.Sprintf("%s-%s-linux-amd64.oci.tar", chartName, version)))
+		layoutDir := sb.TempFile()
+		require.NoError(utils.Untar(filepath.Join(outputDir, fmt.Sprintf("%s-%s-linux-arm64.oci.tar", chartName, version)), layoutDir, utils.TarConfig{}))
+
+		idx, err := layout.ImageIndexFromPath(layoutDir)
+		require.NoError(err)
+		manifest, err := idx.IndexManifest()
+		require.NoError(err)
+		require.Len(manifest.Manifests, 1)
+		desc := manifest.Manifests[0]
+		assert.Equal(digests["linux/arm64"], desc.Digest.String())
+		relocatedRef, err := utils.RelocateImageURL(fmt.Sprintf("%s/%s:%s", serverURL, imageName, imageTag), "registry.example.com/mirror", true, true)
+		require.NoError(err)
+		assert.Equal(relocatedRef, desc.Annotations["org.opencontainers.image.ref.name"])
+		assert.Equal(relocatedRef, desc.Annotations["io.containerd.image.name"])
+	})
+
+	t.Run("Tags images pinned by digest", func(t *testing.T) {
+		pinnedWrapDir := sb.TempFile()
+		_, err := writeSampleImages(imageName, imageTag, filepath.Join(pinnedWrapDir, "images"))
+		require.NoError(err)
+		pin := "@" + digests["linux/amd64"]
+		pinnedImages := []tu.ImageData{
+			{Name: "tagged", Image: fmt.Sprintf("%s:%s%s", imageName, imageTag, pin), Digests: images[0].Digests},
+			{Name: "untagged", Image: "other" + pin, Digests: images[0].Digests},
+		}
+		require.NoError(tu.RenderScenario(scenarioDir, filepath.Join(pinnedWrapDir, "chart"),
+			map[string]interface{}{"ServerURL": serverURL, "Images": pinnedImages, "Name": chartName, "Version": version, "RepositoryURL": serverURL},
+		))
+		pinnedWrapFile := filepath.Join(sb.TempFile(), "test.wrap.tgz")
+		require.NoError(utils.Tar(pinnedWrapDir, pinnedWrapFile, utils.TarConfig{Prefix: chartName}))
+
+		outputDir := sb.TempFile()
+		dt("images", "export", pinnedWrapFile, "--output-dir", outputDir, "--platforms", "linux/amd64").
+			AssertSuccessMatch(t, "only pinned by digest")
+
+		ref, err := name.NewTag(fmt.Sprintf("%s/%s:%s", serverURL, imageName, imageTag))
+		require.NoError(err)
+		_, err = tarball.ImageFromPath(filepath.Join(outputDir, fmt.Sprintf("%s-%s-linux-amd64.docker.tar", chartName, version)), &ref)
+		require.NoError(err)
+	})
+
+	t.Run("Handles errors", func(t *testing.T) {
+		dt("images", "export", wrapFile, "--format", "zip").AssertErrorMatch(t, `unsupported archive format "zip"`)
+		dt("images", "export", wrapFile, "--output-dir", sb.TempFile(), "--platforms", "linux/s390x").AssertErrorMatch(t, `no images found for platform "linux/s390x"`)
+		dt("images", "export", filepath.Join(sb.TempFile(), "missing.wrap.tgz")).AssertErrorMatch(t, "does not exist")
+	})
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/export"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/lock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/mirror"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/pull"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/push"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/unwrap"
@@ -28,5 +30,7 @@ func init() {
 		push.NewCmd(mainConfig),
 		wrap.NewContainerCmd(mainConfig),
 		unwrap.NewContainerCmd(mainConfig),
+		export.NewCmd(mainConfig),
+		mirror.NewCmd(mainConfig),
 	)
 }
@@ -11,7 +11,9 @@ func (suite *CmdSuite) TestImagesHelp() {
 		res := dt("images")
 		res.AssertSuccess(t)
 		for _, reStr := range []string{
+			`export\s+Exports the images of a wrap as image archives`,
 			`lock\s+Creates the lock file`,
+			`mirror\s+Mirrors the images of a Helm chart into a registry`,
 			`pull\s+Pulls the images from the Images\.lock`,
 			`push\s+Pushes the images from Images\.lock`,
 			`verify\s+Verifies the images in an Images\.lock`,
@@ -34,6 +34,10 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 			if !utils.FileExists(chartPath) {
 				return fmt.Errorf("wrap file %q does not exist", chartPath)
 			}
+			splitIndex, err := readSplitIndex(chartPath)
+			if err != nil {
+				return err
+			}
 			lock, err := chartutils.ReadLockFromChart(chartPath)
 			if err != nil {
 				return fmt.Errorf("failed to load Images.lock: %v", err)
@@ -48,6 +52,12 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 					l.Printf("Chart: %s", lock.Chart.Name)
 					l.Printf("Version: %s", lock.Chart.Version)
 					l.Printf("App Version: %s", lock.Chart.AppVersion)
+					if compression, err := utils.DetectCompression(chartPath); err == nil {
+						l.Printf("Compression: %s", compression)
+					}
+					if splitIndex != nil {
+						l.Printf("Parts: %d", len(splitIndex.Parts))
+					}
 					_ = l.Section("Metadata", func(l dtlog.SectionLogger) error {
 						for k, v := range lock.Metadata {
 							l.Printf("- %s: %s", k, v)
@@ -65,6 +75,9 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 										for _, digest := range img.Digests {
 											l.Printf("- Arch: %s", digest.Arch)
 											l.Printf("  Digest: %s", digest.Digest)
+											if digest.OSVersion != "" {
+												l.Printf("  OS Version: %s", digest.OSVersion)
+											}
 										}
 									}
 									return nil
@@ -90,3 +103,16 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 
 	return cmd
 }
+
+// readSplitIndex returns the split index of the wrap, if it is split in several parts,
+// after verifying all of them
+func readSplitIndex(wrapFile string) (*utils.SplitIndex, error) {
+	indexFile, err := utils.FindSplitIndex(wrapFile)
+	if err != nil || indexFile == "" {
+		return nil, err
+	}
+	if err := utils.VerifySplitFile(indexFile); err != nil {
+		return nil, fmt.Errorf("failed to verify split wrap: %v", err)
+	}
+	return utils.ReadSplitIndex(indexFile)
+}
@@ -49,7 +49,10 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 			if err := l.ExecuteStep("Generating Images.lock from annotations...", func() error {
 				return Create(chartPath, lockFilePath, silent.NewLogger(), imagelock.WithPlatforms(platforms),
 					imagelock.WithAnnotationsKey(cfg.AnnotationsKey),
-					imagelock.WithInsecure(cfg.Insecure))
+					imagelock.WithInsecure(cfg.Insecure),
+					imagelock.WithCredentialsFile(cfg.CredentialsFile),
+					imagelock.WithRegistriesFile(cfg.RegistriesFile),
+					imagelock.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile))
 			}); err != nil {
 				return l.Failf("Failed to generate lock: %w", err)
 			}
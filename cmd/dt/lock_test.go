@@ -61,6 +61,36 @@ func (suite *CmdSuite) TestLockCommand() {
 		require.Equal(expectedLock, newLock)
 
 	})
+	t.Run("Generate lock file through a registry mirror", func(t *testing.T) {
+		// The chart images point to an unreachable registry, only accessible through its mirror
+		upstreamURL := "127.0.0.1:1"
+		chartDir := sb.TempFile()
+
+		require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+			map[string]interface{}{"ServerURL": upstreamURL, "Images": images, "Name": chartName, "RepositoryURL": upstreamURL},
+		))
+		registriesFile := sb.TempFile()
+		require.NoError(os.WriteFile(registriesFile, []byte(fmt.Sprintf("mirrors:\n  %q:\n    endpoints: [%q]\n", upstreamURL, serverURL)), 0644))
+
+		data, err := tu.RenderTemplateFile(filepath.Join(scenarioDir, "imagelock.partial.tmpl"),
+			map[string]interface{}{"ServerURL": upstreamURL, "Images": images, "Name": chartName},
+		)
+		require.NoError(err)
+		var expectedLock map[string]interface{}
+		require.NoError(yaml.Unmarshal([]byte(data), &expectedLock))
+		expectedLock["metadata"] = nil
+
+		dt("images", "lock", "--insecure", "--registries-file", registriesFile, chartDir).AssertSuccess(t)
+
+		newData, err := os.ReadFile(filepath.Join(chartDir, "Images.lock"))
+		require.NoError(err)
+		var newLock map[string]interface{}
+		require.NoError(yaml.Unmarshal(newData, &newLock))
+		newLock["metadata"] = nil
+
+		// Images.lock keeps the upstream references
+		require.Equal(expectedLock, newLock)
+	})
 	t.Run("Errors", func(t *testing.T) {
 		t.Run("Handles failure to write lock because of permissions", func(t *testing.T) {
 			scenarioName := "plain-chart"
@@ -20,6 +20,7 @@ type loginOptions struct {
 	user          string
 	password      string
 	passwordStdin bool
+	passwordFile  string
 }
 
 // NewCmd returns a new dt login command
@@ -34,7 +35,10 @@ func NewCmd(cfg *config.Config) *cobra.Command {
   $ dt auth login index.docker.io -u my_username -p my_password
 
   # Log in to index.docker.io with a password from stdin
-  $ dt auth login index.docker.io -u my_username --password-stdin < <(echo my_password)`,
+  $ dt auth login index.docker.io -u my_username --password-stdin < <(echo my_password)
+
+  # Log in to index.docker.io with a password read from a file
+  $ dt auth login index.docker.io -u my_username --password-file /run/secrets/password`,
 		Args:          cobra.ExactArgs(1),
 		SilenceUsage:  true,
 		SilenceErrors: true,
@@ -56,6 +60,8 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 	flags.StringVarP(&opts.user, "username", "u", "", "Username")
 	flags.StringVarP(&opts.password, "password", "p", "", "Password")
 	flags.BoolVarP(&opts.passwordStdin, "password-stdin", "", false, "Take the password from stdin")
+	flags.StringVar(&opts.passwordFile, "password-file", "", "Take the password from the given file")
+	cmd.MarkFlagsMutuallyExclusive("password", "password-stdin", "password-file")
 
 	return cmd
 }
@@ -70,6 +76,14 @@ func login(opts loginOptions, l dtlog.SectionLogger) error {
 
 		opts.password = strings.TrimRight(string(contents), "\r\n")
 	}
+	if opts.passwordFile != "" {
+		contents, err := os.ReadFile(opts.passwordFile)
+		if err != nil {
+			return l.Failf("failed to read password file: %v", err)
+		}
+
+		opts.password = strings.TrimRight(string(contents), "\r\n")
+	}
 	if opts.user == "" && opts.password == "" {
 		return l.Failf("username and password required")
 	}
@@ -0,0 +1,410 @@
+// Package mirror implements the dt images mirror command
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/config"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/verify"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/wrap"
+	"github.com/vmware-labs/distribution-tooling-for-helm/internal/widgets"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/silent"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/relocator"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/wrapping"
+)
+
+// Auth defines the authentication information to access the container registry
+type Auth struct {
+	Username string
+	Password string
+}
+
+// Config defines the configuration for the mirror command
+type Config struct {
+	Context               context.Context
+	AnnotationsKey        string
+	UsePlainHTTP          bool
+	Insecure              bool
+	Platforms             []string
+	TempDirectory         string
+	Version               string
+	Auth                  Auth
+	ContainerRegistryAuth Auth
+	CredentialsFile       string
+	RegistriesFile        string
+	TLS                   registries.TLS
+	ValuesFiles           []string
+	SkipChartPush         bool
+	logger                dtlog.SectionLogger
+}
+
+// Option defines a Config option
+type Option func(*Config)
+
+// WithAuth configures the Auth of the Helm chart registries
+func WithAuth(username, password string) func(c *Config) {
+	return func(c *Config) {
+		c.Auth = Auth{Username: username, Password: password}
+	}
+}
+
+// WithContainerRegistryAuth configures the Auth of the container registries
+func WithContainerRegistryAuth(username, password string) func(c *Config) {
+	return func(c *Config) {
+		c.ContainerRegistryAuth = Auth{Username: username, Password: password}
+	}
+}
+
+// WithCredentialsFile configures the per-registry credentials file of the container registries
+func WithCredentialsFile(file string) func(c *Config) {
+	return func(c *Config) {
+		c.CredentialsFile = file
+	}
+}
+
+// WithRegistriesFile configures the registries file defining the mirrors to pull the container images from
+func WithRegistriesFile(file string) func(c *Config) {
+	return func(c *Config) {
+		c.RegistriesFile = file
+	}
+}
+
+// WithTLS configures the TLS certificate authorities and client certificate used to access the registries
+func WithTLS(caFile, certFile, keyFile string) func(c *Config) {
+	return func(c *Config) {
+		c.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
+// WithInsecure configures the Insecure setting
+func WithInsecure(insecure bool) func(c *Config) {
+	return func(c *Config) {
+		c.Insecure = insecure
+	}
+}
+
+// WithUsePlainHTTP configures the UsePlainHTTP setting
+func WithUsePlainHTTP(usePlainHTTP bool) func(c *Config) {
+	return func(c *Config) {
+		c.UsePlainHTTP = usePlainHTTP
+	}
+}
+
+// WithAnnotationsKey configures the AnnotationsKey setting
+func WithAnnotationsKey(annotationsKey string) func(c *Config) {
+	return func(c *Config) {
+		c.AnnotationsKey = annotationsKey
+	}
+}
+
+// WithPlatforms configures the platforms of the images to mirror
+func WithPlatforms(platforms []string) func(c *Config) {
+	return func(c *Config) {
+		c.Platforms = platforms
+	}
+}
+
+// WithVersion configures the version to request when mirroring remote Helm charts
+func WithVersion(version string) func(c *Config) {
+	return func(c *Config) {
+		c.Version = version
+	}
+}
+
+// WithValuesFiles configures the values files to relocate
+func WithValuesFiles(files ...string) func(c *Config) {
+	return func(c *Config) {
+		c.ValuesFiles = files
+	}
+}
+
+// WithSkipChartPush configures whether to only mirror the images, without pushing the relocated Helm chart
+func WithSkipChartPush(skip bool) func(c *Config) {
+	return func(c *Config) {
+		c.SkipChartPush = skip
+	}
+}
+
+// WithTempDirectory configures the temporary directory
+func WithTempDirectory(tempDir string) func(c *Config) {
+	return func(c *Config) {
+		c.TempDirectory = tempDir
+	}
+}
+
+// WithLogger configures the logger
+func WithLogger(logger dtlog.SectionLogger) func(c *Config) {
+	return func(c *Config) {
+		c.logger = logger
+	}
+}
+
+// WithContext configures the execution context
+func WithContext(ctx context.Context) func(c *Config) {
+	return func(c *Config) {
+		c.Context = ctx
+	}
+}
+
+// GetTemporaryDirectory returns the temporary directory, creating one if not configured
+func (c *Config) GetTemporaryDirectory() (string, error) {
+	if c.TempDirectory != "" {
+		return c.TempDirectory, nil
+	}
+	return os.MkdirTemp("", "chart-*")
+}
+
+// GetLogger returns the configured logger
+func (c *Config) GetLogger() dtlog.SectionLogger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return logrus.NewSectionLogger()
+}
+
+// NewConfig returns a new Config with default values
+func NewConfig(opts ...Option) *Config {
+	cfg := &Config{
+		Context:        context.Background(),
+		logger:         logrus.NewSectionLogger(),
+		AnnotationsKey: imagelock.DefaultAnnotationsKey,
+		Platforms:      []string{},
+		ValuesFiles:    []string{"values.yaml"},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Chart copies the images of a Helm chart straight from their registries into registryURL, then relocates the chart
+// and pushes it to pushChartURL, or to registryURL if empty. It returns the URL of the pushed chart
+func Chart(inputChart, registryURL, pushChartURL string, opts ...Option) (string, error) {
+	return mirrorChart(inputChart, registryURL, pushChartURL, opts...)
+}
+
+func mirrorChart(inputChart, registryURL, pushChartURL string, opts ...Option) (string, error) {
+	cfg := NewConfig(opts...)
+	ctx := cfg.Context
+	parentLog := cfg.GetLogger()
+
+	if registryURL == "" {
+		return "", fmt.Errorf("the registry cannot be empty")
+	}
+	// crane gets confused with the oci scheme
+	registryURL = strings.TrimPrefix(registryURL, "oci://")
+
+	tempDir, err := cfg.GetTemporaryDirectory()
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	l := parentLog.StartSection(fmt.Sprintf("Mirroring Helm chart %q into %q", inputChart, registryURL))
+
+	chartPath, err := wrap.ResolveInputChartPath(inputChart, wrap.NewConfig(
+		wrap.WithTempDirectory(tempDir),
+		wrap.WithLogger(l),
+		wrap.WithVersion(cfg.Version),
+		wrap.WithInsecure(cfg.Insecure),
+		wrap.WithCredentialsFile(cfg.CredentialsFile),
+		wrap.WithRegistriesFile(cfg.RegistriesFile),
+		wrap.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+		wrap.WithUsePlainHTTP(cfg.UsePlainHTTP),
+		wrap.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+	))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve input chart path: %w", err)
+	}
+
+	// The chart is relocated in a copy, leaving the source untouched
+	mirrorDir, err := os.MkdirTemp(tempDir, "mirror-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	w, err := wrapping.Create(chartPath, mirrorDir, chartutils.WithAnnotationsKey(cfg.AnnotationsKey))
+	if err != nil {
+		return "", l.Failf("%w", err)
+	}
+	chart := w.Chart()
+
+	if !utils.FileExists(w.LockFilePath()) {
+		return "", l.Failf("Helm chart %q does not include an Images.lock, create it with \"dt images lock\"", chart.Name())
+	}
+	lock, err := w.GetImagesLock()
+	if err != nil {
+		return "", l.Failf("Failed to load Images.lock: %w", err)
+	}
+	if err := lock.FilterPlatforms(cfg.Platforms); err != nil {
+		return "", l.Failf("Failed to select platforms: %w", err)
+	}
+	if err := writeLock(lock, w.LockFilePath()); err != nil {
+		return "", l.Failf("%w", err)
+	}
+
+	if len(lock.Images) > 0 {
+		if err := l.Section("Mirroring images", func(subLog dtlog.SectionLogger) error {
+			if err := chartutils.MirrorImages(lock, registryURL,
+				chartutils.WithLog(silent.NewLogger()),
+				chartutils.WithContext(ctx),
+				chartutils.WithProgressBar(subLog.ProgressBar()),
+				chartutils.WithInsecureMode(cfg.Insecure),
+				chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+				chartutils.WithCredentialsFile(cfg.CredentialsFile),
+				chartutils.WithRegistriesFile(cfg.RegistriesFile),
+				chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+			); err != nil {
+				return err
+			}
+			subLog.Infof("All images mirrored successfully")
+			return nil
+		}); err != nil {
+			return "", l.Failf("Failed to mirror images: %w", err)
+		}
+		l.Printf(widgets.TerminalSpacer)
+	} else {
+		l.Warnf("The Helm chart does not include any image")
+	}
+
+	if err := l.ExecuteStep(fmt.Sprintf("Relocating %q with prefix %q", chart.Name(), registryURL), func() error {
+		return relocator.RelocateChartDir(
+			w.ChartDir(), registryURL, relocator.WithLog(l),
+			relocator.Recursive, relocator.WithAnnotationsKey(cfg.AnnotationsKey), relocator.WithValuesFiles(cfg.ValuesFiles...),
+		)
+	}); err != nil {
+		return "", l.Failf("Failed to relocate %q: %w", chart.Name(), err)
+	}
+	l.Infof("Helm chart relocated successfully")
+
+	if err := l.ExecuteStep("Verifying Images.lock", func() error {
+		return verify.Lock(w.ChartDir(), w.LockFilePath(), verify.Config{
+			Insecure: cfg.Insecure, AnnotationsKey: cfg.AnnotationsKey, PreserveRepository: true,
+			Auth: verify.Auth{Username: cfg.ContainerRegistryAuth.Username, Password: cfg.ContainerRegistryAuth.Password},
+		})
+	}); err != nil {
+		return "", l.Failf("Failed to verify Helm chart Images.lock: %w", err)
+	}
+	l.Infof("Helm chart Images.lock is valid")
+
+	if cfg.SkipChartPush {
+		return "", nil
+	}
+	auth := cfg.Auth
+	if pushChartURL == "" {
+		pushChartURL = registryURL
+		// we will push the chart to the same registry as the containers
+		auth = cfg.ContainerRegistryAuth
+	}
+	pushChartURL = fmt.Sprintf("oci://%s", strings.TrimPrefix(pushChartURL, "oci://"))
+	if err := l.ExecuteStep(fmt.Sprintf("Pushing Helm chart to %q", pushChartURL), func() error {
+		tarFile := filepath.Join(mirrorDir, fmt.Sprintf("%s-%s.tgz", chart.Name(), chart.Version()))
+		if err := utils.Tar(chart.RootDir(), tarFile, utils.TarConfig{Prefix: chart.Name()}); err != nil {
+			return fmt.Errorf("failed to package Helm chart: %w", err)
+		}
+		return artifacts.PushChart(tarFile, pushChartURL,
+			artifacts.WithInsecure(cfg.Insecure),
+			artifacts.WithRegistryRegistriesFile(cfg.RegistriesFile),
+			artifacts.WithRegistryTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+			artifacts.WithPlainHTTP(cfg.UsePlainHTTP),
+			artifacts.WithRegistryAuth(auth.Username, auth.Password),
+			artifacts.WithRegistryCredentialsFile(cfg.CredentialsFile),
+			artifacts.WithTempDir(tempDir),
+		)
+	}); err != nil {
+		return "", l.Failf("Failed to push Helm chart: %w", err)
+	}
+	l.Infof("Helm chart successfully pushed")
+	return fmt.Sprintf("%s/%s", pushChartURL, chart.Name()), nil
+}
+
+func writeLock(lock *imagelock.ImagesLock, file string) error {
+	fh, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to write Images.lock: %w", err)
+	}
+	defer fh.Close()
+	return lock.ToYAML(fh)
+}
+
+// NewCmd builds a new mirror command
+func NewCmd(cfg *config.Config) *cobra.Command {
+	var (
+		registryURL   string
+		pushChartURL  string
+		version       string
+		platforms     []string
+		skipChartPush bool
+	)
+	valuesFiles := []string{"values.yaml"}
+	cmd := &cobra.Command{
+		Use:   "mirror CHART_PATH|OCI_URI --to REGISTRY",
+		Short: "Mirrors the images of a Helm chart into a registry",
+		Long: `Copies the images in the Images.lock of a Helm chart straight from their registries into the target registry, without wrapping them.
+The Helm chart is then relocated to the target registry, verified and pushed`,
+		Example: `  # Mirror a Helm chart and its images into a Harbor repository
+  $ dt images mirror examples/mariadb --to oci://demo.goharbor.io/test_repo
+
+  # Mirror only the linux/arm64 images of a remote Helm chart
+  $ dt images mirror oci://docker.io/bitnamicharts/mariadb --version 12.2.8 --to oci://demo.goharbor.io/test_repo --platforms linux/arm64`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			l := cfg.Logger()
+
+			if registryURL == "" {
+				return fmt.Errorf("the target registry must be provided with --to")
+			}
+			ctx, cancel := cfg.ContextWithSigterm()
+			defer cancel()
+
+			tempDir, err := cfg.GetTemporaryDirectory()
+			if err != nil {
+				return fmt.Errorf("failed to create temporary directory: %v", err)
+			}
+			fullChartURL, err := mirrorChart(args[0], registryURL, pushChartURL,
+				WithLogger(l),
+				WithContext(ctx),
+				WithVersion(version),
+				WithPlatforms(platforms),
+				WithAnnotationsKey(cfg.AnnotationsKey),
+				WithInsecure(cfg.Insecure),
+				WithCredentialsFile(cfg.CredentialsFile),
+				WithRegistriesFile(cfg.RegistriesFile),
+				WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+				WithUsePlainHTTP(cfg.UsePlainHTTP),
+				WithTempDirectory(tempDir),
+				WithValuesFiles(valuesFiles...),
+				WithSkipChartPush(skipChartPush),
+			)
+			if err != nil {
+				return err
+			}
+			successMessage := "Helm chart mirrored successfully"
+			if fullChartURL != "" {
+				successMessage = fmt.Sprintf(`%s: You can use it now by running "helm install %s --generate-name"`, successMessage, fullChartURL)
+			}
+			l.Printf(widgets.TerminalSpacer)
+			l.Successf(successMessage)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVar(&registryURL, "to", registryURL, "registry to mirror the images and Helm chart into")
+	cmd.PersistentFlags().StringVar(&pushChartURL, "push-chart-url", pushChartURL, "push the relocated Helm chart to the given OCI URL instead of the target registry")
+	cmd.PersistentFlags().StringVar(&version, "version", version, "when mirroring remote Helm charts from OCI, version to request")
+	cmd.PersistentFlags().StringSliceVar(&platforms, "platforms", platforms, "platforms to mirror, defaults to all the platforms in the Images.lock")
+	cmd.PersistentFlags().StringSliceVar(&valuesFiles, "values", valuesFiles, "values files to relocate images (can specify multiple)")
+	cmd.PersistentFlags().BoolVar(&skipChartPush, "skip-chart-push", skipChartPush, "only mirror the images, without pushing the relocated Helm chart")
+	return cmd
+}
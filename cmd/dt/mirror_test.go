@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/mirror"
+	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+)
+
+func (suite *CmdSuite) TestMirrorCommand() {
+	t := suite.T()
+	require := suite.Require()
+	assert := suite.Assert()
+
+	sb := suite.sb
+
+	silentLog := log.New(io.Discard, "", 0)
+	newRegistry := func() string {
+		s := httptest.NewServer(registry.New(registry.Logger(silentLog)))
+		t.Cleanup(s.Close)
+		u, err := url.Parse(s.URL)
+		require.NoError(err)
+		return u.Host
+	}
+	srcRegistry := newRegistry()
+
+	chartName := "test"
+	version := "1.0.0"
+	scenarioDir := "../../testdata/scenarios/complete-chart"
+
+	certDir, err := sb.Mkdir(sb.TempFile(), 0755)
+	require.NoError(err)
+	keyFile, pubKey, err := tu.GenerateCosignCertificateFiles(certDir)
+	require.NoError(err)
+	metadataDir, err := sb.Mkdir(sb.TempFile(), 0755)
+	require.NoError(err)
+	_, err = sb.Write(filepath.Join(metadataDir, "metadata.txt"), "this is a sample text")
+	require.NoError(err)
+
+	images, err := tu.AddSampleImagesToRegistry("test", srcRegistry, tu.WithSignKey(keyFile), tu.WithMetadataDir(metadataDir))
+	require.NoError(err)
+
+	chartDir := sb.TempFile()
+	renderData := map[string]interface{}{"ServerURL": srcRegistry, "Images": images, "Name": chartName, "Version": version, "RepositoryURL": srcRegistry}
+	require.NoError(tu.RenderScenario(scenarioDir, chartDir, renderData))
+	originalChart, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	require.NoError(err)
+
+	for _, useAPI := range []bool{true, false} {
+		t.Run(fmt.Sprintf("Mirrors a Helm chart (API: %t)", useAPI), func(t *testing.T) {
+			targetRegistry := fmt.Sprintf("%s/mirror", newRegistry())
+			if useAPI {
+				l := logrus.NewSectionLogger()
+				l.SetWriter(io.Discard)
+				fullChartURL, err := mirror.Chart(chartDir, targetRegistry, "", mirror.WithLogger(l), mirror.WithUsePlainHTTP(true))
+				require.NoError(err)
+				assert.Equal(fmt.Sprintf("oci://%s/%s", targetRegistry, chartName), fullChartURL)
+			} else {
+				dt("images", "mirror", chartDir, "--to", targetRegistry, "--use-plain-http").AssertSuccessMatch(t, "")
+			}
+
+			for _, img := range images {
+				target, err := utils.RelocateImageURL(fmt.Sprintf("%s/%s", srcRegistry, img.Image), targetRegistry, true, true)
+				require.NoError(err)
+				remoteDigests, err := tu.ReadRemoteImageManifest(target)
+				require.NoError(err)
+				for _, dgstData := range img.Digests {
+					assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+				}
+				assert.NoError(tu.CosignVerifyImage(target, pubKey), "signature for %q should be mirrored", target)
+
+				dgst, err := crane.Digest(target)
+				require.NoError(err)
+				ref, err := name.ParseReference(target)
+				require.NoError(err)
+				tags, err := crane.ListTags(ref.Context().Name())
+				require.NoError(err)
+				assert.Contains(tags, fmt.Sprintf("sha256-%s.metadata", dgst[len("sha256:"):]))
+			}
+			assert.True(
+				artifacts.RemoteChartExist(fmt.Sprintf("oci://%s/%s", targetRegistry, chartName), version, artifacts.WithPlainHTTP(true)),
+				"chart should exist in the target registry",
+			)
+			currentChart, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+			require.NoError(err)
+			assert.Equal(string(originalChart), string(currentChart), "the source chart should not be relocated")
+		})
+	}
+
+	t.Run("Mirrors only the selected platforms", func(t *testing.T) {
+		targetRegistry := fmt.Sprintf("%s/mirror", newRegistry())
+		dt("images", "mirror", chartDir, "--to", targetRegistry, "--use-plain-http", "--platforms", "linux/amd64", "--skip-chart-push").AssertSuccessMatch(t, "")
+
+		for _, img := range images {
+			target, err := utils.RelocateImageURL(fmt.Sprintf("%s/%s", srcRegistry, img.Image), targetRegistry, true, true)
+			require.NoError(err)
+			remoteDigests, err := tu.ReadRemoteImageManifest(target)
+			require.NoError(err)
+			assert.Len(remoteDigests, 1)
+			assert.Contains(remoteDigests, "linux/amd64")
+		}
+		assert.False(
+			artifacts.RemoteChartExist(fmt.Sprintf("oci://%s/%s", targetRegistry, chartName), version, artifacts.WithPlainHTTP(true)),
+			"chart should not be pushed",
+		)
+	})
+
+	t.Run("Handles errors", func(t *testing.T) {
+		dt("images", "mirror", chartDir).AssertErrorMatch(t, "the target registry must be provided with --to")
+		dt("images", "mirror", chartDir, "--to", newRegistry(), "--platforms", "linux/s390x").AssertErrorMatch(t, `platform "linux/s390x" is not available`)
+
+		noLockChart := sb.TempFile()
+		require.NoError(tu.RenderScenario(scenarioDir, noLockChart, renderData))
+		require.NoError(os.RemoveAll(filepath.Join(noLockChart, "Images.lock")))
+		dt("images", "mirror", noLockChart, "--to", newRegistry()).AssertErrorMatch(t, "does not include an Images.lock")
+	})
+}
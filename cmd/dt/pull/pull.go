@@ -22,6 +22,7 @@ func ChartImages(wrap wrapping.Wrap, imagesDir string, opts ...chartutils.Option
 func NewCmd(cfg *config.Config) *cobra.Command {
 	var outputFile string
 	var imagesDir string
+	var includeNondistributable bool
 
 	cmd := &cobra.Command{
 		Use:   "pull CHART_PATH",
@@ -64,6 +65,10 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 						chartutils.WithProgressBar(childLog.ProgressBar()),
 						chartutils.WithArtifactsDir(chart.ImageArtifactsDir()),
 						chartutils.WithInsecureMode(cfg.Insecure),
+						chartutils.WithCredentialsFile(cfg.CredentialsFile),
+						chartutils.WithRegistriesFile(cfg.RegistriesFile),
+						chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+						chartutils.WithIncludeNondistributable(includeNondistributable),
 					); err != nil {
 						return childLog.Failf("%v", err)
 					}
@@ -105,6 +110,8 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&outputFile, "output-file", outputFile, "generate a tar.gz with the output of the pull operation")
 	cmd.PersistentFlags().StringVar(&imagesDir, "images-dir", imagesDir,
 		"directory where the images will be pulled to. If not empty, it overrides the default images directory inside the chart directory")
+	cmd.PersistentFlags().BoolVar(&includeNondistributable, "include-nondistributable", includeNondistributable,
+		"pull the non-distributable (foreign) image layers, such as Windows base layers, too")
 	return cmd
 }
 
@@ -74,6 +74,21 @@ func (suite *CmdSuite) TestPullCommand() {
 		verifyChartDir(tmpDir)
 	})
 
+	t.Run("Pulls images through a registry mirror", func(t *testing.T) {
+		// The Images.lock references an unreachable registry, only accessible through its mirror
+		upstreamURL := "127.0.0.1:1"
+		chartDir := sb.TempFile()
+		require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+			map[string]interface{}{"ServerURL": upstreamURL, "Images": images, "Name": chartName, "RepositoryURL": serverURL},
+		))
+		dt("images", "pull", chartDir).AssertErrorMatch(t, "failed to pull image")
+
+		registriesFile := sb.TempFile()
+		require.NoError(os.WriteFile(registriesFile, []byte(fmt.Sprintf("mirrors:\n  %q:\n    endpoints: [%q]\n", upstreamURL, serverURL)), 0644))
+		dt("images", "pull", "--registries-file", registriesFile, chartDir).AssertSuccess(t)
+		verifyChartDir(chartDir)
+	})
+
 	t.Run("Warning when no images in Images.lock", func(t *testing.T) {
 		images = []tu.ImageData{}
 		scenarioName := "no-images-chart"
@@ -67,6 +67,9 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 					chartutils.WithProgressBar(subLog.ProgressBar()),
 					chartutils.WithArtifactsDir(chart.ImageArtifactsDir()),
 					chartutils.WithInsecureMode(cfg.Insecure),
+					chartutils.WithCredentialsFile(cfg.CredentialsFile),
+					chartutils.WithRegistriesFile(cfg.RegistriesFile),
+					chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				); err != nil {
 					return subLog.Failf("Failed to push images: %w", err)
 				}
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+)
+
+// sectionLogger decorates a SectionLogger recording its warnings and the duration of its steps into a Report
+type sectionLogger struct {
+	dtlog.SectionLogger
+	report *Report
+}
+
+// Logger returns l decorated to record its warnings and the duration of its steps and sections into the report
+func (r *Report) Logger(l dtlog.SectionLogger) dtlog.SectionLogger {
+	if r == nil {
+		return l
+	}
+	return &sectionLogger{SectionLogger: l, report: r}
+}
+
+// Warnf logs a warning message, recording it
+func (l *sectionLogger) Warnf(format string, args ...interface{}) {
+	l.report.AddWarning(fmt.Sprintf(format, args...))
+	l.SectionLogger.Warnf(format, args...)
+}
+
+// ExecuteStep executes a function, recording its duration
+func (l *sectionLogger) ExecuteStep(title string, fn func() error) error {
+	start := time.Now()
+	err := l.SectionLogger.ExecuteStep(title, fn)
+	l.report.addStep(title, start, err)
+	return err
+}
+
+// Section executes the provided function inside a new section, recording its duration
+func (l *sectionLogger) Section(title string, fn func(dtlog.SectionLogger) error) error {
+	start := time.Now()
+	err := l.SectionLogger.Section(title, func(child dtlog.SectionLogger) error {
+		return fn(l.report.Logger(child))
+	})
+	l.report.addStep(title, start, err)
+	return err
+}
+
+// StartSection starts a new log section, whose messages are also recorded
+func (l *sectionLogger) StartSection(title string) dtlog.SectionLogger {
+	return l.report.Logger(l.SectionLogger.StartSection(title))
+}
+
+// ProgressBar returns a new ProgressBar, whose warnings are recorded
+func (l *sectionLogger) ProgressBar() dtlog.ProgressBar {
+	return &progressBar{ProgressBar: l.SectionLogger.ProgressBar(), report: l.report}
+}
+
+// progressBar decorates a ProgressBar recording its warnings into a Report. The decorated
+// implementations return themselves, so their results are not kept
+type progressBar struct {
+	dtlog.ProgressBar
+	report *Report
+}
+
+// Start initiates the progress bar
+func (p *progressBar) Start(title ...interface{}) (dtlog.ProgressBar, error) {
+	_, err := p.ProgressBar.Start(title...)
+	return p, err
+}
+
+// WithTotal sets the progress bar total steps
+func (p *progressBar) WithTotal(total int) dtlog.ProgressBar {
+	p.ProgressBar.WithTotal(total)
+	return p
+}
+
+// UpdateTitle updates the progress bar title
+func (p *progressBar) UpdateTitle(title string) dtlog.ProgressBar {
+	p.ProgressBar.UpdateTitle(title)
+	return p
+}
+
+// Add increments the progress bar the specified amount
+func (p *progressBar) Add(inc int) dtlog.ProgressBar {
+	p.ProgressBar.Add(inc)
+	return p
+}
+
+// Warnf displays a warning message, recording it
+func (p *progressBar) Warnf(format string, args ...interface{}) {
+	p.report.AddWarning(fmt.Sprintf(format, args...))
+	p.ProgressBar.Warnf(format, args...)
+}
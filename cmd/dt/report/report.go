@@ -0,0 +1,294 @@
+// Package report implements the machine-readable summary of the wrap and unwrap commands
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+)
+
+// Status defines the result of an operation
+type Status string
+
+const (
+	// StatusSuccess indicates the operation succeeded
+	StatusSuccess Status = "success"
+	// StatusError indicates the operation failed
+	StatusError Status = "error"
+)
+
+// Chart defines a Helm chart processed by the command
+type Chart struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// File defines a file written by the command
+type File struct {
+	Path   string `json:"path" yaml:"path"`
+	Size   int64  `json:"size" yaml:"size"`
+	SHA256 string `json:"sha256" yaml:"sha256"`
+}
+
+// Digest defines the digest of an image for a platform
+type Digest struct {
+	Platform string `json:"platform" yaml:"platform"`
+	Digest   string `json:"digest" yaml:"digest"`
+}
+
+// Image defines an image processed by the command
+type Image struct {
+	Name  string `json:"name" yaml:"name"`
+	Chart string `json:"chart" yaml:"chart"`
+	// Source is the original image reference
+	Source string `json:"source" yaml:"source"`
+	// Destination is the relocated image reference, when unwrapping
+	Destination string   `json:"destination,omitempty" yaml:"destination,omitempty"`
+	Digests     []Digest `json:"digests" yaml:"digests"`
+	// Pushed indicates the image was pushed to its destination
+	Pushed bool `json:"pushed,omitempty" yaml:"pushed,omitempty"`
+
+	// wrapChart is the wrapped chart whose Images.lock lists the image
+	wrapChart string
+}
+
+// Artifact defines an artifact pushed by the command
+type Artifact struct {
+	// Type is the kind of artifact: chart or chart-metadata
+	Type      string `json:"type" yaml:"type"`
+	Reference string `json:"reference" yaml:"reference"`
+}
+
+// Step defines a step or section executed by the command
+type Step struct {
+	Title  string `json:"title" yaml:"title"`
+	Status Status `json:"status" yaml:"status"`
+	// Duration is the duration of the step, in seconds
+	Duration float64 `json:"duration" yaml:"duration"`
+}
+
+// Report defines the summary of a wrap or unwrap execution. All its methods can be called on a nil
+// Report, doing nothing, so callers do not need to check whether a report was requested
+type Report struct {
+	mu sync.Mutex
+
+	Command string `json:"command" yaml:"command"`
+	Input   string `json:"input" yaml:"input"`
+	Status  Status `json:"status" yaml:"status"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+	// StartTime and EndTime delimit the execution, whose Duration is in seconds
+	StartTime time.Time  `json:"startTime" yaml:"startTime"`
+	EndTime   time.Time  `json:"endTime" yaml:"endTime"`
+	Duration  float64    `json:"duration" yaml:"duration"`
+	Charts    []Chart    `json:"charts" yaml:"charts"`
+	Output    *File      `json:"output,omitempty" yaml:"output,omitempty"`
+	Images    []*Image   `json:"images" yaml:"images"`
+	Artifacts []Artifact `json:"artifacts" yaml:"artifacts"`
+	Steps     []Step     `json:"steps" yaml:"steps"`
+	Warnings  []string   `json:"warnings" yaml:"warnings"`
+}
+
+// New returns a new Report of the command processing input, started now
+func New(command, input string) *Report {
+	return &Report{
+		Command:   command,
+		Input:     input,
+		StartTime: time.Now().UTC(),
+		Charts:    make([]Chart, 0),
+		Images:    make([]*Image, 0),
+		Artifacts: make([]Artifact, 0),
+		Steps:     make([]Step, 0),
+		Warnings:  make([]string, 0),
+	}
+}
+
+// AddChart adds a Helm chart to the report
+func (r *Report) AddChart(name, version string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Charts = append(r.Charts, Chart{Name: name, Version: version})
+}
+
+func digests(img *imagelock.ChartImage) []Digest {
+	result := make([]Digest, 0, len(img.Digests))
+	for _, d := range img.Digests {
+		result = append(result, Digest{Platform: d.Arch, Digest: d.Digest.String()})
+	}
+	return result
+}
+
+// AddImages adds the images listed in the Images.lock of the wrapped chart
+func (r *Report) AddImages(chart string, images imagelock.ImageList) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, img := range images {
+		r.Images = append(r.Images, &Image{
+			Name: img.Name, Chart: img.Chart, Source: img.Image, Digests: digests(img), wrapChart: chart,
+		})
+	}
+}
+
+// RelocateImages sets the destination and digests of the images of the wrapped chart to the ones of
+// relocated, its relocated Images.lock images, listed in the same order
+func (r *Report) RelocateImages(chart string, relocated imagelock.ImageList) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := 0
+	for _, img := range r.Images {
+		if img.wrapChart != chart {
+			continue
+		}
+		if i >= len(relocated) {
+			return
+		}
+		img.Destination = relocated[i].Image
+		img.Digests = digests(relocated[i])
+		i++
+	}
+}
+
+// SetImagesPushed marks the relocated images as pushed to their destination
+func (r *Report) SetImagesPushed() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, img := range r.Images {
+		if img.Destination != "" {
+			img.Pushed = true
+		}
+	}
+}
+
+// AddArtifact adds a pushed artifact of the given type to the report
+func (r *Report) AddArtifact(artifactType, reference string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Artifacts = append(r.Artifacts, Artifact{Type: artifactType, Reference: reference})
+}
+
+// AddWarning adds a warning message to the report
+func (r *Report) AddWarning(msg string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// addStep adds the result of a step started at start to the report
+func (r *Report) addStep(title string, start time.Time, err error) {
+	if r == nil {
+		return
+	}
+	status := StatusSuccess
+	if err != nil {
+		status = StatusError
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Steps = append(r.Steps, Step{Title: title, Status: status, Duration: time.Since(start).Seconds()})
+}
+
+// SetOutput sets the output file of the report, computing its size and sha256 digest
+func (r *Report) SetOutput(filename string) error {
+	if r == nil {
+		return nil
+	}
+	fh, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer fh.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, fh)
+	if err != nil {
+		return fmt.Errorf("failed to compute output file digest: %w", err)
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Output = &File{Path: filename, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}
+	return nil
+}
+
+// Finish records the end of the execution and its result
+func (r *Report) Finish(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.EndTime = time.Now().UTC()
+	r.Duration = r.EndTime.Sub(r.StartTime).Seconds()
+	r.Status = StatusSuccess
+	if err != nil {
+		r.Status = StatusError
+		r.Error = err.Error()
+	}
+}
+
+// Write writes the report into filename, in YAML format for .yaml and .yml files and in JSON otherwise
+func (r *Report) Write(filename string) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(r)
+	default:
+		data, err = json.MarshalIndent(r, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to serialize report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// Complete finishes the report of an execution ending with err and writes it into filename
+func (r *Report) Complete(filename string, err error) error {
+	if r == nil {
+		return nil
+	}
+	r.Finish(err)
+	return r.Write(filename)
+}
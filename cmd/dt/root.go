@@ -22,12 +22,30 @@ func newRootCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, _ []string) {
 			_ = cmd.Help()
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := config.ApplyEnv(cmd.Flags()); err != nil {
+				return err
+			}
+			if err := mainConfig.ApplyProfile(cmd.Flags()); err != nil {
+				return err
+			}
+			return startTelemetry(cmd)
+		},
 	}
+	cmd.PersistentFlags().StringVar(&mainConfig.ConfigFile, "config", mainConfig.ConfigFile, "configuration file defining the profiles (defaults to $XDG_CONFIG_HOME/dt/config.yaml)")
+	cmd.PersistentFlags().StringVar(&mainConfig.Profile, "profile", mainConfig.Profile, "profile of the configuration file providing the values of the flags not explicitly set")
 	cmd.PersistentFlags().BoolVar(&mainConfig.Insecure, "insecure", mainConfig.Insecure, "skip TLS verification")
 	cmd.PersistentFlags().BoolVar(&mainConfig.UsePlainHTTP, "use-plain-http", mainConfig.UsePlainHTTP, "use plain HTTP when pulling and pushing charts")
+	cmd.PersistentFlags().StringVar(&mainConfig.CredentialsFile, "credentials-file", mainConfig.CredentialsFile, "YAML file mapping registries to their credentials, used when none are explicitly provided")
+	cmd.PersistentFlags().StringVar(&mainConfig.RegistriesFile, "registries-file", mainConfig.RegistriesFile, "YAML file defining the mirrors to pull images from, tried in order before each registry")
+	cmd.PersistentFlags().StringVar(&mainConfig.TLS.CAFile, "ca-file", mainConfig.TLS.CAFile, "PEM bundle of certificate authorities to verify the registries certificates with, in addition to the system ones")
+	cmd.PersistentFlags().StringVar(&mainConfig.TLS.CertFile, "cert-file", mainConfig.TLS.CertFile, "PEM client certificate presented to registries requiring mutual TLS")
+	cmd.PersistentFlags().StringVar(&mainConfig.TLS.KeyFile, "key-file", mainConfig.TLS.KeyFile, "PEM key of the client certificate")
 	cmd.PersistentFlags().StringVar(&mainConfig.AnnotationsKey, "annotations-key", mainConfig.AnnotationsKey, "annotation key used to define the list of included images")
 
 	cmd.PersistentFlags().StringVar(&mainConfig.LogLevel, "log-level", mainConfig.LogLevel, "set log level: (trace, debug, info, warn, error, fatal, panic)")
+	cmd.PersistentFlags().StringVar(&mainConfig.LogFormat, "log-format", mainConfig.LogFormat, "set log format: (text, json), json emitting one event per line")
+	cmd.PersistentFlags().StringVar(&mainConfig.TelemetryFile, "telemetry-file", mainConfig.TelemetryFile, "write OpenTelemetry traces and metrics into the file, one JSON document per line, instead of exporting them as configured by the OTEL_* environment variables")
 	cmd.PersistentFlags().BoolVar(&mainConfig.UsePlainLog, "plain", mainConfig.UsePlainLog, "suppress the progress bar and symbols in messages and display only plain log messages")
 	cmd.PersistentFlags().BoolVar(&config.KeepArtifacts, "keep-artifacts", config.KeepArtifacts, "keep temporary artifacts created during the tool execution")
 
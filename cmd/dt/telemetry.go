@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
+)
+
+// telemetryFlushTimeout bounds the time spent exporting the pending telemetry before exiting
+const telemetryFlushTimeout = 10 * time.Second
+
+// stopTelemetry ends the span of the executed command, recording its result, and flushes the telemetry
+var stopTelemetry = func(error) {}
+
+// startTelemetry configures the telemetry exporters and starts the span of the executed command,
+// parent of the spans of all its operations
+func startTelemetry(cmd *cobra.Command) error {
+	shutdown, err := telemetry.Setup(mainConfig.Context,
+		telemetry.WithFile(mainConfig.TelemetryFile), telemetry.WithService("dt", Version))
+	if err != nil {
+		return err
+	}
+	ctx, span := telemetry.StartSpan(mainConfig.Context, cmd.CommandPath())
+	mainConfig.Context = ctx
+
+	stopTelemetry = func(cmdErr error) {
+		telemetry.EndSpan(span, cmdErr)
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryFlushTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			mainConfig.Logger().Warnf("%v", err)
+		}
+	}
+	return nil
+}
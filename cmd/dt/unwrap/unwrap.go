@@ -2,15 +2,19 @@
 package unwrap
 
 import (
+	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/config"
-	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/push"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/report"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/verify"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/wrap"
 	"github.com/vmware-labs/distribution-tooling-for-helm/internal/widgets"
@@ -19,6 +23,9 @@ import (
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/silent"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
 
@@ -48,8 +55,16 @@ type Config struct {
 	FetchArtifacts        bool
 	Auth                  Auth
 	ContainerRegistryAuth Auth
+	CredentialsFile       string
+	RegistriesFile        string
+	TLS                   registries.TLS
 	ValuesFiles           []string
 	PreserveRepository    bool
+	Streaming             bool
+	ChartRepoDir          string
+	OCILayoutDir          string
+	// Report, if set, collects the summary of the unwrap
+	Report *report.Report
 
 	// Interactive enables interacting with the user
 	Interactive bool
@@ -82,6 +97,27 @@ func WithContainerRegistryAuth(username, password string) func(c *Config) {
 	}
 }
 
+// WithCredentialsFile configures the CredentialsFile of the unwrap Config
+func WithCredentialsFile(file string) func(c *Config) {
+	return func(c *Config) {
+		c.CredentialsFile = file
+	}
+}
+
+// WithRegistriesFile configures the registries file defining the per-registry settings of the unwrap Config
+func WithRegistriesFile(file string) func(c *Config) {
+	return func(c *Config) {
+		c.RegistriesFile = file
+	}
+}
+
+// WithTLS configures the TLS certificate authorities and client certificate used to access the registries
+func WithTLS(caFile, certFile, keyFile string) func(c *Config) {
+	return func(c *Config) {
+		c.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
 // WithSayYes configures the SayYes of the WrapConfig
 func WithSayYes(sayYes bool) func(c *Config) {
 	return func(c *Config) {
@@ -228,6 +264,34 @@ func WithPreserveRepository(preserve bool) func(c *Config) {
 	}
 }
 
+// WithStreaming configures the Streaming of the Config
+func WithStreaming(streaming bool) func(c *Config) {
+	return func(c *Config) {
+		c.Streaming = streaming
+	}
+}
+
+// WithChartRepoDir configures the Helm repository directory to add the unwrapped chart to
+func WithChartRepoDir(dir string) func(c *Config) {
+	return func(c *Config) {
+		c.ChartRepoDir = dir
+	}
+}
+
+// WithOCILayoutDir configures the OCI image layout directory to write the unwrapped images and chart to
+func WithOCILayoutDir(dir string) func(c *Config) {
+	return func(c *Config) {
+		c.OCILayoutDir = dir
+	}
+}
+
+// WithReport configures the Report collecting the summary of the unwrap
+func WithReport(r *report.Report) func(c *Config) {
+	return func(c *Config) {
+		c.Report = r
+	}
+}
+
 // NewConfig returns a new WrapConfig with default values
 func NewConfig(opts ...Option) *Config {
 	cfg := &Config{
@@ -266,16 +330,29 @@ func askYesNoQuestion(msg string, cfg *Config) bool {
 	return widgets.ShowYesNoQuestion(msg)
 }
 
-func unwrapChart(inputChart, registryURL, pushChartURL string, opts ...Option) (string, error) {
+func unwrapChart(inputChart, registryURL, pushChartURL string, opts ...Option) (_ string, err error) {
 
 	cfg := NewConfig(opts...)
 
-	ctx := cfg.Context
+	ctx, span := telemetry.StartSpan(cfg.Context, "UnwrapChart",
+		attribute.String("dt.input", inputChart), attribute.String("dt.registry", registryURL))
+	defer func() { telemetry.EndSpan(span, err) }()
+	// Operations configured from opts are traced inside the unwrap span too
+	opts = append(opts, WithContext(ctx))
+	cfg.Context = ctx
 	parentLog := cfg.GetLogger()
 
 	if registryURL == "" {
 		return "", fmt.Errorf("the registry cannot be empty")
 	}
+	if cfg.OCILayoutDir != "" {
+		if cfg.Streaming {
+			return "", fmt.Errorf("images cannot be streamed when writing them to an OCI layout")
+		}
+		if artifacts.IsHTTPRepoURL(pushChartURL) {
+			return "", fmt.Errorf("the Helm chart URL must be an OCI registry when writing to an OCI layout")
+		}
+	}
 
 	tempDir, err := cfg.GetTemporaryDirectory()
 	if err != nil {
@@ -288,35 +365,60 @@ func unwrapChart(inputChart, registryURL, pushChartURL string, opts ...Option) (
 		l.Debugf("Temporary assets kept at %q", tempDir)
 	}
 
-	chartPath, err := wrap.ResolveInputChartPath(
-		inputChart,
-		wrap.NewConfig(
-			wrap.WithTempDirectory(cfg.TempDirectory),
-			wrap.WithLogger(l),
-			wrap.WithVersion(cfg.Version),
-			wrap.WithInsecure(cfg.Insecure),
-			wrap.WithUsePlainHTTP(cfg.UsePlainHTTP),
-		),
-	)
+	// When streaming, the images are pushed straight from the wrap file
+	var streamFrom string
+	if cfg.Streaming {
+		if isTar, _ := utils.IsTarFile(inputChart); isTar {
+			streamFrom = inputChart
+		} else {
+			l.Debugf("%q is not a wrap file, images will be pushed from disk", inputChart)
+		}
+	}
+
+	var chartPath string
+	if streamFrom != "" {
+		chartPath, err = untarWithoutImages(inputChart, tempDir, l)
+	} else {
+		chartPath, err = wrap.ResolveInputChartPath(
+			inputChart,
+			wrap.NewConfig(
+				wrap.WithTempDirectory(cfg.TempDirectory),
+				wrap.WithLogger(l),
+				wrap.WithVersion(cfg.Version),
+				wrap.WithInsecure(cfg.Insecure),
+				wrap.WithCredentialsFile(cfg.CredentialsFile),
+				wrap.WithRegistriesFile(cfg.RegistriesFile),
+				wrap.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+				wrap.WithUsePlainHTTP(cfg.UsePlainHTTP),
+			),
+		)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve input chart path: %w", err)
 	}
 
+	if wrapping.IsMultiWrap(chartPath) {
+		return "", unwrapCharts(chartPath, streamFrom, registryURL, pushChartURL, l, opts...)
+	}
+
 	wrap, err := wrapping.Load(chartPath)
 	if err != nil {
 		return "", err
 	}
-	if err := l.ExecuteStep(fmt.Sprintf("Relocating %q with prefix %q", wrap.ChartDir(), registryURL), func() error {
-		return relocator.RelocateChartDir(
-			wrap.ChartDir(), registryURL, relocator.WithLog(l),
-			relocator.Recursive, relocator.WithAnnotationsKey(cfg.AnnotationsKey), relocator.WithValuesFiles(cfg.ValuesFiles...),
-			relocator.WithSkipImageRelocation(cfg.SkipImageRelocation),
-			relocator.WithPreserveRepository(cfg.PreserveRepository),
-		)
-	}); err != nil {
-		return "", l.Failf("failed to relocate %q: %w", chartPath, err)
+	if err := relocateWrap(wrap, registryURL, cfg, l); err != nil {
+		return "", err
+	}
+	if err := selectPlatforms(cfg, l, wrap); err != nil {
+		return "", err
+	}
+	reportRelocatedImages(cfg, wrap)
+
+	if cfg.OCILayoutDir != "" {
+		if pushChartURL == "" {
+			pushChartURL = registryURL
+		}
+		return "", writeToOCILayout(wrap, []wrapping.Wrap{wrap}, pushChartURL, l, opts...)
 	}
-	l.Infof("Helm chart relocated successfully")
 
 	images := getImageList(wrap, l)
 
@@ -327,14 +429,25 @@ func unwrapChart(inputChart, registryURL, pushChartURL string, opts ...Option) (
 		}
 		if askYesNoQuestion(l.PrefixText("Do you want to push the wrapped images to the OCI registry?"), cfg) {
 			if err := l.Section("Pushing Images", func(subLog dtlog.SectionLogger) error {
-				return pushChartImagesAndVerify(ctx, wrap, NewConfig(append(opts, WithLogger(subLog))...))
+				return pushChartImagesAndVerify(ctx, wrap, streamFrom, NewConfig(append(opts, WithLogger(subLog))...))
 			}); err != nil {
 				return "", l.Failf("Failed to push images: %w", err)
 			}
+			cfg.Report.SetImagesPushed()
 			l.Printf(widgets.TerminalSpacer)
 		}
 	}
 
+	if cfg.ChartRepoDir != "" {
+		if err := l.ExecuteStep(fmt.Sprintf("Adding Helm chart to the Helm repository at %q", cfg.ChartRepoDir), func() error {
+			return addChartToRepoDir(wrap, cfg.ChartRepoDir, cfg)
+		}); err != nil {
+			return "", l.Failf("Failed to add Helm chart to the Helm repository: %w", err)
+		}
+		l.Infof("Helm chart successfully added to %q", cfg.ChartRepoDir)
+		return "", nil
+	}
+
 	if askYesNoQuestion(l.PrefixText("Do you want to push the Helm chart to the OCI registry?"), cfg) {
 		if pushChartURL == "" {
 			pushChartURL = registryURL
@@ -343,17 +456,23 @@ func unwrapChart(inputChart, registryURL, pushChartURL string, opts ...Option) (
 		}
 		pushChartURL = normalizeOCIURL(pushChartURL)
 		fullChartURL := fmt.Sprintf("%s/%s", pushChartURL, wrap.Chart().Name())
+		if artifacts.IsHTTPRepoURL(pushChartURL) {
+			// Charts in Helm repositories cannot be installed by URL
+			fullChartURL = ""
+		}
 
 		if err := l.ExecuteStep(fmt.Sprintf("Pushing Helm chart to %q", pushChartURL), func() error {
 			return utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
 				if try > 0 {
 					l.Debugf("Failed to push Helm chart: %v", prevErr)
+					telemetry.RecordRetry(ctx, "push_chart", try, prevErr)
 				}
 				return pushChart(ctx, wrap, pushChartURL, cfg)
 			})
 		}); err != nil {
 			return "", l.Failf("Failed to push Helm chart: %w", err)
 		}
+		reportPushedChart(cfg, wrap, pushChartURL)
 
 		l.Infof("Helm chart successfully pushed")
 		return fullChartURL, nil
@@ -361,6 +480,251 @@ func unwrapChart(inputChart, registryURL, pushChartURL string, opts ...Option) (
 	return "", nil
 }
 
+// unwrapCharts relocates all the Helm charts bundled in a multi-chart wrap, pushing
+// their shared images only once
+func unwrapCharts(wrapPath, streamFrom, registryURL, pushChartURL string, l dtlog.SectionLogger, opts ...Option) error {
+	cfg := NewConfig(opts...)
+	ctx := cfg.Context
+
+	wrap, err := wrapping.LoadMulti(wrapPath, chartutils.WithAnnotationsKey(cfg.AnnotationsKey))
+	if err != nil {
+		return err
+	}
+	for _, chartWrap := range wrap.Charts() {
+		if err := relocateWrap(chartWrap, registryURL, cfg, l); err != nil {
+			return err
+		}
+	}
+	if !cfg.SkipImageRelocation {
+		if err := relocator.RelocateLockFile(wrap.LockFilePath(), registryURL, cfg.PreserveRepository); err != nil {
+			return l.Failf("failed to relocate Images.lock file: %w", err)
+		}
+	}
+	lockables := []wrapping.Lockable{wrap}
+	for _, chartWrap := range wrap.Charts() {
+		lockables = append(lockables, chartWrap)
+	}
+	if err := selectPlatforms(cfg, l, lockables...); err != nil {
+		return err
+	}
+	reportRelocatedImages(cfg, wrap.Charts()...)
+
+	if cfg.OCILayoutDir != "" {
+		if pushChartURL == "" {
+			pushChartURL = registryURL
+		}
+		return writeToOCILayout(wrap, wrap.Charts(), pushChartURL, l, opts...)
+	}
+
+	images := getImageList(wrap, l)
+
+	if len(images) > 0 && !cfg.SkipPullImages {
+		if cfg.Interactive {
+			showImagesSummary(images, l)
+		}
+		if askYesNoQuestion(l.PrefixText("Do you want to push the wrapped images to the OCI registry?"), cfg) {
+			if err := l.Section("Pushing Images", func(subLog dtlog.SectionLogger) error {
+				subCfg := NewConfig(append(opts, WithLogger(subLog))...)
+				if err := pushWrapImages(ctx, wrap, streamFrom, subCfg); err != nil {
+					return err
+				}
+				for _, chartWrap := range wrap.Charts() {
+					if err := verifyChartLock(chartWrap, subCfg); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return l.Failf("Failed to push images: %w", err)
+			}
+			cfg.Report.SetImagesPushed()
+			l.Printf(widgets.TerminalSpacer)
+		}
+	}
+
+	if cfg.ChartRepoDir != "" {
+		for _, chartWrap := range wrap.Charts() {
+			chartName := chartWrap.Chart().Name()
+			if err := l.ExecuteStep(fmt.Sprintf("Adding Helm chart %q to the Helm repository at %q", chartName, cfg.ChartRepoDir), func() error {
+				return addChartToRepoDir(chartWrap, cfg.ChartRepoDir, cfg)
+			}); err != nil {
+				return l.Failf("Failed to add Helm chart %q to the Helm repository: %w", chartName, err)
+			}
+		}
+		l.Infof("Helm charts successfully added to %q", cfg.ChartRepoDir)
+		return nil
+	}
+
+	if askYesNoQuestion(l.PrefixText("Do you want to push the Helm charts to the OCI registry?"), cfg) {
+		if pushChartURL == "" {
+			pushChartURL = registryURL
+			// we will push the charts to the same registry as the containers
+			cfg.Auth = cfg.ContainerRegistryAuth
+		}
+		pushChartURL = normalizeOCIURL(pushChartURL)
+
+		for _, chartWrap := range wrap.Charts() {
+			chartName := chartWrap.Chart().Name()
+			if err := l.ExecuteStep(fmt.Sprintf("Pushing Helm chart %q to %q", chartName, pushChartURL), func() error {
+				return utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
+					if try > 0 {
+						l.Debugf("Failed to push Helm chart: %v", prevErr)
+						telemetry.RecordRetry(ctx, "push_chart", try, prevErr)
+					}
+					return pushChart(ctx, chartWrap, pushChartURL, cfg)
+				})
+			}); err != nil {
+				return l.Failf("Failed to push Helm chart %q: %w", chartName, err)
+			}
+			reportPushedChart(cfg, chartWrap, pushChartURL)
+			l.Infof("Helm chart %q successfully pushed to %q", chartName, fmt.Sprintf("%s/%s", pushChartURL, chartName))
+		}
+	}
+	return nil
+}
+
+// writeToOCILayout writes the relocated images of the wrap and its charts into the configured OCI layout,
+// instead of pushing them. Charts are referenced under chartURL unless they are added to a Helm repository directory
+func writeToOCILayout(wrap wrapping.Bundle, charts []wrapping.Wrap, chartURL string, l dtlog.SectionLogger, opts ...Option) error {
+	cfg := NewConfig(opts...)
+	layoutDir := cfg.OCILayoutDir
+
+	if lock, err := wrap.GetImagesLock(); err != nil {
+		l.Debugf("failed to load list of images: failed to load lock file: %v", err)
+	} else if len(lock.Images) > 0 && !cfg.SkipPullImages {
+		if err := l.Section(fmt.Sprintf("Writing images to the OCI layout at %q", layoutDir), func(subLog dtlog.SectionLogger) error {
+			if err := chartutils.WriteImagesToLayout(lock, wrap.ImagesDir(), layoutDir,
+				chartutils.WithLog(silent.NewLogger()),
+				chartutils.WithContext(cfg.Context),
+				chartutils.WithArtifactsDir(wrap.ImageArtifactsDir()),
+				chartutils.WithProgressBar(subLog.ProgressBar()),
+			); err != nil {
+				return err
+			}
+			subLog.Infof("All images written successfully")
+			return nil
+		}); err != nil {
+			return l.Failf("Failed to write images: %w", err)
+		}
+		l.Printf(widgets.TerminalSpacer)
+	}
+
+	lp, err := artifacts.OpenLayout(layoutDir)
+	if err != nil {
+		return l.Failf("Failed to write Helm charts: %w", err)
+	}
+	for _, chartWrap := range charts {
+		chart := chartWrap.Chart()
+		if cfg.ChartRepoDir != "" {
+			if err := l.ExecuteStep(fmt.Sprintf("Adding Helm chart %q to the Helm repository at %q", chart.Name(), cfg.ChartRepoDir), func() error {
+				return addChartToRepoDir(chartWrap, cfg.ChartRepoDir, cfg)
+			}); err != nil {
+				return l.Failf("Failed to add Helm chart %q to the Helm repository: %w", chart.Name(), err)
+			}
+			continue
+		}
+		// Tags cannot contain '+', so Helm replaces it when pushing charts
+		ref := fmt.Sprintf("%s/%s:%s", strings.TrimPrefix(normalizeOCIURL(chartURL), "oci://"), chart.Name(), strings.ReplaceAll(chart.Version(), "+", "_"))
+		if err := l.ExecuteStep(fmt.Sprintf("Writing Helm chart %q to the OCI layout", ref), func() error {
+			tarFile, err := packageChart(chartWrap, cfg)
+			if err != nil {
+				return err
+			}
+			return artifacts.WriteChartToLayout(lp, tarFile, ref, filepath.Join(chart.RootDir(), artifacts.HelmChartArtifactMetadataDir))
+		}); err != nil {
+			return l.Failf("Failed to write Helm chart %q: %w", chart.Name(), err)
+		}
+	}
+	l.Infof("Wrap successfully written to the OCI layout at %q", layoutDir)
+	return nil
+}
+
+// selectPlatforms rewrites the Images.lock files of the provided wraps keeping only the configured
+// platforms, so just their images are pushed
+func selectPlatforms(cfg *Config, l dtlog.SectionLogger, wraps ...wrapping.Lockable) error {
+	if len(cfg.Platforms) == 0 {
+		return nil
+	}
+	if err := l.ExecuteStep(fmt.Sprintf("Selecting platforms %s", strings.Join(cfg.Platforms, ", ")), func() error {
+		for _, w := range wraps {
+			if !utils.FileExists(w.LockFilePath()) {
+				continue
+			}
+			lock, err := w.GetImagesLock()
+			if err != nil {
+				return fmt.Errorf("failed to load Images.lock: %w", err)
+			}
+			if err := lock.FilterPlatforms(cfg.Platforms); err != nil {
+				return err
+			}
+			fh, err := os.Create(w.LockFilePath())
+			if err != nil {
+				return fmt.Errorf("failed to write Images.lock: %w", err)
+			}
+			err = lock.ToYAML(fh)
+			fh.Close()
+			if err != nil {
+				return fmt.Errorf("failed to write Images.lock: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return l.Failf("Failed to select platforms: %w", err)
+	}
+	return nil
+}
+
+func relocateWrap(wrap wrapping.Wrap, registryURL string, cfg *Config, l dtlog.SectionLogger) error {
+	if cfg.Report != nil {
+		chart := wrap.Chart()
+		cfg.Report.AddChart(chart.Name(), chart.Version())
+		if lock, err := wrap.GetImagesLock(); err == nil {
+			cfg.Report.AddImages(chart.Name(), lock.Images)
+		}
+	}
+	if err := l.ExecuteStep(fmt.Sprintf("Relocating %q with prefix %q", wrap.ChartDir(), registryURL), func() error {
+		return relocator.RelocateChartDir(
+			wrap.ChartDir(), registryURL, relocator.WithLog(l),
+			relocator.Recursive, relocator.WithAnnotationsKey(cfg.AnnotationsKey), relocator.WithValuesFiles(cfg.ValuesFiles...),
+			relocator.WithSkipImageRelocation(cfg.SkipImageRelocation),
+			relocator.WithPreserveRepository(cfg.PreserveRepository),
+		)
+	}); err != nil {
+		return l.Failf("failed to relocate %q: %w", wrap.ChartDir(), err)
+	}
+	l.Infof("Helm chart relocated successfully")
+	return nil
+}
+
+// reportRelocatedImages records the relocated images of the wraps into the report
+func reportRelocatedImages(cfg *Config, wraps ...wrapping.Wrap) {
+	if cfg.Report == nil {
+		return
+	}
+	for _, w := range wraps {
+		if lock, err := w.GetImagesLock(); err == nil {
+			cfg.Report.RelocateImages(w.Chart().Name(), lock.Images)
+		}
+	}
+}
+
+// reportPushedChart records the Helm chart of the wrap, and its metadata, pushed to pushChartURL into the report
+func reportPushedChart(cfg *Config, wrap wrapping.Wrap, pushChartURL string) {
+	if cfg.Report == nil {
+		return
+	}
+	if artifacts.IsHTTPRepoURL(pushChartURL) {
+		cfg.Report.AddArtifact("chart", pushChartURL)
+		return
+	}
+	chart := wrap.Chart()
+	ref := fmt.Sprintf("%s/%s:%s", pushChartURL, chart.Name(), chart.Version())
+	cfg.Report.AddArtifact("chart", ref)
+	if utils.FileExists(filepath.Join(chart.RootDir(), artifacts.HelmChartArtifactMetadataDir)) {
+		cfg.Report.AddArtifact("chart-metadata", ref)
+	}
+}
+
 func unwrapContainer(inputContainer, registryURL string, opts ...Option) (string, error) {
 	cfg := NewConfig(opts...)
 
@@ -389,6 +753,9 @@ func unwrapContainer(inputContainer, registryURL string, opts ...Option) (string
 			wrap.WithLogger(l),
 			wrap.WithVersion(cfg.Version),
 			wrap.WithInsecure(cfg.Insecure),
+			wrap.WithCredentialsFile(cfg.CredentialsFile),
+			wrap.WithRegistriesFile(cfg.RegistriesFile),
+			wrap.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 			wrap.WithUsePlainHTTP(cfg.UsePlainHTTP),
 		),
 	)
@@ -412,6 +779,9 @@ func unwrapContainer(inputContainer, registryURL string, opts ...Option) (string
 			return "", fmt.Errorf("failed to relocate Images.lock file: %v", err)
 		}
 	}
+	if err := selectPlatforms(cfg, l, wrapContainer); err != nil {
+		return "", err
+	}
 
 	if len(images) > 0 && !cfg.SkipPullImages {
 		// If we are not in interactive mode, we do not show the list of images
@@ -430,26 +800,95 @@ func unwrapContainer(inputContainer, registryURL string, opts ...Option) (string
 	return "", nil
 }
 
-func pushChartImagesAndVerify(ctx context.Context, wrap wrapping.Wrap, cfg *Config) error {
-	lockFile := wrap.LockFilePath()
+// untarWithoutImages uncompresses the wrap file skipping its images directory, which
+// will be streamed from the file when pushing
+func untarWithoutImages(inputChart string, tempDir string, l dtlog.SectionLogger) (string, error) {
+	var chartPath string
+	if err := l.ExecuteStep("Uncompressing Helm chart without images", func() error {
+		dir, err := os.MkdirTemp(tempDir, "dt-wrap*")
+		if err != nil {
+			return fmt.Errorf("failed to create sandbox directory")
+		}
+		chartPath = dir
+		return utils.Untar(inputChart, dir, utils.TarConfig{
+			StripComponents: 1,
+			Skip: func(f string) bool {
+				return f == "images" || strings.HasPrefix(f, "images/")
+			},
+		})
+	}); err != nil {
+		return "", l.Failf("Failed to uncompress %q: %w", inputChart, err)
+	}
+	l.Infof("Helm chart uncompressed to %q", chartPath)
+	return chartPath, nil
+}
 
-	l := cfg.GetLogger()
+// getTarPrefix returns the top level directory of the wrap file
+func getTarPrefix(ctx context.Context, tarFile string) (string, error) {
+	var prefix string
+	if err := utils.WalkTarFile(ctx, tarFile, func(_ *tar.Reader, header *tar.Header) error {
+		prefix, _, _ = strings.Cut(path.Clean(header.Name), "/")
+		return utils.ErrEndTarWalk
+	}); err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		return "", fmt.Errorf("%q is empty", tarFile)
+	}
+	return prefix, nil
+}
+
+func pushChartImagesAndVerify(ctx context.Context, wrap wrapping.Wrap, streamFrom string, cfg *Config) error {
+	lockFile := wrap.LockFilePath()
 	if !utils.FileExists(lockFile) {
 		return fmt.Errorf("lock file %q does not exist", lockFile)
 	}
-	if err := push.ChartImages(
-		wrap,
-		wrap.ImagesDir(),
+	if err := pushWrapImages(ctx, wrap, streamFrom, cfg); err != nil {
+		return err
+	}
+	return verifyChartLock(wrap, cfg)
+}
+
+// pushWrapImages pushes the images of the wrap, either from disk or straight from the streamFrom wrap file
+func pushWrapImages(ctx context.Context, wrap wrapping.Bundle, streamFrom string, cfg *Config) error {
+	l := cfg.GetLogger()
+	lock, err := wrap.GetImagesLock()
+	if err != nil {
+		return fmt.Errorf("failed to load Images.lock: %v", err)
+	}
+	pushOpts := []chartutils.Option{
 		chartutils.WithLog(silent.NewLogger()),
 		chartutils.WithContext(ctx),
 		chartutils.WithArtifactsDir(wrap.ImageArtifactsDir()),
 		chartutils.WithProgressBar(l.ProgressBar()),
 		chartutils.WithInsecureMode(cfg.Insecure),
 		chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
-	); err != nil {
+		chartutils.WithCredentialsFile(cfg.CredentialsFile),
+		chartutils.WithRegistriesFile(cfg.RegistriesFile),
+		chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+	}
+	if streamFrom != "" {
+		prefix, err := getTarPrefix(ctx, streamFrom)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", streamFrom, err)
+		}
+		imagesDir, err := filepath.Rel(wrap.RootDir(), wrap.ImagesDir())
+		if err != nil {
+			return err
+		}
+		if err := chartutils.PushImagesFromTar(lock, streamFrom, path.Join(prefix, filepath.ToSlash(imagesDir)), pushOpts...); err != nil {
+			return err
+		}
+	} else if err := chartutils.PushImages(lock, wrap.ImagesDir(), pushOpts...); err != nil {
 		return err
 	}
 	l.Infof("All images pushed successfully")
+	return nil
+}
+
+func verifyChartLock(wrap wrapping.Wrap, cfg *Config) error {
+	l := cfg.GetLogger()
+	lockFile := wrap.LockFilePath()
 	if err := l.ExecuteStep("Verifying Images.lock", func() error {
 
 		return verify.Lock(wrap.ChartDir(), lockFile, verify.Config{
@@ -480,7 +919,10 @@ func pushImages(ctx context.Context, wrap wrapping.WrapContainer, cfg *Config) e
 		chartutils.WithArtifactsDir(wrap.ImageArtifactsDir()),
 		chartutils.WithProgressBar(l.ProgressBar()),
 		chartutils.WithInsecureMode(cfg.Insecure),
-		chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password))
+		chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+		chartutils.WithCredentialsFile(cfg.CredentialsFile),
+		chartutils.WithRegistriesFile(cfg.RegistriesFile),
+		chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile))
 }
 
 func getImageList(wrap wrapping.Lockable, l dtlog.SectionLogger) imagelock.ImageList {
@@ -515,46 +957,81 @@ func normalizeOCIURL(url string) string {
 	return url
 }
 
-func pushChart(ctx context.Context, wrap wrapping.Wrap, pushChartURL string, cfg *Config) error {
-	var tmpDir, dir string
-	var err error
-	tmpDir, err = cfg.GetTemporaryDirectory()
+// packageChart packages the wrapped chart into a temporary tarball
+func packageChart(wrap wrapping.Wrap, cfg *Config) (string, error) {
+	tmpDir, err := cfg.GetTemporaryDirectory()
 	if err != nil {
-		return fmt.Errorf("failed to get temp dir: %w", err)
+		return "", fmt.Errorf("failed to get temp dir: %w", err)
 	}
 
-	dir, err = os.MkdirTemp(tmpDir, "chart-*")
+	dir, err := os.MkdirTemp(tmpDir, "chart-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
 	chart := wrap.Chart()
 	chartPath := chart.RootDir()
-	tempTarFile := filepath.Join(dir, fmt.Sprintf("%s.tgz", chart.Name()))
+	tempTarFile := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", chart.Name(), chart.Version()))
 	if err = utils.Tar(chartPath, tempTarFile, utils.TarConfig{
 		Prefix: chart.Name(),
 	}); err != nil {
-		return fmt.Errorf("failed to untar filename %q: %w", chartPath, err)
+		return "", fmt.Errorf("failed to untar filename %q: %w", chartPath, err)
 	}
+	return tempTarFile, nil
+}
 
-	tmpDir, err = cfg.GetTemporaryDirectory()
+// addChartToRepoDir adds the wrapped chart to the Helm repository at repoDir
+func addChartToRepoDir(wrap wrapping.Wrap, repoDir string, cfg *Config) error {
+	tarFile, err := packageChart(wrap, cfg)
+	if err != nil {
+		return err
+	}
+	if err := artifacts.AddChartToRepoDir(tarFile, repoDir); err != nil {
+		return err
+	}
+	cfg.Report.AddArtifact("chart", filepath.Join(repoDir, filepath.Base(tarFile)))
+	return nil
+}
+
+func pushChart(ctx context.Context, wrap wrapping.Wrap, pushChartURL string, cfg *Config) error {
+	tempTarFile, err := packageChart(wrap, cfg)
+	if err != nil {
+		return err
+	}
+
+	if artifacts.IsHTTPRepoURL(pushChartURL) {
+		return artifacts.UploadChart(tempTarFile, pushChartURL,
+			artifacts.WithInsecure(cfg.Insecure),
+			artifacts.WithRegistryRegistriesFile(cfg.RegistriesFile),
+			artifacts.WithRegistryTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+			artifacts.WithRegistryAuth(cfg.Auth.Username, cfg.Auth.Password),
+		)
+	}
+
+	tmpDir, err := cfg.GetTemporaryDirectory()
 	if err != nil {
 		return fmt.Errorf("failed to get temp dir: %w", err)
 	}
 
 	if err = artifacts.PushChart(tempTarFile, pushChartURL,
 		artifacts.WithInsecure(cfg.Insecure),
+		artifacts.WithRegistryRegistriesFile(cfg.RegistriesFile),
+		artifacts.WithRegistryTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 		artifacts.WithPlainHTTP(cfg.UsePlainHTTP),
 		artifacts.WithRegistryAuth(cfg.Auth.Username, cfg.Auth.Password),
+		artifacts.WithRegistryCredentialsFile(cfg.CredentialsFile),
 		artifacts.WithTempDir(tmpDir),
 	); err != nil {
 		return err
 	}
+	chart := wrap.Chart()
 	fullChartURL := fmt.Sprintf("%s/%s", pushChartURL, chart.Name())
 
 	metadataArtifactDir := filepath.Join(chart.RootDir(), artifacts.HelmChartArtifactMetadataDir)
 	if utils.FileExists(metadataArtifactDir) {
-		return artifacts.PushChartMetadata(ctx, fmt.Sprintf("%s:%s", fullChartURL, chart.Version()), metadataArtifactDir, artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password))
+		return artifacts.PushChartMetadata(ctx, fmt.Sprintf("%s:%s", fullChartURL, chart.Version()), metadataArtifactDir,
+			artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password), artifacts.WithCredentialsFile(cfg.CredentialsFile),
+			artifacts.WithRegistriesFile(cfg.RegistriesFile), artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile))
 	}
 	return nil
 }
@@ -567,6 +1044,11 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 		version             string
 		skipImageRelocation bool
 		skipPullImages      bool
+		streaming           bool
+		chartRepoDir        string
+		ociLayoutDir        string
+		platforms           []string
+		outputReport        string
 	)
 	valuesFiles := []string{"values.yaml"}
 	cmd := &cobra.Command{
@@ -575,14 +1057,37 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 		Long:  "Unwraps a wrapped package and moves it into a target OCI registry. This command will read a wrap tarball and push all its container images and Helm chart into the target OCI registry",
 		Example: `  # Unwrap a Helm chart and push it into a Harbor repository
   $ dt unwrap mariadb-12.2.8.wrap.tgz oci://demo.goharbor.io/test_repo
+
+  # Unwrap a Helm chart pushing its images straight from the wrap file, without extracting them to disk
+  $ dt unwrap mariadb-12.2.8.wrap.tgz oci://demo.goharbor.io/test_repo --stream
+
+  # Unwrap a Helm chart adding it to a static Helm repository directory instead of pushing it
+  $ dt unwrap mariadb-12.2.8.wrap.tgz oci://demo.goharbor.io/test_repo --chart-repo-dir /srv/charts
+
+  # Unwrap a Helm chart uploading it to a ChartMuseum repository
+  $ dt unwrap mariadb-12.2.8.wrap.tgz oci://demo.goharbor.io/test_repo --push-chart-url https://charts.example.com
+
+  # Unwrap a Helm chart relocated to a Harbor repository into a local OCI layout, without pushing anything
+  $ dt unwrap mariadb-12.2.8.wrap.tgz oci://demo.goharbor.io/test_repo --to-oci-layout ./mariadb-layout
+
+  # Unwrap a Helm chart pushing only its linux/arm64 images
+  $ dt unwrap mariadb-12.2.8.wrap.tgz oci://demo.goharbor.io/test_repo --platforms linux/arm64
 `,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		Args:          cobra.ExactArgs(2),
 		RunE: func(_ *cobra.Command, args []string) error {
-			l := cfg.Logger()
-
 			inputChart, registryURL := args[0], args[1]
+
+			var unwrapReport *report.Report
+			if outputReport != "" {
+				unwrapReport = report.New("unwrap", inputChart)
+			}
+			l := unwrapReport.Logger(cfg.Logger())
+
+			if chartRepoDir != "" && pushChartURL != "" {
+				return fmt.Errorf("--chart-repo-dir and --push-chart-url cannot be used together")
+			}
 			ctx, cancel := cfg.ContextWithSigterm()
 			defer cancel()
 
@@ -598,16 +1103,27 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 				WithInteractive(true),
 				WithAnnotationsKey(cfg.AnnotationsKey),
 				WithInsecure(cfg.Insecure),
+				WithCredentialsFile(cfg.CredentialsFile),
+				WithRegistriesFile(cfg.RegistriesFile),
+				WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				WithTempDirectory(tempDir),
 				WithUsePlainHTTP(cfg.UsePlainHTTP),
 				WithValuesFiles(valuesFiles...),
 				WithSkipImageRelocation(skipImageRelocation),
 				WithSkipPullImages(skipPullImages),
+				WithStreaming(streaming),
+				WithChartRepoDir(chartRepoDir),
+				WithOCILayoutDir(ociLayoutDir),
+				WithPlatforms(platforms),
+				WithReport(unwrapReport),
 			)
-			if err != nil {
-				return err
+			if reportErr := unwrapReport.Complete(outputReport, err); err != nil || reportErr != nil {
+				return errors.Join(err, reportErr)
 			}
 			var successMessage = "Helm chart unwrapped successfully"
+			if ociLayoutDir != "" {
+				successMessage = fmt.Sprintf("Helm chart unwrapped successfully into the OCI layout at %q", ociLayoutDir)
+			}
 			if fullChartURL != "" {
 				successMessage = fmt.Sprintf(`%s: You can use it now by running "helm install %s --generate-name"`, successMessage, fullChartURL)
 			}
@@ -618,11 +1134,16 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVar(&version, "version", version, "when unwrapping remote Helm charts from OCI, version to request")
-	cmd.PersistentFlags().StringVar(&pushChartURL, "push-chart-url", pushChartURL, "push the unwrapped Helm chart to the given URL")
+	cmd.PersistentFlags().StringVar(&pushChartURL, "push-chart-url", pushChartURL, "push the unwrapped Helm chart to the given URL, either an OCI registry or a ChartMuseum compatible http(s) Helm repository")
+	cmd.PersistentFlags().StringVar(&chartRepoDir, "chart-repo-dir", chartRepoDir, "add the unwrapped Helm chart to the Helm repository in the given directory, updating its index.yaml, instead of pushing it")
+	cmd.PersistentFlags().StringVar(&ociLayoutDir, "to-oci-layout", ociLayoutDir, "write the relocated images and Helm chart into the OCI image layout in the given directory instead of pushing them to the registry")
 	cmd.PersistentFlags().BoolVar(&sayYes, "yes", sayYes, "respond 'yes' to any yes/no question")
 	cmd.PersistentFlags().StringSliceVar(&valuesFiles, "values", valuesFiles, "values files to relocate images (can specify multiple)")
 	cmd.PersistentFlags().BoolVar(&skipImageRelocation, "skip-image-relocation", skipImageRelocation, "Skip relocating image references in the different files")
 	cmd.PersistentFlags().BoolVar(&skipPullImages, "skip-pull-images", skipPullImages, "Skip pulling images")
+	cmd.PersistentFlags().BoolVar(&streaming, "stream", streaming, "push the images directly from the wrap file instead of extracting them to disk first")
+	cmd.PersistentFlags().StringSliceVar(&platforms, "platforms", platforms, "only push the images for the given platforms, rewriting the Images.lock to match (e.g. linux/arm64)")
+	cmd.PersistentFlags().StringVar(&outputReport, "output-report", outputReport, "write a summary of the unwrap to the given file, in YAML for .yaml and .yml files and JSON otherwise")
 
 	return cmd
 }
@@ -656,6 +1177,9 @@ func NewContainerCmd(cfg *config.Config) *cobra.Command {
 				WithSayYes(sayYes),
 				WithContext(ctx),
 				WithInsecure(cfg.Insecure),
+				WithCredentialsFile(cfg.CredentialsFile),
+				WithRegistriesFile(cfg.RegistriesFile),
+				WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				WithTempDirectory(tempDir),
 				WithUsePlainHTTP(cfg.UsePlainHTTP),
 				WithInteractive(true),
@@ -1,27 +1,43 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/report"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/unwrap"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/wrap"
 	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
 
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/repo/repotest"
 )
 
@@ -283,6 +299,434 @@ func (suite *CmdSuite) TestUnwrapCommand() {
 					"chart should exist in the repository",
 				)
 			})
+			if !tc.auth {
+				t.Run("Unwrap Chart writing a report", func(t *testing.T) {
+					require := suite.Require()
+					assert := suite.Assert()
+
+					wrapDir := sb.TempFile()
+					chartDir := filepath.Join(wrapDir, "chart")
+
+					images, err := writeSampleImages(imageName, imageTag, filepath.Join(wrapDir, "images"))
+					require.NoError(err)
+					require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+						map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version, "RepositoryURL": serverURL},
+					))
+					data, err := tu.RenderTemplateFile(filepath.Join(scenarioDir, "imagelock.partial.tmpl"),
+						map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version},
+					)
+					require.NoError(err)
+					require.NoError(os.WriteFile(filepath.Join(chartDir, "Images.lock"), []byte(data), 0755))
+
+					targetRegistry := newUniqueTargetRegistry()
+					reportFile := filepath.Join(sb.TempFile(), "report.json")
+					dt("unwrap", wrapDir, targetRegistry, "--plain", "--yes", "--use-plain-http", "--output-report", reportFile).AssertSuccess(t)
+
+					reportData, err := os.ReadFile(reportFile)
+					require.NoError(err)
+					var r report.Report
+					require.NoError(json.Unmarshal(reportData, &r))
+
+					assert.Equal("unwrap", r.Command)
+					assert.Equal(wrapDir, r.Input)
+					assert.Equal(report.StatusSuccess, r.Status)
+					assert.Equal([]report.Chart{{Name: chartName, Version: version}}, r.Charts)
+					assert.Nil(r.Output)
+					assert.NotEmpty(r.Steps)
+					assert.Equal([]report.Artifact{
+						{Type: "chart", Reference: fmt.Sprintf("oci://%s/%s:%s", targetRegistry, chartName, version)},
+					}, r.Artifacts)
+
+					require.Len(r.Images, len(images))
+					for i, img := range images {
+						assert.Equal(fmt.Sprintf("%s/%s", serverURL, img.Image), r.Images[i].Source)
+						assert.Equal(fmt.Sprintf("%s/%s", targetRegistry, img.Image), r.Images[i].Destination)
+						assert.True(r.Images[i].Pushed)
+						require.Len(r.Images[i].Digests, len(img.Digests))
+					}
+
+					// Failing to write the report does not hide the unwrap error
+					blocker := sb.Touch(sb.TempFile())
+					dt("unwrap", filepath.Join(sb.TempFile(), "missing.wrap.tgz"), targetRegistry, "--plain", "--yes", "--use-plain-http",
+						"--output-report", filepath.Join(blocker, "report.json"),
+					).AssertErrorMatch(t, regexp.MustCompile(`(?s)failed to load Helm chart.*failed to create report directory`))
+				})
+			}
+			t.Run("Unwrap Chart streaming images from the wrap file", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				wrapDir := sb.TempFile()
+
+				chartDir := filepath.Join(wrapDir, "chart")
+
+				images, err := writeSampleImages(imageName, imageTag, filepath.Join(wrapDir, "images"))
+				require.NoError(err)
+
+				require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version, "RepositoryURL": serverURL},
+				))
+
+				data, err := tu.RenderTemplateFile(filepath.Join(scenarioDir, "imagelock.partial.tmpl"),
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version},
+				)
+				require.NoError(err)
+				require.NoError(os.WriteFile(filepath.Join(chartDir, "Images.lock"), []byte(data), 0755))
+
+				wrapFile := filepath.Join(sb.TempFile(), fmt.Sprintf("%s-%s.wrap.tgz", chartName, version))
+				require.NoError(utils.Tar(wrapDir, wrapFile, utils.TarConfig{Prefix: fmt.Sprintf("%s-%s", chartName, version)}))
+
+				targetRegistry := newUniqueTargetRegistry()
+				args := []string{"unwrap", wrapFile, targetRegistry, "--plain", "--yes", "--use-plain-http", "--stream"}
+				if useAPI {
+					l := logrus.NewSectionLogger()
+					l.SetWriter(io.Discard)
+					opts := []unwrap.Option{
+						unwrap.WithLogger(l),
+						unwrap.WithUsePlainHTTP(true),
+						unwrap.WithSayYes(true),
+						unwrap.WithContainerRegistryAuth(username, password),
+						unwrap.WithStreaming(true),
+					}
+					_, err := unwrap.Chart(wrapFile, targetRegistry, "", opts...)
+					require.NoError(err)
+				} else {
+					dt(args...).AssertSuccessMatch(suite.T(), "")
+				}
+				// Verify the images were pushed
+				for _, img := range images {
+					src := fmt.Sprintf("%s/%s", targetRegistry, img.Image)
+					remoteDigests, err := tu.ReadRemoteImageManifest(src, tu.WithAuth(username, password))
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, dgstData := range img.Digests {
+						assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+					}
+				}
+				assert.True(
+					artifacts.RemoteChartExist(
+						fmt.Sprintf("oci://%s/%s", targetRegistry, chartName),
+						version,
+						artifacts.WithRegistryAuth(username, password),
+						artifacts.WithPlainHTTP(true),
+					),
+					"chart should exist in the repository",
+				)
+			})
+			newSampleWrap := func() (string, []tu.ImageData) {
+				require := suite.Require()
+				wrapDir := sb.TempFile()
+				chartDir := filepath.Join(wrapDir, "chart")
+
+				images, err := writeSampleImages(imageName, imageTag, filepath.Join(wrapDir, "images"))
+				require.NoError(err)
+
+				require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version, "RepositoryURL": serverURL},
+				))
+				data, err := tu.RenderTemplateFile(filepath.Join(scenarioDir, "imagelock.partial.tmpl"),
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version},
+				)
+				require.NoError(err)
+				require.NoError(os.WriteFile(filepath.Join(chartDir, "Images.lock"), []byte(data), 0755))
+				return wrapDir, images
+			}
+			t.Run("Unwrap Chart into a Helm repository directory", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				wrapDir, _ := newSampleWrap()
+				targetRegistry := newUniqueTargetRegistry()
+
+				// Existing charts in the repository are preserved
+				repoDir, err := sb.Mkdir(sb.TempFile(), 0755)
+				require.NoError(err)
+				index := repo.NewIndexFile()
+				require.NoError(index.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "other", Version: "0.1.0"}, "other-0.1.0.tgz", "", "sha256:1234"))
+				require.NoError(index.WriteFile(filepath.Join(repoDir, "index.yaml"), 0644))
+
+				if useAPI {
+					l := logrus.NewSectionLogger()
+					l.SetWriter(io.Discard)
+					_, err := unwrap.Chart(wrapDir, targetRegistry, "",
+						unwrap.WithLogger(l),
+						unwrap.WithUsePlainHTTP(true),
+						unwrap.WithSayYes(true),
+						unwrap.WithContainerRegistryAuth(username, password),
+						unwrap.WithChartRepoDir(repoDir),
+					)
+					require.NoError(err)
+				} else {
+					dt("unwrap", wrapDir, targetRegistry, "--plain", "--yes", "--use-plain-http", "--chart-repo-dir", repoDir).AssertSuccessMatch(t, "")
+					dt("unwrap", wrapDir, targetRegistry, "--chart-repo-dir", repoDir, "--push-chart-url", targetRegistry).AssertErrorMatch(t, "cannot be used together")
+				}
+
+				index, err = repo.LoadIndexFile(filepath.Join(repoDir, "index.yaml"))
+				require.NoError(err)
+				assert.True(index.Has("other", "0.1.0"))
+				assert.True(index.Has(chartName, version))
+
+				c, err := loader.Load(filepath.Join(repoDir, fmt.Sprintf("%s-%s.tgz", chartName, version)))
+				require.NoError(err)
+				assert.Equal(chartName, c.Name())
+				for _, f := range c.Raw {
+					if f.Name == "values.yaml" {
+						assert.Contains(string(f.Data), targetRegistry, "values.yaml should be relocated")
+					}
+				}
+				assert.False(
+					artifacts.RemoteChartExist(
+						fmt.Sprintf("oci://%s/%s", targetRegistry, chartName),
+						version,
+						artifacts.WithRegistryAuth(username, password),
+						artifacts.WithPlainHTTP(true),
+					),
+					"chart should not be pushed to the registry",
+				)
+			})
+			t.Run("Unwrap Chart into a ChartMuseum repository", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				var uploaded []byte
+				var uploadUser, uploadPass string
+				repoSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodPost || r.URL.Path != "/api/charts" {
+						http.NotFound(w, r)
+						return
+					}
+					uploadUser, uploadPass, _ = r.BasicAuth()
+					uploaded, _ = io.ReadAll(r.Body)
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"saved":true}`))
+				}))
+				defer repoSrv.Close()
+
+				wrapDir, _ := newSampleWrap()
+				targetRegistry := newUniqueTargetRegistry()
+				if useAPI {
+					l := logrus.NewSectionLogger()
+					l.SetWriter(io.Discard)
+					fullChartURL, err := unwrap.Chart(wrapDir, targetRegistry, repoSrv.URL,
+						unwrap.WithLogger(l),
+						unwrap.WithUsePlainHTTP(true),
+						unwrap.WithSayYes(true),
+						unwrap.WithAuth("repouser", "repopass"),
+						unwrap.WithContainerRegistryAuth(username, password),
+					)
+					require.NoError(err)
+					assert.Empty(fullChartURL)
+					assert.Equal("repouser", uploadUser)
+					assert.Equal("repopass", uploadPass)
+				} else {
+					dt("unwrap", wrapDir, targetRegistry, "--plain", "--yes", "--use-plain-http", "--push-chart-url", repoSrv.URL).AssertSuccessMatch(t, "")
+				}
+
+				c, err := loader.LoadArchive(bytes.NewReader(uploaded))
+				require.NoError(err)
+				assert.Equal(chartName, c.Name())
+				assert.Equal(version, c.Metadata.Version)
+			})
+			t.Run("Unwrap Chart into an OCI layout", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				wrapDir, images := newSampleWrap()
+				targetRegistry := newUniqueTargetRegistry()
+				layoutDir := sb.TempFile()
+
+				if useAPI {
+					l := logrus.NewSectionLogger()
+					l.SetWriter(io.Discard)
+					_, err := unwrap.Chart(wrapDir, targetRegistry, "",
+						unwrap.WithLogger(l),
+						unwrap.WithSayYes(true),
+						unwrap.WithOCILayoutDir(layoutDir),
+					)
+					require.NoError(err)
+				} else {
+					dt("unwrap", wrapDir, targetRegistry, "--plain", "--yes", "--to-oci-layout", layoutDir).AssertSuccessMatch(t, "")
+					dt("unwrap", wrapDir, targetRegistry, "--to-oci-layout", layoutDir, "--stream").AssertErrorMatch(t, "cannot be streamed")
+				}
+
+				idx, err := layout.ImageIndexFromPath(layoutDir)
+				require.NoError(err)
+				manifest, err := idx.IndexManifest()
+				require.NoError(err)
+				refs := make(map[string]v1.Descriptor)
+				for _, desc := range manifest.Manifests {
+					refs[desc.Annotations["org.opencontainers.image.ref.name"]] = desc
+				}
+
+				chartRef := fmt.Sprintf("%s/%s:%s", targetRegistry, chartName, version)
+				require.Contains(refs, chartRef)
+				chartManifest, err := idx.Image(refs[chartRef].Digest)
+				require.NoError(err)
+				chartImgManifest, err := chartManifest.Manifest()
+				require.NoError(err)
+				assert.Equal("application/vnd.cncf.helm.config.v1+json", string(chartImgManifest.Config.MediaType))
+
+				for _, img := range images {
+					ref := fmt.Sprintf("%s/%s", targetRegistry, img.Image)
+					require.Contains(refs, ref)
+					imgIdx, err := idx.ImageIndex(refs[ref].Digest)
+					require.NoError(err)
+
+					// The layout can be copied into the registry as is
+					dest, err := name.ParseReference(ref)
+					require.NoError(err)
+					require.NoError(remote.WriteIndex(dest, imgIdx, remote.WithAuth(&authn.Basic{Username: username, Password: password})))
+					remoteDigests, err := tu.ReadRemoteImageManifest(ref, tu.WithAuth(username, password))
+					require.NoError(err)
+					for _, dgstData := range img.Digests {
+						assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+					}
+				}
+				assert.False(
+					artifacts.RemoteChartExist(
+						fmt.Sprintf("oci://%s/%s", targetRegistry, chartName),
+						version,
+						artifacts.WithRegistryAuth(username, password),
+						artifacts.WithPlainHTTP(true),
+					),
+					"chart should not be pushed to the registry",
+				)
+			})
+			t.Run("Unwrap Chart selecting platforms", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				wrapDir, images := newSampleWrap()
+				targetRegistry := newUniqueTargetRegistry()
+
+				if useAPI {
+					l := logrus.NewSectionLogger()
+					l.SetWriter(io.Discard)
+					_, err := unwrap.Chart(wrapDir, targetRegistry, "",
+						unwrap.WithLogger(l),
+						unwrap.WithUsePlainHTTP(true),
+						unwrap.WithSayYes(true),
+						unwrap.WithContainerRegistryAuth(username, password),
+						unwrap.WithPlatforms([]string{"linux/arm64"}),
+					)
+					require.NoError(err)
+				} else {
+					missingWrapDir, _ := newSampleWrap()
+					dt("unwrap", missingWrapDir, targetRegistry, "--plain", "--yes", "--use-plain-http", "--platforms", "linux/s390x").AssertErrorMatch(t, `platform "linux/s390x" is not available`)
+					dt("unwrap", wrapDir, targetRegistry, "--plain", "--yes", "--use-plain-http", "--platforms", "linux/arm64").AssertSuccessMatch(t, "")
+
+					// Charts without images have no platforms to select
+					noImagesWrapDir := sb.TempFile()
+					require.NoError(tu.RenderScenario("../../testdata/scenarios/no-images-chart", filepath.Join(noImagesWrapDir, "chart"),
+						map[string]interface{}{"Name": chartName, "Version": version},
+					))
+					dt("unwrap", noImagesWrapDir, newUniqueTargetRegistry(), "--plain", "--yes", "--use-plain-http", "--platforms", "linux/arm64").AssertSuccess(t)
+				}
+
+				for _, img := range images {
+					src := fmt.Sprintf("%s/%s", targetRegistry, img.Image)
+					remoteDigests, err := tu.ReadRemoteImageManifest(src, tu.WithAuth(username, password))
+					require.NoError(err)
+					require.Len(remoteDigests, 1)
+					for _, dgstData := range img.Digests {
+						if dgstData.Arch == "linux/arm64" {
+							assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+						}
+					}
+				}
+
+				// The pushed Images.lock only lists the selected platform
+				pullDir, err := sb.Mkdir(sb.TempFile(), 0755)
+				require.NoError(err)
+				chartDir, err := artifacts.PullChart(fmt.Sprintf("oci://%s/%s", targetRegistry, chartName), version, pullDir,
+					artifacts.WithRegistryAuth(username, password),
+					artifacts.WithPlainHTTP(true),
+				)
+				require.NoError(err)
+				fh, err := os.Open(filepath.Join(chartDir, "Images.lock"))
+				require.NoError(err)
+				defer fh.Close()
+				lock, err := imagelock.FromYAML(fh)
+				require.NoError(err)
+				require.Len(lock.Images, len(images))
+				for _, img := range lock.Images {
+					require.Len(img.Digests, 1)
+					assert.Equal("linux/arm64", img.Digests[0].Arch)
+				}
+			})
+			t.Run("Unwrap split Chart", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				wrapDir := sb.TempFile()
+
+				chartDir := filepath.Join(wrapDir, "chart")
+
+				images, err := writeSampleImages(imageName, imageTag, filepath.Join(wrapDir, "images"))
+				require.NoError(err)
+
+				require.NoError(tu.RenderScenario(scenarioDir, chartDir,
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version, "RepositoryURL": serverURL},
+				))
+
+				data, err := tu.RenderTemplateFile(filepath.Join(scenarioDir, "imagelock.partial.tmpl"),
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version},
+				)
+				require.NoError(err)
+				require.NoError(os.WriteFile(filepath.Join(chartDir, "Images.lock"), []byte(data), 0755))
+
+				wrapFile := filepath.Join(sb.TempFile(), fmt.Sprintf("%s-%s.wrap.tgz", chartName, version))
+				require.NoError(utils.Tar(wrapDir, wrapFile, utils.TarConfig{Prefix: fmt.Sprintf("%s-%s", chartName, version), SplitSize: 1024}))
+
+				for _, streaming := range []bool{false, true} {
+					targetRegistry := newUniqueTargetRegistry()
+					inputFile := utils.SplitPartName(wrapFile, 1)
+					args := []string{"unwrap", inputFile, targetRegistry, "--plain", "--yes", "--use-plain-http", fmt.Sprintf("--stream=%t", streaming)}
+					if useAPI {
+						l := logrus.NewSectionLogger()
+						l.SetWriter(io.Discard)
+						opts := []unwrap.Option{
+							unwrap.WithLogger(l),
+							unwrap.WithUsePlainHTTP(true),
+							unwrap.WithSayYes(true),
+							unwrap.WithContainerRegistryAuth(username, password),
+							unwrap.WithStreaming(streaming),
+						}
+						_, err := unwrap.Chart(inputFile, targetRegistry, "", opts...)
+						require.NoError(err)
+					} else {
+						dt(args...).AssertSuccessMatch(suite.T(), "")
+					}
+					// Verify the images were pushed
+					for _, img := range images {
+						src := fmt.Sprintf("%s/%s", targetRegistry, img.Image)
+						remoteDigests, err := tu.ReadRemoteImageManifest(src, tu.WithAuth(username, password))
+						if err != nil {
+							t.Fatal(err)
+						}
+						for _, dgstData := range img.Digests {
+							assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+						}
+					}
+					assert.True(
+						artifacts.RemoteChartExist(
+							fmt.Sprintf("oci://%s/%s", targetRegistry, chartName),
+							version,
+							artifacts.WithRegistryAuth(username, password),
+							artifacts.WithPlainHTTP(true),
+						),
+						"chart should exist in the repository",
+					)
+				}
+
+				lastPart := utils.SplitPartName(wrapFile, 2)
+				partData, err := os.ReadFile(lastPart)
+				require.NoError(err)
+				partData[0] ^= 0xff
+				require.NoError(os.WriteFile(lastPart, partData, 0644))
+				dt("unwrap", wrapFile+utils.SplitIndexSuffix, newUniqueTargetRegistry(), "--plain", "--yes", "--use-plain-http").AssertErrorMatch(t, "digest mismatch")
+			})
 		})
 	}
 }
@@ -424,6 +868,98 @@ func (suite *CmdSuite) TestEndToEnd() {
 					Auth:                  tu.Auth{Username: username, Password: password},
 				})
 			})
+			t.Run("Wrap and unwrap several Charts", func(t *testing.T) {
+				require := suite.Require()
+				assert := suite.Assert()
+
+				srcRegistry := newTargetRegistry("wrap-unwrap-multi-test")
+				images, err := tu.AddSampleImagesToRegistry(imageName, srcRegistry, tu.WithAuth(contUser, contPass))
+				require.NoError(err)
+
+				// Both charts share the same images, which are only bundled once
+				chartNames := []string{"test-app", "test-db"}
+				releaseDir := sb.TempFile()
+				manifest := "name: platform\nversion: 1.0.0\ncharts:\n"
+				for _, name := range chartNames {
+					require.NoError(tu.RenderScenario(scenarioDir, filepath.Join(releaseDir, name),
+						map[string]interface{}{"ServerURL": srcRegistry, "Images": images, "Name": name, "Version": version, "RepositoryURL": srcRegistry},
+					))
+					manifest += fmt.Sprintf("  - chart: ./%s\n", name)
+				}
+				manifestFile, err := sb.Write(filepath.Join(releaseDir, "release.yaml"), manifest)
+				require.NoError(err)
+
+				wrapFile := filepath.Join(sb.TempFile(), "platform-1.0.0.wrap.tgz")
+				if useAPI {
+					l := logrus.NewSectionLogger()
+					l.SetWriter(io.Discard)
+					release, err := wrap.LoadRelease(manifestFile)
+					require.NoError(err)
+					_, err = wrap.Charts(release,
+						wrap.WithLogger(l),
+						wrap.WithUsePlainHTTP(true),
+						wrap.WithOutputFile(wrapFile),
+						wrap.WithContainerRegistryAuth(contUser, contPass),
+						// Local charts are never fetched from the Helm repository
+						wrap.WithRepoURL("http://127.0.0.1:1/charts"),
+					)
+					require.NoError(err)
+				} else {
+					dt("wrap", "--manifest", manifestFile, "--output-file", wrapFile, "--use-plain-http").AssertSuccessMatch(t, "Helm charts wrapped into")
+					dt("wrap", "verify", wrapFile).AssertSuccessMatch(t, "")
+					dt("wrap", filepath.Join(releaseDir, "test-app"), filepath.Join(releaseDir, "test-db"), "--version", version).AssertErrorMatch(t, "--version cannot be used when wrapping several Helm charts")
+					dt("wrap", filepath.Join(releaseDir, "test-app"), "mychart", "--repo-url", "http://127.0.0.1:1/charts").AssertErrorMatch(t, "--repo-url cannot be used when wrapping several Helm charts")
+				}
+				lock, err := chartutils.ReadLockFromChart(wrapFile)
+				require.NoError(err)
+				assert.Equal("platform", lock.Chart.Name)
+				assert.Len(lock.Images, len(images))
+
+				for _, streaming := range []bool{false, true} {
+					targetRegistry := newUniqueTargetRegistry()
+					chartTargetRegistry := targetRegistry
+					chartAuth := tu.Auth{Username: contUser, Password: contPass}
+					if pushChartURL != "" {
+						chartTargetRegistry = pushChartURL
+						chartAuth = tu.Auth{Username: username, Password: password}
+					}
+					if useAPI {
+						l := logrus.NewSectionLogger()
+						l.SetWriter(io.Discard)
+						_, err := unwrap.Chart(wrapFile, targetRegistry, pushChartURL,
+							unwrap.WithLogger(l),
+							unwrap.WithUsePlainHTTP(true),
+							unwrap.WithSayYes(true),
+							unwrap.WithStreaming(streaming),
+							unwrap.WithAuth(chartAuth.Username, chartAuth.Password),
+							unwrap.WithContainerRegistryAuth(contUser, contPass),
+						)
+						require.NoError(err)
+					} else {
+						dt("unwrap", wrapFile, targetRegistry, "--plain", "--yes", "--use-plain-http", fmt.Sprintf("--stream=%t", streaming)).AssertSuccessMatch(t, "")
+					}
+
+					for _, img := range images {
+						src := fmt.Sprintf("%s/%s/%s", targetRegistry, "wrap-unwrap-multi-test", img.Image)
+						remoteDigests, err := tu.ReadRemoteImageManifest(src, tu.WithAuth(contUser, contPass))
+						require.NoError(err)
+						for _, dgstData := range img.Digests {
+							assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+						}
+					}
+					for _, name := range chartNames {
+						assert.True(
+							artifacts.RemoteChartExist(
+								fmt.Sprintf("oci://%s/%s", chartTargetRegistry, name),
+								version,
+								artifacts.WithRegistryAuth(chartAuth.Username, chartAuth.Password),
+								artifacts.WithPlainHTTP(true),
+							),
+							"chart %q should exist in the repository", name,
+						)
+					}
+				}
+			})
 		})
 	}
 }
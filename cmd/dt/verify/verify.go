@@ -10,6 +10,7 @@ import (
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/config"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
 )
 
@@ -25,6 +26,9 @@ type Config struct {
 	Insecure           bool
 	PreserveRepository bool
 	Auth               Auth
+	CredentialsFile    string
+	RegistriesFile     string
+	TLS                registries.TLS
 }
 
 // Lock verifies the images in an Images.lock
@@ -46,6 +50,9 @@ func Lock(chartPath string, lockFile string, cfg Config) error {
 		imagelock.WithAnnotationsKey(cfg.AnnotationsKey),
 		imagelock.WithContext(context.Background()),
 		imagelock.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+		imagelock.WithCredentialsFile(cfg.CredentialsFile),
+		imagelock.WithRegistriesFile(cfg.RegistriesFile),
+		imagelock.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 		imagelock.WithInsecure(cfg.Insecure),
 		imagelock.WithPreserveRepository(cfg.PreserveRepository),
 	)
@@ -91,7 +98,8 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 			}
 
 			if err := l.ExecuteStep("Verifying Images.lock", func() error {
-				return Lock(chartPath, lockFile, Config{Insecure: cfg.Insecure, AnnotationsKey: cfg.AnnotationsKey, PreserveRepository: true})
+				return Lock(chartPath, lockFile, Config{Insecure: cfg.Insecure, AnnotationsKey: cfg.AnnotationsKey, PreserveRepository: true,
+					CredentialsFile: cfg.CredentialsFile, RegistriesFile: cfg.RegistriesFile, TLS: cfg.TLS})
 			}); err != nil {
 				return l.Failf("failed to verify %q lock: %w", chartPath, err)
 			}
@@ -0,0 +1,151 @@
+package wrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/wrapping"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultReleaseName is the name of multi-chart wraps not created from a release manifest
+const defaultReleaseName = "charts"
+
+// Release describes a set of Helm charts wrapped together
+type Release struct {
+	Name    string         `yaml:"name"`
+	Version string         `yaml:"version"`
+	Charts  []ReleaseChart `yaml:"charts"`
+}
+
+// ReleaseChart describes each of the charts of a Release
+type ReleaseChart struct {
+	Chart   string `yaml:"chart"`   // Local path, OCI reference or Helm repository chart reference
+	Version string `yaml:"version"` // Version to request for remote charts
+	RepoURL string `yaml:"repoURL"` // Helm repository to fetch the chart from
+}
+
+// LoadRelease reads a release manifest file. Relative chart paths are resolved from the manifest directory
+func LoadRelease(file string) (*Release, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release manifest: %w", err)
+	}
+	release := &Release{}
+	if err := yaml.Unmarshal(data, release); err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest %q: %w", file, err)
+	}
+	if len(release.Charts) == 0 {
+		return nil, fmt.Errorf("release manifest %q does not contain any chart", file)
+	}
+	for i, c := range release.Charts {
+		if c.Chart == "" {
+			return nil, fmt.Errorf("release manifest %q: chart #%d does not define its location", file, i+1)
+		}
+		if c.RepoURL != "" || chartutils.IsRemoteChart(c.Chart) || filepath.IsAbs(c.Chart) {
+			continue
+		}
+		// Anything else not found next to the manifest is a chart from the configured Helm repositories
+		if chartPath := filepath.Join(filepath.Dir(file), c.Chart); utils.FileExists(chartPath) {
+			release.Charts[i].Chart = chartPath
+		}
+	}
+	return release, nil
+}
+
+// Charts wraps several Helm charts into a single wrap sharing their images
+func Charts(release *Release, opts ...Option) (string, error) {
+	return wrapCharts(release, opts...)
+}
+
+func wrapCharts(release *Release, opts ...Option) (string, error) {
+	cfg := NewConfig(opts...)
+
+	parentLog := cfg.GetLogger()
+
+	if err := utils.ValidateCompression(cfg.Compression, cfg.CompressionLevel); err != nil {
+		return "", err
+	}
+	if len(release.Charts) == 0 {
+		return "", fmt.Errorf("no Helm charts to wrap")
+	}
+	name := release.Name
+	if name == "" {
+		name = defaultReleaseName
+	}
+
+	l := parentLog.StartSection(fmt.Sprintf("Wrapping %d Helm charts into %q", len(release.Charts), name))
+
+	subCfg := NewConfig(append(opts, WithLogger(l))...)
+
+	tmpDir, err := cfg.GetTemporaryDirectory()
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	wrap, err := wrapping.CreateMulti(filepath.Join(tmpDir, "wrap"))
+	if err != nil {
+		return "", l.Failf("failed to create wrap: %v", err)
+	}
+
+	for _, c := range release.Charts {
+		// Each chart is fetched from its own repository, if any, never from the one of the command
+		chartOpts := make([]Option, 0, len(opts)+3)
+		chartOpts = append(chartOpts, opts...)
+		chartOpts = append(chartOpts, WithLogger(l), WithVersion(c.Version), WithRepoURL(c.RepoURL))
+		chartCfg := NewConfig(chartOpts...)
+		chartPath, err := ResolveInputChartPath(c.Chart, chartCfg)
+		if err != nil {
+			return "", err
+		}
+		chartWrap, err := wrap.AddChart(chartPath, chartutils.WithAnnotationsKey(cfg.AnnotationsKey))
+		if err != nil {
+			return "", l.Failf("failed to add %q to the wrap: %v", c.Chart, err)
+		}
+		chart := chartWrap.Chart()
+
+		if chartCfg.ShouldFetchChartArtifacts(c.Chart) {
+			chartURL := fmt.Sprintf("%s:%s", c.Chart, chart.Version())
+			if err := fetchArtifacts(chartURL, filepath.Join(chart.RootDir(), artifacts.HelmChartArtifactMetadataDir), chartCfg); err != nil {
+				return "", err
+			}
+		}
+		if err := validateWrapLock(chartWrap, chartCfg); err != nil {
+			return "", err
+		}
+		if cfg.Carvelize {
+			if err := generateCarvelBundle(chartPath, chart.RootDir(), chartCfg); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := wrap.WriteImagesLock(name, release.Version); err != nil {
+		return "", l.Failf("failed to write Images.lock: %w", err)
+	}
+
+	prefix := name
+	if release.Version != "" {
+		prefix = fmt.Sprintf("%s-%s", name, release.Version)
+	}
+	outputFile := cfg.OutputFile
+	if outputFile == "" {
+		outputBaseName := fmt.Sprintf("%s.wrap%s", prefix, cfg.Compression.Extension())
+		if outputFile, err = filepath.Abs(outputBaseName); err != nil {
+			l.Debugf("failed to normalize output file: %v", err)
+			outputFile = filepath.Join(tmpDir, outputBaseName)
+		}
+	}
+
+	if !cfg.SkipPullImages && !cfg.Streaming {
+		if err := pullImages(wrap, subCfg); err != nil {
+			return "", err
+		}
+	}
+
+	return writeWrapFile(wrap, outputFile, prefix, subCfg)
+}
@@ -0,0 +1,116 @@
+package wrap
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/config"
+	"github.com/vmware-labs/distribution-tooling-for-helm/internal/widgets"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/wrapping"
+)
+
+// Verify checks the integrity of the images bundled in a wrap against its Images.lock.
+// The verification is performed offline, without accessing any registry
+func Verify(inputPath string, opts ...Option) error {
+	return verifyWrap(inputPath, opts...)
+}
+
+func verifyWrap(inputPath string, opts ...Option) error {
+	cfg := NewConfig(opts...)
+
+	if chartutils.IsRemoteChart(inputPath) {
+		return fmt.Errorf("only local wraps can be verified")
+	}
+
+	l := cfg.GetLogger().StartSection(fmt.Sprintf("Verifying wrap %q", inputPath))
+
+	subCfg := NewConfig(append(opts, WithLogger(l))...)
+
+	wrapPath, err := ResolveInputChartPath(inputPath, subCfg)
+	if err != nil {
+		return err
+	}
+
+	var wrap wrapping.Bundle
+	if wrapping.IsMultiWrap(wrapPath) {
+		wrap, err = wrapping.LoadMulti(wrapPath, chartutils.WithAnnotationsKey(cfg.AnnotationsKey))
+	} else {
+		wrap, err = wrapping.Load(wrapPath, chartutils.WithAnnotationsKey(cfg.AnnotationsKey))
+	}
+	if err != nil {
+		return l.Failf("failed to load wrap: %w", err)
+	}
+
+	lock, err := wrap.GetImagesLock()
+	if err != nil {
+		return l.Failf("Failed to load Images.lock: %w", err)
+	}
+	if len(lock.Images) == 0 {
+		l.Warnf("No images found in Images.lock")
+	}
+
+	if err := l.Section(fmt.Sprintf("Verifying images in %q", wrap.ImagesDir()), func(childLog dtlog.SectionLogger) error {
+		if err := chartutils.VerifyImages(
+			lock,
+			wrap.ImagesDir(),
+			chartutils.WithLog(childLog),
+			chartutils.WithContext(cfg.Context),
+			chartutils.WithProgressBar(childLog.ProgressBar()),
+		); err != nil {
+			return childLog.Failf("%v", err)
+		}
+		childLog.Infof("All images are valid")
+		return nil
+	}); err != nil {
+		return l.Failf("Wrap verification failed: %w", err)
+	}
+	return nil
+}
+
+// NewVerifyCmd builds a new wrap verify command
+func NewVerifyCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify FILE",
+		Short: "Verifies the integrity of a wrap",
+		Long: `Verifies the integrity of a wrapped Helm chart without accessing any registry.
+This command recomputes the digests of all the bundled OCI layouts and checks them against the Images.lock`,
+		Example: `  # Verify a wrapped Helm chart
+  $ dt wrap verify mariadb-12.2.8.wrap.tgz`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			inputPath := args[0]
+
+			ctx, cancel := cfg.ContextWithSigterm()
+			defer cancel()
+
+			tmpDir, err := config.GetGlobalTempWorkDir()
+			if err != nil {
+				return err
+			}
+
+			parentLog := cfg.Logger()
+
+			if err := verifyWrap(inputPath,
+				WithLogger(parentLog),
+				WithContext(ctx),
+				WithAnnotationsKey(cfg.AnnotationsKey),
+				WithTempDirectory(tmpDir),
+			); err != nil {
+				if _, ok := err.(*dtlog.LoggedError); ok {
+					// We already logged it, lets be less verbose
+					return fmt.Errorf("failed to verify wrap: %v", err)
+				}
+				return err
+			}
+
+			parentLog.Printf(widgets.TerminalSpacer)
+			parentLog.Successf("Wrap %q is valid", inputPath)
+			return nil
+		},
+	}
+	return cmd
+}
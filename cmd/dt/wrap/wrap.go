@@ -4,21 +4,27 @@ package wrap
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/carvelize"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/config"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/lock"
-	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/pull"
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/report"
 	"github.com/vmware-labs/distribution-tooling-for-helm/internal/widgets"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/silent"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
 
@@ -34,21 +40,40 @@ type Auth struct {
 
 // Config defines the configuration for the Wrap/Unwrap command
 type Config struct {
-	Context               context.Context
-	AnnotationsKey        string
-	UsePlainHTTP          bool
-	Insecure              bool
-	Platforms             []string
-	logger                dtlog.SectionLogger
-	TempDirectory         string
-	Version               string
-	Carvelize             bool
-	KeepArtifacts         bool
-	FetchArtifacts        bool
-	SkipPullImages        bool
-	Auth                  Auth
-	ContainerRegistryAuth Auth
-	OutputFile            string
+	Context        context.Context
+	AnnotationsKey string
+	UsePlainHTTP   bool
+	Insecure       bool
+	Platforms      []string
+	logger         dtlog.SectionLogger
+	TempDirectory  string
+	Version        string
+	RepoURL        string
+	Carvelize      bool
+	KeepArtifacts  bool
+	FetchArtifacts bool
+	SkipPullImages bool
+	Streaming      bool
+	// IncludeNondistributable pulls the non-distributable (foreign) image layers into the wrap
+	IncludeNondistributable bool
+	Auth                    Auth
+	ContainerRegistryAuth   Auth
+	CredentialsFile         string
+	RegistriesFile          string
+	TLS                     registries.TLS
+	OutputFile              string
+	Compression             utils.Compression
+	CompressionLevel        int
+	SplitSize               int64
+	// Report, if set, collects the summary of the wrap
+	Report *report.Report
+}
+
+// WithReport configures the Report collecting the summary of the wrap
+func WithReport(r *report.Report) func(c *Config) {
+	return func(c *Config) {
+		c.Report = r
+	}
 }
 
 // WithKeepArtifacts configures the KeepArtifacts of the WrapConfig
@@ -85,6 +110,36 @@ func WithContainerRegistryAuth(username, password string) func(c *Config) {
 	}
 }
 
+// WithCredentialsFile configures the CredentialsFile of the wrap Config
+func WithCredentialsFile(file string) func(c *Config) {
+	return func(c *Config) {
+		c.CredentialsFile = file
+	}
+}
+
+// WithRegistriesFile configures the RegistriesFile of the wrap Config
+func WithRegistriesFile(file string) func(c *Config) {
+	return func(c *Config) {
+		c.RegistriesFile = file
+	}
+}
+
+// WithTLS configures the TLS certificate authorities and client certificate used to access the registries
+func WithTLS(caFile, certFile, keyFile string) func(c *Config) {
+	return func(c *Config) {
+		c.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
+// repoURLFor returns the Helm repository to fetch inputPath from, if any. Local charts and OCI
+// references never come from the configured Helm repository
+func (c *Config) repoURLFor(inputPath string) string {
+	if chartutils.IsRemoteChart(inputPath) || utils.FileExists(inputPath) {
+		return ""
+	}
+	return c.RepoURL
+}
+
 // ShouldFetchChartArtifacts returns true if the chart artifacts should be fetched
 func (c *Config) ShouldFetchChartArtifacts(inputChart string) bool {
 	if chartutils.IsRemoteChart(inputChart) {
@@ -138,6 +193,60 @@ func WithSkipPullImages(skipPullImages bool) func(c *Config) {
 	}
 }
 
+// WithIncludeNondistributable configures the IncludeNondistributable of the WrapConfig
+func WithIncludeNondistributable(include bool) func(c *Config) {
+	return func(c *Config) {
+		c.IncludeNondistributable = include
+	}
+}
+
+// WithStreaming configures the Streaming of the WrapConfig
+func WithStreaming(streaming bool) func(c *Config) {
+	return func(c *Config) {
+		c.Streaming = streaming
+	}
+}
+
+// WithCompression configures the Compression of the WrapConfig
+func WithCompression(compression utils.Compression) func(c *Config) {
+	return func(c *Config) {
+		c.Compression = compression
+	}
+}
+
+// WithCompressionLevel configures the CompressionLevel of the WrapConfig
+func WithCompressionLevel(level int) func(c *Config) {
+	return func(c *Config) {
+		c.CompressionLevel = level
+	}
+}
+
+// WithSplitSize configures the SplitSize of the WrapConfig
+func WithSplitSize(size int64) func(c *Config) {
+	return func(c *Config) {
+		c.SplitSize = size
+	}
+}
+
+// tarConfig returns the utils.TarConfig to write the wrap file
+func (c *Config) tarConfig(prefix string) utils.TarConfig {
+	return utils.TarConfig{
+		Prefix:           prefix,
+		Compression:      c.Compression,
+		CompressionLevel: c.CompressionLevel,
+		SplitSize:        c.SplitSize,
+	}
+}
+
+// wrapFile returns the file to report for the outputFile wrap, which is
+// its index file when the wrap is split in several parts
+func (c *Config) wrapFile(outputFile string) string {
+	if c.SplitSize > 0 {
+		return outputFile + utils.SplitIndexSuffix
+	}
+	return outputFile
+}
+
 // WithVersion configures the Version of the WrapConfig
 func WithVersion(version string) func(c *Config) {
 	return func(c *Config) {
@@ -145,6 +254,13 @@ func WithVersion(version string) func(c *Config) {
 	}
 }
 
+// WithRepoURL configures the Helm repository URL to fetch charts from
+func WithRepoURL(repoURL string) func(c *Config) {
+	return func(c *Config) {
+		c.RepoURL = repoURL
+	}
+}
+
 // WithLogger configures the Logger of the WrapConfig
 func WithLogger(logger dtlog.SectionLogger) func(c *Config) {
 	return func(c *Config) {
@@ -203,6 +319,7 @@ func NewConfig(opts ...Option) *Config {
 		logger:         logrus.NewSectionLogger(),
 		AnnotationsKey: imagelock.DefaultAnnotationsKey,
 		Platforms:      []string{},
+		Compression:    utils.CompressionGzip,
 	}
 
 	for _, opt := range opts {
@@ -218,6 +335,13 @@ func Chart(inputPath string, opts ...Option) (string, error) {
 
 // ResolveInputChartPath resolves the input chart into a local uncompressed chart path
 func ResolveInputChartPath(inputPath string, cfg *Config) (string, error) {
+	_, span := telemetry.StartSpan(cfg.Context, "ResolveInputChartPath", attribute.String("dt.input", inputPath))
+	chartPath, err := resolveInputChartPath(inputPath, cfg)
+	telemetry.EndSpan(span, err)
+	return chartPath, err
+}
+
+func resolveInputChartPath(inputPath string, cfg *Config) (string, error) {
 	l := cfg.GetLogger()
 	var chartPath string
 
@@ -226,7 +350,7 @@ func ResolveInputChartPath(inputPath string, cfg *Config) (string, error) {
 		return "", fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 
-	if chartutils.IsRemoteChart(inputPath) {
+	if chartutils.IsRemoteChart(inputPath) || cfg.repoURLFor(inputPath) != "" || (!utils.FileExists(inputPath) && artifacts.IsRepoChart(inputPath)) {
 		if err = l.ExecuteStep("Fetching remote Helm chart", func() error {
 			version := cfg.Version
 			chartPath, err = fetchRemoteChart(inputPath, version, tmpDir, cfg)
@@ -297,11 +421,20 @@ func fetchRemoteChart(chartURL string, version string, dir string, cfg *Config)
 	if err != nil {
 		return "", err
 	}
+	repoURL := cfg.repoURLFor(chartURL)
+	if repoURL != "" {
+		// Helm repositories are indexed by chart name, allow REPO_NAME/CHART_NAME references too
+		chartURL = path.Base(chartURL)
+	}
 	chartPath, err := artifacts.PullChart(
 		chartURL, version, dir,
+		artifacts.WithRepoURL(repoURL),
 		artifacts.WithInsecure(cfg.Insecure),
+		artifacts.WithRegistryRegistriesFile(cfg.RegistriesFile),
+		artifacts.WithRegistryTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 		artifacts.WithPlainHTTP(cfg.UsePlainHTTP),
 		artifacts.WithRegistryAuth(cfg.Auth.Username, cfg.Auth.Password),
+		artifacts.WithRegistryCredentialsFile(cfg.CredentialsFile),
 		artifacts.WithTempDir(d),
 	)
 	if err != nil {
@@ -310,16 +443,23 @@ func fetchRemoteChart(chartURL string, version string, dir string, cfg *Config)
 	return chartPath, nil
 }
 
-func validateWrapLock(wrap wrapping.Wrap, cfg *Config) error {
+func validateWrapLock(wrap wrapping.Wrap, cfg *Config) (err error) {
 	l := cfg.GetLogger()
 	chart := wrap.Chart()
 
+	ctx, span := telemetry.StartSpan(cfg.Context, "ValidateWrapLock",
+		attribute.String("dt.chart", chart.Name()), attribute.String("dt.chart.version", chart.Version()))
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	lockFile := wrap.LockFilePath()
 	if utils.FileExists(lockFile) {
 		if err := l.ExecuteStep("Verifying Images.lock", func() error {
 			return wrap.VerifyLock(imagelock.WithAnnotationsKey(cfg.AnnotationsKey),
-				imagelock.WithContext(cfg.Context),
+				imagelock.WithContext(ctx),
 				imagelock.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+				imagelock.WithCredentialsFile(cfg.CredentialsFile),
+				imagelock.WithRegistriesFile(cfg.RegistriesFile),
+				imagelock.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				imagelock.WithInsecure(cfg.Insecure))
 		}); err != nil {
 			return l.Failf("Failed to verify lock: %w", err)
@@ -334,8 +474,11 @@ func validateWrapLock(wrap wrapping.Wrap, cfg *Config) error {
 					imagelock.WithSkipImageDigestResolution(cfg.SkipPullImages),
 					imagelock.WithInsecure(cfg.Insecure),
 					imagelock.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+					imagelock.WithCredentialsFile(cfg.CredentialsFile),
+					imagelock.WithRegistriesFile(cfg.RegistriesFile),
+					imagelock.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 					imagelock.WithPlatforms(cfg.Platforms),
-					imagelock.WithContext(cfg.Context),
+					imagelock.WithContext(ctx),
 				)
 			},
 		); err != nil {
@@ -343,6 +486,14 @@ func validateWrapLock(wrap wrapping.Wrap, cfg *Config) error {
 		}
 		l.Infof("Images.lock file written to %q", lockFile)
 	}
+	if cfg.Report != nil {
+		lock, err := wrap.GetImagesLock()
+		if err != nil {
+			return l.Failf("Failed to load Images.lock: %v", err)
+		}
+		cfg.Report.AddChart(chart.Name(), chart.Version())
+		cfg.Report.AddImages(chart.Name(), lock.Images)
+	}
 	return nil
 }
 
@@ -350,13 +501,16 @@ func fetchArtifacts(chartURL string, destDir string, cfg *Config) error {
 	if err := artifacts.FetchChartMetadata(
 		context.Background(), chartURL,
 		destDir, artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+		artifacts.WithCredentialsFile(cfg.CredentialsFile),
+		artifacts.WithRegistriesFile(cfg.RegistriesFile),
+		artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 	); err != nil && err != artifacts.ErrTagDoesNotExist {
 		return fmt.Errorf("failed to fetch chart remote metadata: %w", err)
 	}
 	return nil
 }
 
-func pullImages(wrap wrapping.Wrap, cfg *Config) error {
+func pullImages(wrap wrapping.Bundle, cfg *Config) error {
 	l := cfg.GetLogger()
 
 	lock, err := wrap.GetImagesLock()
@@ -367,18 +521,22 @@ func pullImages(wrap wrapping.Wrap, cfg *Config) error {
 		l.Warnf("No images found in Images.lock")
 	} else {
 		return l.Section(fmt.Sprintf("Pulling images into %q", wrap.ImagesDir()), func(childLog dtlog.SectionLogger) error {
-			if err := pull.ChartImages(
-				wrap,
+			if err := chartutils.PullImages(
+				lock,
 				wrap.ImagesDir(),
 				chartutils.WithLog(childLog),
 				chartutils.WithContext(cfg.Context),
 				chartutils.WithFetchArtifacts(cfg.FetchArtifacts),
 				chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+				chartutils.WithCredentialsFile(cfg.CredentialsFile),
+				chartutils.WithRegistriesFile(cfg.RegistriesFile),
+				chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				chartutils.WithArtifactsDir(wrap.ImageArtifactsDir()),
 				chartutils.WithProgressBar(childLog.ProgressBar()),
 				chartutils.WithInsecureMode(cfg.Insecure),
+				chartutils.WithIncludeNondistributable(cfg.IncludeNondistributable),
 			); err != nil {
-				return childLog.Failf("%v", err)
+				return childLog.Failf("failed to pull images: %v", err)
 			}
 			childLog.Infof("All images pulled successfully")
 			return nil
@@ -387,15 +545,21 @@ func pullImages(wrap wrapping.Wrap, cfg *Config) error {
 	return nil
 }
 
-func wrapChart(inputPath string, opts ...Option) (string, error) {
+func wrapChart(inputPath string, opts ...Option) (_ string, err error) {
 	cfg := NewConfig(opts...)
 
-	ctx := cfg.Context
+	ctx, span := telemetry.StartSpan(cfg.Context, "WrapChart", attribute.String("dt.input", inputPath))
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	parentLog := cfg.GetLogger()
 
+	if err := utils.ValidateCompression(cfg.Compression, cfg.CompressionLevel); err != nil {
+		return "", err
+	}
+
 	l := parentLog.StartSection(fmt.Sprintf("Wrapping Helm chart %q", inputPath))
 
-	subCfg := NewConfig(append(opts, WithLogger(l))...)
+	subCfg := NewConfig(append(opts, WithLogger(l), WithContext(ctx))...)
 
 	chartPath, err := ResolveInputChartPath(inputPath, subCfg)
 	if err != nil {
@@ -430,44 +594,148 @@ func wrapChart(inputPath string, opts ...Option) (string, error) {
 
 	outputFile := cfg.OutputFile
 	if outputFile == "" {
-		outputBaseName := fmt.Sprintf("%s-%s.wrap.tgz", chart.Name(), chart.Version())
+		outputBaseName := fmt.Sprintf("%s-%s.wrap%s", chart.Name(), chart.Version(), cfg.Compression.Extension())
 		if outputFile, err = filepath.Abs(outputBaseName); err != nil {
 			l.Debugf("failed to normalize output file: %v", err)
 			outputFile = filepath.Join(filepath.Dir(chartRoot), outputBaseName)
 		}
 	}
 
-	if !cfg.SkipPullImages {
+	if !cfg.SkipPullImages && !cfg.Streaming {
 		if err := pullImages(wrap, subCfg); err != nil {
 			return "", err
 		}
 	}
 	if cfg.Carvelize {
-		if err := l.Section(fmt.Sprintf("Generating Carvel bundle for Helm chart %q", chartPath), func(childLog dtlog.SectionLogger) error {
-			return carvelize.GenerateBundle(
-				chartRoot,
-				chartutils.WithAnnotationsKey(cfg.AnnotationsKey),
-				chartutils.WithLog(childLog),
+		if err := generateCarvelBundle(chartPath, chartRoot, subCfg); err != nil {
+			return "", err
+		}
+	}
+
+	prefix := fmt.Sprintf("%s-%s", chart.Name(), chart.Version())
+	return writeWrapFile(wrap, outputFile, prefix, subCfg)
+}
+
+// writeWrapFile compresses the wrap into outputFile, streaming its images into it when requested
+func writeWrapFile(wrap wrapping.Bundle, outputFile string, prefix string, cfg *Config) (string, error) {
+	l := cfg.GetLogger()
+	if cfg.Streaming {
+		if err := streamWrap(wrap, outputFile, prefix, cfg); err != nil {
+			return "", err
+		}
+		l.Infof("Streamed into %q", cfg.wrapFile(outputFile))
+	} else {
+		if err := l.ExecuteStep(
+			"Compressing Helm chart...",
+			func() error {
+				return utils.TarContext(cfg.Context, wrap.RootDir(), outputFile, cfg.tarConfig(prefix))
+			},
+		); err != nil {
+			return "", l.Failf("failed to wrap Helm chart: %w", err)
+		}
+		l.Infof("Compressed into %q", cfg.wrapFile(outputFile))
+	}
+
+	if err := cfg.Report.SetOutput(cfg.wrapFile(outputFile)); err != nil {
+		return "", err
+	}
+	return cfg.wrapFile(outputFile), nil
+}
+
+func generateCarvelBundle(chartPath string, chartRoot string, cfg *Config) error {
+	l := cfg.GetLogger()
+	if err := l.Section(fmt.Sprintf("Generating Carvel bundle for Helm chart %q", chartPath), func(childLog dtlog.SectionLogger) error {
+		return carvelize.GenerateBundle(
+			chartRoot,
+			chartutils.WithAnnotationsKey(cfg.AnnotationsKey),
+			chartutils.WithLog(childLog),
+		)
+	}); err != nil {
+		return l.Failf("%w", err)
+	}
+	l.Infof("Carvel bundle created successfully")
+	return nil
+}
+
+// streamWrap writes the wrap into outputFile, streaming the images from their registries straight
+// into the archive instead of staging them into the wrap directory first
+func streamWrap(wrap wrapping.Bundle, outputFile string, prefix string, cfg *Config) (err error) {
+	l := cfg.GetLogger()
+
+	lock, err := wrap.GetImagesLock()
+	if err != nil {
+		return l.Failf("Failed to load Images.lock: %v", err)
+	}
+	withImages := !cfg.SkipPullImages && len(lock.Images) > 0
+	if !cfg.SkipPullImages && len(lock.Images) == 0 {
+		l.Warnf("No images found in Images.lock")
+	}
+
+	if withImages && cfg.FetchArtifacts {
+		if err := l.ExecuteStep("Fetching image artifacts", func() error {
+			return chartutils.PullImagesArtifacts(
+				lock,
+				wrap.ImageArtifactsDir(),
+				chartutils.WithContext(cfg.Context),
+				chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+				chartutils.WithCredentialsFile(cfg.CredentialsFile),
+				chartutils.WithRegistriesFile(cfg.RegistriesFile),
+				chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+				chartutils.WithInsecureMode(cfg.Insecure),
 			)
 		}); err != nil {
-			return "", l.Failf("%w", err)
+			return l.Failf("Failed to fetch image artifacts: %w", err)
 		}
-		l.Infof("Carvel bundle created successfully")
 	}
 
-	if err := l.ExecuteStep(
-		"Compressing Helm chart...",
-		func() error {
-			return utils.TarContext(ctx, wrap.RootDir(), outputFile, utils.TarConfig{
-				Prefix: fmt.Sprintf("%s-%s", chart.Name(), chart.Version()),
-			})
-		},
-	); err != nil {
-		return "", l.Failf("failed to wrap Helm chart: %w", err)
+	tw, err := utils.NewTarWriter(outputFile, cfg.tarConfig(""))
+	if err != nil {
+		return l.Failf("failed to wrap Helm chart: %w", err)
+	}
+	defer func() {
+		if closeErr := tw.Close(); closeErr != nil && err == nil {
+			err = l.Failf("failed to wrap Helm chart: %w", closeErr)
+		}
+		// Do not leave a truncated wrap behind
+		if err != nil {
+			_ = tw.Remove()
+		}
+	}()
+
+	// The chart goes first so its Images.lock can be read without walking the whole archive
+	if err := l.ExecuteStep("Compressing Helm chart...", func() error {
+		return tw.AddDir(cfg.Context, wrap.RootDir(), utils.TarConfig{Prefix: prefix})
+	}); err != nil {
+		return l.Failf("failed to wrap Helm chart: %w", err)
 	}
-	l.Infof("Compressed into %q", outputFile)
 
-	return outputFile, nil
+	if !withImages {
+		return nil
+	}
+	relImagesDir, err := filepath.Rel(wrap.RootDir(), wrap.ImagesDir())
+	if err != nil {
+		return l.Failf("failed to resolve images directory: %w", err)
+	}
+	return l.Section(fmt.Sprintf("Streaming images into %q", outputFile), func(childLog dtlog.SectionLogger) error {
+		if err := chartutils.StreamImages(
+			lock,
+			tw,
+			path.Join(prefix, filepath.ToSlash(relImagesDir)),
+			chartutils.WithLog(childLog),
+			chartutils.WithContext(cfg.Context),
+			chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+			chartutils.WithCredentialsFile(cfg.CredentialsFile),
+			chartutils.WithRegistriesFile(cfg.RegistriesFile),
+			chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+			chartutils.WithProgressBar(childLog.ProgressBar()),
+			chartutils.WithInsecureMode(cfg.Insecure),
+			chartutils.WithIncludeNondistributable(cfg.IncludeNondistributable),
+		); err != nil {
+			return childLog.Failf("%v", err)
+		}
+		childLog.Infof("All images streamed successfully")
+		return nil
+	})
 }
 
 // NewCmd builds a new wrap command
@@ -478,23 +746,86 @@ func NewCmd(cfg *config.Config) *cobra.Command {
 	var fetchArtifacts bool
 	var carvelize bool
 	var skipPullImages bool
+	var streaming bool
+	var includeNondistributable bool
+	var compression = string(utils.CompressionGzip)
+	var compressionLevel int
+	var splitSize string
+	var manifest string
+	var repoURL string
+	var outputReport string
 	var examples = `  # Wrap a Helm chart from a local folder
   $ dt wrap examples/mariadb
 
   # Wrap a Helm chart in an OCI registry
   $ dt wrap oci://docker.io/bitnamicharts/mariadb
+
+  # Wrap a Helm chart from a Helm repository, either passing its URL or its name in repositories.yaml
+  $ dt wrap mariadb --repo-url https://charts.bitnami.com/bitnami --version 12.2.8
+  $ dt wrap bitnami/mariadb --version 12.2.8
+
+  # Wrap a Helm chart streaming its images straight into the wrap file
+  $ dt wrap oci://docker.io/bitnamicharts/mariadb --stream
+
+  # Wrap a Helm chart with Windows images, including their non-distributable base layers
+  $ dt wrap examples/windows-app --platforms windows/amd64:10.0.17763.5329 --include-nondistributable
+
+  # Wrap a Helm chart into a zstd compressed wrap file
+  $ dt wrap oci://docker.io/bitnamicharts/mariadb --compression zstd
+
+  # Wrap a Helm chart into 4G parts, described by the mariadb-12.2.8.wrap.tgz.index.yaml index file
+  $ dt wrap oci://docker.io/bitnamicharts/mariadb --split-size 4G
+
+  # Wrap several Helm charts into charts.wrap.tgz, sharing their images
+  $ dt wrap oci://docker.io/bitnamicharts/mariadb oci://docker.io/bitnamicharts/wordpress
+
+  # Wrap the Helm charts listed in a release manifest
+  $ dt wrap --manifest release.yaml
+
+  # Verify the integrity of an existing wrap
+  $ dt wrap verify mariadb-12.2.8.wrap.tgz
 	`
 	cmd := &cobra.Command{
-		Use:   "wrap CHART_PATH|OCI_URI",
+		Use:   "wrap CHART_PATH|OCI_URI...",
 		Short: "Wraps a Helm chart",
 		Long: `Wraps a Helm chart either local or remote into a distributable package.
-This command will pull all the container images and wrap it into a single tarball along with the Images.lock and metadata`,
+This command will pull all the container images and wrap it into a single tarball along with the Images.lock and metadata.
+Several Helm charts can be wrapped together, either passing them as arguments or listing them in a release manifest:
+
+  name: platform
+  version: 1.0.0
+  charts:
+    - chart: oci://docker.io/bitnamicharts/mariadb
+      version: 12.2.8
+    - chart: ./wordpress`,
 		Example:       examples,
 		SilenceUsage:  true,
 		SilenceErrors: true,
-		Args:          cobra.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if manifest != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(_ *cobra.Command, args []string) error {
-			chartPath := args[0]
+			var release *Release
+			if manifest != "" {
+				var err error
+				if release, err = LoadRelease(manifest); err != nil {
+					return err
+				}
+			} else if len(args) > 1 {
+				if version != "" {
+					return fmt.Errorf("--version cannot be used when wrapping several Helm charts, set their versions in a --manifest file instead")
+				}
+				if repoURL != "" {
+					return fmt.Errorf("--repo-url cannot be used when wrapping several Helm charts, set their repositories in a --manifest file instead")
+				}
+				release = &Release{}
+				for _, arg := range args {
+					release.Charts = append(release.Charts, ReleaseChart{Chart: arg})
+				}
+			}
 
 			ctx, cancel := cfg.ContextWithSigterm()
 			defer cancel()
@@ -504,24 +835,76 @@ This command will pull all the container images and wrap it into a single tarbal
 				return err
 			}
 
-			parentLog := cfg.Logger()
+			wrapCompression, err := utils.ParseCompression(compression)
+			if err != nil {
+				return err
+			}
+
+			var wrapSplitSize int64
+			if splitSize != "" {
+				if wrapSplitSize, err = utils.ParseSize(splitSize); err != nil {
+					return fmt.Errorf("invalid --split-size: %w", err)
+				}
+			}
 
-			wrappedChart, err := wrapChart(chartPath,
+			var wrapReport *report.Report
+			if outputReport != "" {
+				input := manifest
+				if input == "" {
+					input = strings.Join(args, " ")
+				}
+				wrapReport = report.New("wrap", input)
+			}
+			parentLog := wrapReport.Logger(cfg.Logger())
+
+			wrapOpts := []Option{
 				WithLogger(parentLog),
+				WithReport(wrapReport),
 				WithAnnotationsKey(cfg.AnnotationsKey), WithContext(ctx),
-				WithPlatforms(platforms), WithVersion(version),
+				WithPlatforms(platforms), WithVersion(version), WithRepoURL(repoURL),
 				WithFetchArtifacts(fetchArtifacts), WithCarvelize(carvelize),
 				WithUsePlainHTTP(cfg.UsePlainHTTP), WithInsecure(cfg.Insecure),
+				WithCredentialsFile(cfg.CredentialsFile),
+				WithRegistriesFile(cfg.RegistriesFile),
+				WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				WithOutputFile(outputFile),
 				WithTempDirectory(tmpDir),
 				WithSkipPullImages(skipPullImages),
-			)
+				WithStreaming(streaming),
+				WithIncludeNondistributable(includeNondistributable),
+				WithCompression(wrapCompression),
+				WithCompressionLevel(compressionLevel),
+				WithSplitSize(wrapSplitSize),
+			}
+			if release != nil {
+				wrappedCharts, err := wrapCharts(release, wrapOpts...)
+				reportErr := wrapReport.Complete(outputReport, err)
+				if err != nil {
+					if _, ok := err.(*dtlog.LoggedError); ok {
+						// We already logged it, lets be less verbose
+						err = fmt.Errorf("failed to wrap Helm charts: %v", err)
+					}
+					return errors.Join(err, reportErr)
+				}
+				if reportErr != nil {
+					return reportErr
+				}
+				parentLog.Printf(widgets.TerminalSpacer)
+				parentLog.Successf("Helm charts wrapped into %q", wrappedCharts)
+				return nil
+			}
+
+			wrappedChart, err := wrapChart(args[0], wrapOpts...)
+			reportErr := wrapReport.Complete(outputReport, err)
 			if err != nil {
 				if _, ok := err.(*dtlog.LoggedError); ok {
 					// We already logged it, lets be less verbose
-					return fmt.Errorf("failed to wrap Helm chart: %v", err)
+					err = fmt.Errorf("failed to wrap Helm chart: %v", err)
 				}
-				return err
+				return errors.Join(err, reportErr)
+			}
+			if reportErr != nil {
+				return reportErr
 			}
 
 			parentLog.Printf(widgets.TerminalSpacer)
@@ -531,12 +914,22 @@ This command will pull all the container images and wrap it into a single tarbal
 		},
 	}
 
-	cmd.PersistentFlags().StringVar(&version, "version", version, "when wrapping remote Helm charts from OCI, version to request")
-	cmd.PersistentFlags().StringVar(&outputFile, "output-file", outputFile, "generate a tar.gz with the output of the pull operation")
+	cmd.PersistentFlags().StringVar(&version, "version", version, "when wrapping remote Helm charts from OCI or Helm repositories, version to request")
+	cmd.PersistentFlags().StringVar(&repoURL, "repo-url", repoURL, "URL of the Helm repository to fetch the Helm charts from")
+	cmd.PersistentFlags().StringVar(&outputFile, "output-file", outputFile, "generate a tar file with the output of the pull operation")
 	cmd.PersistentFlags().StringSliceVar(&platforms, "platforms", platforms, "platforms to include in the Images.lock file")
 	cmd.PersistentFlags().BoolVar(&carvelize, "add-carvel-bundle", carvelize, "whether the wrap should include a Carvel bundle or not")
 	cmd.PersistentFlags().BoolVar(&fetchArtifacts, "fetch-artifacts", fetchArtifacts, "fetch remote metadata and signature artifacts")
 	cmd.PersistentFlags().BoolVar(&skipPullImages, "skip-pull-images", skipPullImages, "skip pulling images when wrapping a Helm Chart")
+	cmd.PersistentFlags().BoolVar(&streaming, "stream", streaming, "stream the images directly into the output file instead of staging them on disk first")
+	cmd.PersistentFlags().BoolVar(&includeNondistributable, "include-nondistributable", includeNondistributable, "include the non-distributable (foreign) image layers, such as Windows base layers, in the wrap")
+	cmd.PersistentFlags().StringVar(&compression, "compression", compression, "compression format of the output file (gzip, zstd or none)")
+	cmd.PersistentFlags().IntVar(&compressionLevel, "compression-level", compressionLevel, "compression level of the output file, specific to the compression format (0 uses its default)")
+	cmd.PersistentFlags().StringVar(&manifest, "manifest", manifest, "release manifest file listing the Helm charts to wrap together")
+	cmd.PersistentFlags().StringVar(&outputReport, "output-report", outputReport, "write a summary of the wrap to the given file, in YAML for .yaml and .yml files and JSON otherwise")
+	cmd.PersistentFlags().StringVar(&splitSize, "split-size", splitSize, "split the output file into parts of at most the given size (e.g. 4G, 700MiB), described by an index file")
+
+	cmd.AddCommand(NewVerifyCmd(cfg))
 
 	return cmd
 }
@@ -575,6 +968,9 @@ func wrapContainer(imageRef string, opts ...Option) (string, error) {
 			imagelock.WithSkipImageDigestResolution(cfg.SkipPullImages),
 			imagelock.WithContext(ctx),
 			imagelock.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+			imagelock.WithCredentialsFile(cfg.CredentialsFile),
+			imagelock.WithRegistriesFile(cfg.RegistriesFile),
+			imagelock.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 		)
 		return genErr
 	})
@@ -603,9 +999,13 @@ func wrapContainer(imageRef string, opts ...Option) (string, error) {
 				chartutils.WithContext(ctx),
 				chartutils.WithFetchArtifacts(cfg.FetchArtifacts),
 				chartutils.WithAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
+				chartutils.WithCredentialsFile(cfg.CredentialsFile),
+				chartutils.WithRegistriesFile(cfg.RegistriesFile),
+				chartutils.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				chartutils.WithArtifactsDir(wc.ImageArtifactsDir()),
 				chartutils.WithProgressBar(childLog.ProgressBar()),
 				chartutils.WithInsecureMode(cfg.Insecure),
+				chartutils.WithIncludeNondistributable(cfg.IncludeNondistributable),
 			)
 		})
 		if err != nil {
@@ -622,7 +1022,7 @@ func wrapContainer(imageRef string, opts ...Option) (string, error) {
 	}
 	outputFile := cfg.OutputFile
 	if outputFile == "" {
-		outputBaseName := fmt.Sprintf("%s-%s.container.wrap.tgz", baseName, identifier)
+		outputBaseName := fmt.Sprintf("%s-%s.container.wrap%s", baseName, identifier, cfg.Compression.Extension())
 		if outputFile, err = filepath.Abs(outputBaseName); err != nil {
 			l.Debugf("failed to normalize output file: %v", err)
 			outputFile = outputBaseName
@@ -630,15 +1030,13 @@ func wrapContainer(imageRef string, opts ...Option) (string, error) {
 	}
 
 	if err := l.ExecuteStep("Compressing container image wrap...", func() error {
-		return utils.TarContext(ctx, wc.RootDir(), outputFile, utils.TarConfig{
-			Prefix: fmt.Sprintf("%s-%s", baseName, identifier),
-		})
+		return utils.TarContext(ctx, wc.RootDir(), outputFile, cfg.tarConfig(fmt.Sprintf("%s-%s", baseName, identifier)))
 	}); err != nil {
 		return "", l.Failf("failed to wrap container image: %w", err)
 	}
-	l.Infof("Compressed into %q", outputFile)
+	l.Infof("Compressed into %q", cfg.wrapFile(outputFile))
 
-	return outputFile, nil
+	return cfg.wrapFile(outputFile), nil
 }
 
 // NewContainerCmd builds a new container wrap command
@@ -646,6 +1044,7 @@ func NewContainerCmd(cfg *config.Config) *cobra.Command {
 	var outputFile string
 	var platforms []string
 	var fetchArtifacts bool
+	var includeNondistributable bool
 
 	cmd := &cobra.Command{
 		Use:   "wrap OCI_REF",
@@ -685,8 +1084,12 @@ with an Images.lock file into a single tarball.`,
 				WithFetchArtifacts(fetchArtifacts),
 				WithUsePlainHTTP(cfg.UsePlainHTTP),
 				WithInsecure(cfg.Insecure),
+				WithCredentialsFile(cfg.CredentialsFile),
+				WithRegistriesFile(cfg.RegistriesFile),
+				WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 				WithOutputFile(outputFile),
 				WithTempDirectory(tmpDir),
+				WithIncludeNondistributable(includeNondistributable),
 			)
 			if err != nil {
 				if _, ok := err.(*dtlog.LoggedError); ok {
@@ -705,6 +1108,7 @@ with an Images.lock file into a single tarball.`,
 	cmd.PersistentFlags().StringVar(&outputFile, "output-file", outputFile, "output tarball path (defaults to <name>-<tag>.container.wrap.tgz)")
 	cmd.PersistentFlags().StringSliceVar(&platforms, "platforms", platforms, "platforms to include in the Images.lock file (e.g. linux/amd64,linux/arm64)")
 	cmd.PersistentFlags().BoolVar(&fetchArtifacts, "fetch-artifacts", fetchArtifacts, "fetch remote metadata and signature artifacts")
+	cmd.PersistentFlags().BoolVar(&includeNondistributable, "include-nondistributable", includeNondistributable, "include the non-distributable (foreign) image layers, such as Windows base layers, in the wrap")
 
 	return cmd
 }
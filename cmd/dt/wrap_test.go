@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 
 	"io"
@@ -10,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -19,11 +23,13 @@ import (
 
 	"helm.sh/helm/v3/pkg/repo/repotest"
 
+	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/report"
 	"github.com/vmware-labs/distribution-tooling-for-helm/cmd/dt/wrap"
 	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/carvel"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/wrapping"
 	"gopkg.in/yaml.v3"
@@ -40,6 +46,10 @@ type wrapOpts struct {
 	ChartName             string
 	Version               string
 	OutputFile            string
+	Streaming             bool
+	Compression           utils.Compression
+	SplitSize             int64
+	RepoURL               string
 	SkipExpectedLock      bool
 	Images                []tu.ImageData
 	ArtifactsMetadata     map[string][]byte
@@ -111,7 +121,7 @@ func testChartWrap(t *testing.T, sb *tu.Sandbox, inputChart string, expectedLock
 		expectedWrapFile = cfg.OutputFile
 		args = append(args, "--output-file", expectedWrapFile)
 	} else {
-		expectedWrapFile = filepath.Join(workingDir, fmt.Sprintf("%s-%v.wrap.tgz", cfg.ChartName, cfg.Version))
+		expectedWrapFile = filepath.Join(workingDir, fmt.Sprintf("%s-%v.wrap%s", cfg.ChartName, cfg.Version, cfg.Compression.Extension()))
 	}
 	if cfg.GenerateCarvelBundle {
 		args = append(args, "--add-carvel-bundle")
@@ -119,6 +129,18 @@ func testChartWrap(t *testing.T, sb *tu.Sandbox, inputChart string, expectedLock
 	if cfg.FetchArtifacts {
 		args = append(args, "--fetch-artifacts")
 	}
+	if cfg.Streaming {
+		args = append(args, "--stream")
+	}
+	if cfg.Compression != "" {
+		args = append(args, "--compression", string(cfg.Compression))
+	}
+	if cfg.SplitSize > 0 {
+		args = append(args, "--split-size", fmt.Sprint(cfg.SplitSize))
+	}
+	if cfg.RepoURL != "" {
+		args = append(args, "--repo-url", cfg.RepoURL)
+	}
 
 	if cfg.UseAPI {
 		l := logrus.NewSectionLogger()
@@ -130,6 +152,10 @@ func testChartWrap(t *testing.T, sb *tu.Sandbox, inputChart string, expectedLock
 			wrap.WithFetchArtifacts(cfg.FetchArtifacts),
 			wrap.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
 			wrap.WithOutputFile(expectedWrapFile),
+			wrap.WithStreaming(cfg.Streaming),
+			wrap.WithCompression(cfg.Compression),
+			wrap.WithSplitSize(cfg.SplitSize),
+			wrap.WithRepoURL(cfg.RepoURL),
 			wrap.WithContainerRegistryAuth(cfg.ContainerRegistryAuth.Username, cfg.ContainerRegistryAuth.Password),
 		}
 		_, err := wrap.Chart(inputChart, opts...)
@@ -141,7 +167,15 @@ func testChartWrap(t *testing.T, sb *tu.Sandbox, inputChart string, expectedLock
 			dt(args...).AssertSuccess(t)
 		}
 	}
+	if cfg.SplitSize > 0 {
+		expectedWrapFile += utils.SplitIndexSuffix
+	}
 	require.FileExists(t, expectedWrapFile)
+	if cfg.Compression != "" {
+		compression, err := utils.DetectCompression(expectedWrapFile)
+		require.NoError(t, err)
+		assert.Equal(t, cfg.Compression, compression)
+	}
 
 	tmpDir := sb.TempFile()
 	require.NoError(t, utils.Untar(expectedWrapFile, tmpDir, utils.TarConfig{StripComponents: 1}))
@@ -405,6 +439,67 @@ func (suite *CmdSuite) TestWrapCommand() {
 				})
 			})
 
+			t.Run("Wrap Chart from a Helm repository", func(t *testing.T) {
+				var repoSrv *repotest.Server
+				var repoUser, repoPass string
+				if tc.auth {
+					repoSrv = repotest.NewTempServerWithCleanupAndBasicAuth(t, "")
+					repoUser, repoPass = "username", "password"
+				} else {
+					var err error
+					repoSrv, err = repotest.NewTempServerWithCleanup(t, "")
+					require.NoError(err)
+				}
+				defer repoSrv.Stop()
+
+				chartDir := createSampleChart(sb.TempFile(), withLock)
+
+				data, err := tu.RenderTemplateFile(filepath.Join(scenarioDir, "imagelock.partial.tmpl"),
+					map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "Version": version},
+				)
+				require.NoError(err)
+				var expectedLock map[string]interface{}
+				require.NoError(yaml.Unmarshal([]byte(data), &expectedLock))
+
+				// Clear the timestamp
+				expectedLock["metadata"] = nil
+
+				require.NoError(utils.Tar(chartDir, filepath.Join(repoSrv.Root(), fmt.Sprintf("%s-%s.tgz", chartName, version)), utils.TarConfig{Prefix: chartName}))
+				require.NoError(repoSrv.CreateIndex())
+
+				opts := wrapOpts{
+					ChartName:             chartName,
+					Version:               version,
+					Images:                images,
+					UseAPI:                useAPI,
+					ContainerRegistryAuth: tu.Auth{Username: username, Password: password},
+					Auth:                  tu.Auth{Username: repoUser, Password: repoPass},
+				}
+				t.Run("Using its URL", func(t *testing.T) {
+					repoOpts := opts
+					repoOpts.RepoURL = repoSrv.URL()
+					testChartWrap(t, sb, chartName, expectedLock, repoOpts)
+				})
+				t.Run("Using its name in repositories.yaml", func(t *testing.T) {
+					helmDir, err := sb.Mkdir(sb.TempFile(), 0755)
+					require.NoError(err)
+					_, err = sb.Mkdir(filepath.Join(helmDir, "cache"), 0755)
+					require.NoError(err)
+					index, err := os.ReadFile(filepath.Join(repoSrv.Root(), "index.yaml"))
+					require.NoError(err)
+					_, err = sb.Write(filepath.Join(helmDir, "cache/testrepo-index.yaml"), string(index))
+					require.NoError(err)
+					repositoriesFile, err := sb.Write(filepath.Join(helmDir, "repositories.yaml"), fmt.Sprintf(
+						"apiVersion: v1\nrepositories:\n  - name: testrepo\n    url: %s\n    username: %q\n    password: %q\n",
+						repoSrv.URL(), repoUser, repoPass))
+					require.NoError(err)
+
+					t.Setenv("HELM_REPOSITORY_CONFIG", repositoriesFile)
+					t.Setenv("HELM_REPOSITORY_CACHE", filepath.Join(helmDir, "cache"))
+					testChartWrap(t, sb, "testrepo/"+chartName, expectedLock, opts)
+				})
+			})
+
 			t.Run("Wrap Chart with custom output filename", func(t *testing.T) {
 				tempFilename := fmt.Sprintf("%s/chart.wrap.tar.gz", sb.TempFile())
 				testSampleWrap(t, withLock, tempFilename, false, WithoutArtifacts, useAPI, username, password)
@@ -412,6 +507,170 @@ func (suite *CmdSuite) TestWrapCommand() {
 				suite.Assert().FileExists(tempFilename)
 			})
 
+			t.Run("Wrap Chart streaming images", func(t *testing.T) {
+				chartDir := createSampleChart(sb.TempFile(), withoutLock)
+				for _, fetchArtifacts := range []bool{WithArtifacts, WithoutArtifacts} {
+					tempFilename := fmt.Sprintf("%s/chart.wrap.tar.gz", sb.TempFile())
+					testChartWrap(t, sb, chartDir, nil, wrapOpts{
+						FetchArtifacts:        fetchArtifacts,
+						ChartName:             chartName,
+						Version:               version,
+						OutputFile:            tempFilename,
+						Streaming:             true,
+						SkipExpectedLock:      true,
+						ArtifactsMetadata:     metadataArtifacts,
+						Images:                images,
+						UseAPI:                useAPI,
+						ContainerRegistryAuth: tu.Auth{Username: username, Password: password},
+					})
+					dt("wrap", "verify", tempFilename).AssertSuccessMatch(t, "Wrap .* is valid")
+				}
+			})
+
+			if !tc.auth {
+				t.Run("Wrap Chart writing a report", func(t *testing.T) {
+					chartDir := createSampleChart(sb.TempFile(), withoutLock)
+					outputFile := filepath.Join(sb.TempFile(), "chart.wrap.tgz")
+					for _, reportFile := range []string{filepath.Join(sb.TempFile(), "report.json"), filepath.Join(sb.TempFile(), "report.yaml")} {
+						dt("wrap", chartDir, "--output-file", outputFile, "--output-report", reportFile).AssertSuccess(t)
+
+						data, err := os.ReadFile(reportFile)
+						require.NoError(err)
+						var r report.Report
+						if filepath.Ext(reportFile) == ".yaml" {
+							require.NoError(yaml.Unmarshal(data, &r))
+						} else {
+							require.NoError(json.Unmarshal(data, &r))
+						}
+						assert.Equal(t, "wrap", r.Command)
+						assert.Equal(t, chartDir, r.Input)
+						assert.Equal(t, report.StatusSuccess, r.Status)
+						assert.Equal(t, []report.Chart{{Name: chartName, Version: version}}, r.Charts)
+						assert.NotEmpty(t, r.Steps)
+
+						wrapData, err := os.ReadFile(outputFile)
+						require.NoError(err)
+						assert.Equal(t, &report.File{
+							Path: outputFile, Size: int64(len(wrapData)), SHA256: fmt.Sprintf("%x", sha256.Sum256(wrapData)),
+						}, r.Output)
+
+						require.Len(r.Images, len(images))
+						for i, img := range images {
+							assert.Equal(t, fmt.Sprintf("%s/%s", serverURL, img.Image), r.Images[i].Source)
+							assert.Empty(t, r.Images[i].Destination)
+							require.Len(r.Images[i].Digests, len(img.Digests))
+							for j, d := range img.Digests {
+								assert.Equal(t, report.Digest{Platform: d.Arch, Digest: d.Digest.String()}, r.Images[i].Digests[j])
+							}
+						}
+					}
+
+					// Failing to write the report does not hide the wrap error
+					blocker := sb.Touch(sb.TempFile())
+					dt("wrap", filepath.Join(sb.TempFile(), "missing"), "--output-report", filepath.Join(blocker, "report.json")).
+						AssertErrorMatch(t, regexp.MustCompile(`(?s)failed to wrap Helm chart.*failed to create report directory`))
+				})
+				t.Run("Wrap Chart writing telemetry", func(t *testing.T) {
+					chartDir := createSampleChart(sb.TempFile(), withoutLock)
+					outputFile := filepath.Join(sb.TempFile(), "chart.wrap.tgz")
+					telemetryFile := filepath.Join(sb.TempFile(), "telemetry.json")
+					dt("wrap", chartDir, "--output-file", outputFile, "--telemetry-file", telemetryFile).AssertSuccess(t)
+
+					fh, err := os.Open(telemetryFile)
+					require.NoError(err)
+					defer fh.Close()
+					spans := make(map[string]int)
+					metrics := make(map[string]bool)
+					scanner := bufio.NewScanner(fh)
+					scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+					for scanner.Scan() {
+						var doc struct {
+							Name         string
+							ScopeMetrics []struct {
+								Metrics []struct {
+									Name string
+								}
+							}
+						}
+						require.NoError(json.Unmarshal(scanner.Bytes(), &doc))
+						if doc.Name != "" {
+							spans[doc.Name]++
+						}
+						for _, sm := range doc.ScopeMetrics {
+							for _, m := range sm.Metrics {
+								metrics[m.Name] = true
+							}
+						}
+					}
+					require.NoError(scanner.Err())
+					for _, name := range []string{"WrapChart", "ResolveInputChartPath", "ValidateWrapLock", "PullImages", "Tar"} {
+						assert.Equal(t, 1, spans[name], "expected one %q span", name)
+					}
+					pulledImages := 0
+					for _, img := range images {
+						pulledImages += len(img.Digests)
+					}
+					assert.Equal(t, pulledImages, spans["PullImage"])
+					assert.True(t, metrics[telemetry.BytesMetric])
+				})
+			}
+
+			t.Run("Wrap Chart with other compression formats", func(t *testing.T) {
+				chartDir := createSampleChart(sb.TempFile(), withoutLock)
+				for _, compression := range []utils.Compression{utils.CompressionZstd, utils.CompressionNone} {
+					for _, streaming := range []bool{false, true} {
+						tempFilename := filepath.Join(sb.TempFile(), "chart.wrap"+compression.Extension())
+						testChartWrap(t, sb, chartDir, nil, wrapOpts{
+							ChartName:             chartName,
+							Version:               version,
+							OutputFile:            tempFilename,
+							Streaming:             streaming,
+							Compression:           compression,
+							SkipExpectedLock:      true,
+							Images:                images,
+							UseAPI:                useAPI,
+							ContainerRegistryAuth: tu.Auth{Username: username, Password: password},
+						})
+						dt("wrap", "verify", tempFilename).AssertSuccessMatch(t, "Wrap .* is valid")
+						dt("info", tempFilename).AssertSuccessMatch(t, fmt.Sprintf("Compression: %s", compression))
+					}
+				}
+				dt("wrap", chartDir, "--compression", "lz4").AssertErrorMatch(t, `unsupported compression "lz4"`)
+				dt("wrap", chartDir, "--compression", "zstd", "--compression-level", "30").AssertErrorMatch(t, "invalid zstd compression level 30")
+			})
+
+			t.Run("Wrap Chart split in parts", func(t *testing.T) {
+				chartDir := createSampleChart(sb.TempFile(), withoutLock)
+				for _, streaming := range []bool{false, true} {
+					tempFilename := filepath.Join(sb.TempFile(), "chart.wrap.tgz")
+					testChartWrap(t, sb, chartDir, nil, wrapOpts{
+						ChartName:             chartName,
+						Version:               version,
+						OutputFile:            tempFilename,
+						Streaming:             streaming,
+						SplitSize:             1024,
+						SkipExpectedLock:      true,
+						Images:                images,
+						UseAPI:                useAPI,
+						ContainerRegistryAuth: tu.Auth{Username: username, Password: password},
+					})
+					require.FileExists(utils.SplitPartName(tempFilename, 2))
+					require.NoFileExists(tempFilename)
+
+					dt("wrap", "verify", utils.SplitPartName(tempFilename, 1)).AssertSuccessMatch(t, "Wrap .* is valid")
+					dt("info", utils.SplitPartName(tempFilename, 1)).AssertSuccessMatch(t, `(?s)Chart:\s+test.*Parts: \d+`)
+					dt("info", utils.SplitPartName(tempFilename, 2)).AssertErrorMatch(t, "is not the first part")
+
+					lastPart := utils.SplitPartName(tempFilename, 2)
+					data, err := os.ReadFile(lastPart)
+					require.NoError(err)
+					data[0] ^= 0xff
+					require.NoError(os.WriteFile(lastPart, data, 0644))
+					dt("info", tempFilename+utils.SplitIndexSuffix).AssertErrorMatch(t, "digest mismatch")
+				}
+				dt("wrap", chartDir, "--split-size", "4X").AssertErrorMatch(t, "invalid --split-size")
+			})
+
 			t.Run("Wrap Chart and generate carvel bundle", func(t *testing.T) {
 				tempFilename := fmt.Sprintf("%s/chart.wrap.tar.gz", sb.TempFile())
 				testSampleWrap(t, withLock, tempFilename, true, WithoutArtifacts, useAPI, username, password) // triggers the Carvel checks
@@ -426,3 +685,63 @@ func (suite *CmdSuite) TestWrapCommand() {
 		})
 	}
 }
+
+func (suite *CmdSuite) TestWrapVerifyCommand() {
+	t := suite.T()
+	require := suite.Require()
+
+	sb := suite.sb
+
+	serverURL := "localhost"
+	scenarioDir := "../../testdata/scenarios/complete-chart"
+	chartName := "test"
+
+	createWrap := func(t *testing.T) (string, []tu.ImageData) {
+		wrapDir := sb.TempFile()
+		images, err := writeSampleImages("test", "mytag", filepath.Join(wrapDir, "images"))
+		require.NoError(err)
+		require.NoError(tu.RenderScenario(scenarioDir, filepath.Join(wrapDir, "chart"),
+			map[string]interface{}{"ServerURL": serverURL, "Images": images, "Name": chartName, "RepositoryURL": serverURL},
+		))
+		t.Cleanup(func() { _ = os.RemoveAll(wrapDir) })
+		return wrapDir, images
+	}
+	layoutDir := func(wrapDir string, digest tu.DigestData) string {
+		return filepath.Join(wrapDir, "images", fmt.Sprintf("%s.layout", digest.Digest.Encoded()))
+	}
+
+	t.Run("Verifies a valid wrap", func(t *testing.T) {
+		wrapDir, _ := createWrap(t)
+		tarFile := sb.TempFile()
+		require.NoError(utils.Tar(wrapDir, tarFile, utils.TarConfig{Prefix: chartName}))
+
+		for _, inputPath := range []string{wrapDir, tarFile} {
+			dt("wrap", "verify", inputPath).AssertSuccessMatch(t, "Wrap .* is valid")
+		}
+	})
+	t.Run("Fails when a layout is missing", func(t *testing.T) {
+		wrapDir, images := createWrap(t)
+		digest := images[0].Digests[0]
+		require.NoError(os.RemoveAll(layoutDir(wrapDir, digest)))
+
+		dt("wrap", "verify", wrapDir).AssertErrorMatch(t, fmt.Sprintf(`missing OCI layout for platform %q`, digest.Arch))
+	})
+	t.Run("Fails when a layout is not referenced in Images.lock", func(t *testing.T) {
+		wrapDir, images := createWrap(t)
+		require.NoError(utils.CopyDir(layoutDir(wrapDir, images[0].Digests[0]),
+			filepath.Join(wrapDir, "images", fmt.Sprintf("%s.layout", strings.Repeat("0", 64)))))
+
+		dt("wrap", "verify", wrapDir).AssertErrorMatch(t, `layout .* is not referenced in Images.lock`)
+	})
+	t.Run("Fails when a blob is corrupted", func(t *testing.T) {
+		wrapDir, images := createWrap(t)
+		blobsDir := filepath.Join(layoutDir(wrapDir, images[0].Digests[0]), "blobs", "sha256")
+		entries, err := os.ReadDir(blobsDir)
+		require.NoError(err)
+		for _, entry := range entries {
+			require.NoError(os.WriteFile(filepath.Join(blobsDir, entry.Name()), []byte("corrupted"), 0644))
+		}
+
+		dt("wrap", "verify", wrapDir).AssertErrorMatch(t, `digest mismatch`)
+	})
+}
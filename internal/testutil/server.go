@@ -129,10 +129,12 @@ func manifestResponse(img *ImageData) string {
 {{- $archList := splitList "/" $e.Arch }}
 {{- $os   := index $archList 0 }}
 {{- $arch := index $archList 1 }}
+{{- $variant := "" }}
+{{- if gt (len $archList) 2 }}{{ $variant = index $archList 2 }}{{ end }}
             {
                "mediaType":"application/vnd.docker.distribution.manifest.v2+json",
                "size":430,"digest":"{{$e.Digest}}",
-               "platform":{"architecture":"{{$arch}}","os":"{{$os}}"}
+               "platform":{"architecture":"{{$arch}}","os":"{{$os}}"{{if $variant}},"variant":"{{$variant}}"{{end}}}
             }{{if not (isLast $i $listLen)}},{{end}}
 {{- end}}
         ]
@@ -373,6 +373,9 @@ func ReadRemoteImageManifest(src string, opts ...Option) (map[string]DigestData,
 			}
 
 			arch := fmt.Sprintf("%s/%s", img.Platform.OS, img.Platform.Architecture)
+			if img.Platform.Variant != "" {
+				arch = fmt.Sprintf("%s/%s", arch, img.Platform.Variant)
+			}
 			imgDigest := DigestData{
 				Digest: digest.Digest(img.Digest.String()),
 				Arch:   arch,
@@ -9,8 +9,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/google/go-containerregistry/pkg/authn"
-
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
@@ -21,6 +19,7 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
 )
 
@@ -50,6 +49,9 @@ type Config struct {
 	ResolveReference bool
 	InsecureMode     bool
 	Auth             Auth
+	CredentialsFile  string
+	RegistriesFile   string
+	TLS              registries.TLS
 }
 
 // Option defines a Config option
@@ -65,6 +67,27 @@ func WithAuth(username, password string) func(cfg *Config) {
 	}
 }
 
+// WithCredentialsFile configures the per-registry credentials file used when no Auth is provided
+func WithCredentialsFile(file string) func(cfg *Config) {
+	return func(cfg *Config) {
+		cfg.CredentialsFile = file
+	}
+}
+
+// WithRegistriesFile configures the registries file defining the mirrors to pull artifacts from
+func WithRegistriesFile(file string) func(cfg *Config) {
+	return func(cfg *Config) {
+		cfg.RegistriesFile = file
+	}
+}
+
+// WithTLS configures the TLS certificate authorities and client certificate used to access the registries
+func WithTLS(caFile, certFile, keyFile string) func(cfg *Config) {
+	return func(cfg *Config) {
+		cfg.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
 // WithInsecureMode configures Insecure transport
 func WithInsecureMode(insecure bool) func(cfg *Config) {
 	return func(cfg *Config) {
@@ -100,17 +123,15 @@ func getImageTagAndDigest(image string, opts ...Option) (string, string, error)
 	switch v := ref.(type) {
 	case name.Tag:
 		cfg := NewConfig(opts...)
-		craneOpts := make([]crane.Option, 0)
-		if cfg.InsecureMode {
-			craneOpts = append(craneOpts, crane.Insecure)
+		craneOpts, err := getArtifactCraneOpts(context.Background(), cfg)
+		if err != nil {
+			return "", "", err
 		}
-		if cfg.Auth.Password != "" && cfg.Auth.Username != "" {
-			craneOpts = append(craneOpts, crane.WithAuth(&authn.Basic{
-				Username: cfg.Auth.Username,
-				Password: cfg.Auth.Password,
-			}))
+		mirrors, err := registries.Load(cfg.RegistriesFile)
+		if err != nil {
+			return "", "", err
 		}
-		desc, err := imagelock.GetImageRemoteDescriptor(image, craneOpts...)
+		desc, err := mirrors.Get(image, crane.GetOptions(craneOpts...))
 		if err != nil {
 			return "", "", fmt.Errorf("error getting descriptor: %w", err)
 		}
@@ -144,13 +165,9 @@ func pushArtifact(ctx context.Context, image string, dest string, tagSuffix stri
 	if !utils.FileExists(dest) {
 		return "", ErrLocalArtifactNotExist
 	}
-	craneOpts := []crane.Option{crane.WithContext(ctx)}
-
-	if cfg.Auth.Password != "" && cfg.Auth.Username != "" {
-		craneOpts = append(craneOpts, crane.WithAuth(&authn.Basic{
-			Username: cfg.Auth.Username,
-			Password: cfg.Auth.Password,
-		}))
+	craneOpts, err := getArtifactCraneOpts(ctx, cfg)
+	if err != nil {
+		return "", err
 	}
 	repo, err := getImageRepository(image)
 	if err != nil {
@@ -256,15 +273,9 @@ func getImageRepository(image string) (string, error) {
 func pullArtifact(ctx context.Context, image string, destDir string, tagSuffix string, opts ...Option) (string, error) {
 	cfg := NewConfig(opts...)
 
-	craneOpts := []crane.Option{crane.WithContext(ctx)}
-	if cfg.InsecureMode {
-		craneOpts = append(craneOpts, crane.Insecure)
-	}
-	if cfg.Auth.Password != "" && cfg.Auth.Username != "" {
-		craneOpts = append(craneOpts, crane.WithAuth(&authn.Basic{
-			Username: cfg.Auth.Username,
-			Password: cfg.Auth.Password,
-		}))
+	craneOpts, err := getArtifactCraneOpts(ctx, cfg)
+	if err != nil {
+		return "", err
 	}
 	o := crane.GetOptions(craneOpts...)
 
@@ -285,19 +296,45 @@ func pullArtifact(ctx context.Context, image string, destDir string, tagSuffix s
 		tag = fmt.Sprintf("%s-%s", imgTag, tagSuffix)
 	}
 
-	exist, err := TagExist(ctx, repo, tag, o)
+	mirrors, err := registries.Load(cfg.RegistriesFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to check tag %q: %w", tag, err)
+		return "", err
 	}
-	if !exist {
-		return "", ErrTagDoesNotExist
+	ref, err := name.NewRepository(repo, o.Name...)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse repository %q: %w", repo, err)
 	}
-
-	newImg := fmt.Sprintf("%s:%s", repo, tag)
-	rmt, err := imagelock.GetImageRemoteDescriptor(newImg, craneOpts...)
+	candidates, err := mirrors.Repositories(ref)
 	if err != nil {
 		return "", err
 	}
+
+	// Pull from the first repository, trying the mirrors first, holding the tag. If any of them
+	// could be checked, the tag is reported as missing rather than returning the errors of the others
+	var rmt *remote.Descriptor
+	var allErrors error
+	checked := false
+	for _, candidate := range candidates {
+		exist, err := TagExist(ctx, candidate.Name(), tag, o)
+		if err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("failed to check tag %q: %w", tag, err))
+			continue
+		}
+		checked = true
+		if !exist {
+			continue
+		}
+		if rmt, err = remote.Get(candidate.Tag(tag), o.Remote...); err != nil {
+			return "", err
+		}
+		break
+	}
+	if rmt == nil {
+		if checked {
+			return "", ErrTagDoesNotExist
+		}
+		return "", allErrors
+	}
 	img, err := rmt.Image()
 	if err != nil {
 		return "", err
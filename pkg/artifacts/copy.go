@@ -0,0 +1,98 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/auth"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+)
+
+func getArtifactCraneOpts(ctx context.Context, cfg *Config) ([]crane.Option, error) {
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return nil, err
+	}
+	craneOpts, err := regs.CraneOptions(cfg.TLS, cfg.InsecureMode)
+	if err != nil {
+		return nil, err
+	}
+	craneOpts = append(craneOpts, crane.WithContext(ctx))
+	authOpt, err := auth.CraneOption(cfg.Auth.Username, cfg.Auth.Password, cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	craneOpts = append(craneOpts, authOpt)
+	return craneOpts, nil
+}
+
+// copyArtifact copies the artifact tagged srcTag in srcRepo into dstRepo as dstTag, returning its digest
+func copyArtifact(srcRepo, srcTag, dstRepo, dstTag string, craneOpts []crane.Option) (string, error) {
+	exist, err := TagExist(context.Background(), srcRepo, srcTag, crane.GetOptions(craneOpts...))
+	if err != nil {
+		return "", fmt.Errorf("failed to check tag %q: %w", srcTag, err)
+	}
+	if !exist {
+		return "", ErrTagDoesNotExist
+	}
+	src := fmt.Sprintf("%s:%s", srcRepo, srcTag)
+	if err := crane.Copy(src, fmt.Sprintf("%s:%s", dstRepo, dstTag), craneOpts...); err != nil {
+		return "", fmt.Errorf("failed to copy %q: %w", src, err)
+	}
+	return crane.Digest(src, craneOpts...)
+}
+
+// CopyImageArtifacts copies the signature and metadata artifacts of the src image straight into the
+// repository of the dst image, tagged for its image index with digest dstDigest.
+// Artifacts missing in the source repository are skipped
+func CopyImageArtifacts(ctx context.Context, src string, dst string, dstDigest v1.Hash, opts ...Option) error {
+	cfg := NewConfig(opts...)
+	craneOpts, err := getArtifactCraneOpts(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	srcRepo, err := getImageRepository(src)
+	if err != nil {
+		return fmt.Errorf("failed to get image repository: %w", err)
+	}
+	dstRepo, err := getImageRepository(dst)
+	if err != nil {
+		return fmt.Errorf("failed to get image repository: %w", err)
+	}
+	imgTag, hex, err := getImageTagAndDigest(src, opts...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := copyArtifact(srcRepo, fmt.Sprintf("sha256-%s.sig", hex), dstRepo, fmt.Sprintf("sha256-%s.sig", dstDigest.Hex), craneOpts); err != nil && err != ErrTagDoesNotExist {
+		return fmt.Errorf("failed to copy image signature: %w", err)
+	}
+
+	metadataTag := fmt.Sprintf("sha256-%s.metadata", hex)
+	metadataDigest, err := copyArtifact(srcRepo, metadataTag, dstRepo, fmt.Sprintf("sha256-%s.metadata", dstDigest.Hex), craneOpts)
+	if err == ErrTagDoesNotExist {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to copy image metadata: %w", err)
+	}
+	if _, err := copyArtifact(srcRepo, metadataTag, dstRepo, fmt.Sprintf("%s-metadata", imgTag), craneOpts); err != nil {
+		return fmt.Errorf("failed to copy image metadata: %w", err)
+	}
+	// The metadata artifact is copied as is, so its signature keeps the same tag
+	metadataSigTag := fmt.Sprintf("sha256-%s.sig", digestHex(metadataDigest))
+	if _, err := copyArtifact(srcRepo, metadataSigTag, dstRepo, metadataSigTag, craneOpts); err != nil && err != ErrTagDoesNotExist {
+		return fmt.Errorf("failed to copy image metadata signature: %w", err)
+	}
+	return nil
+}
+
+func digestHex(dgst string) string {
+	h, err := v1.NewHash(dgst)
+	if err != nil {
+		return dgst
+	}
+	return h.Hex
+}
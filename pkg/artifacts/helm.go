@@ -2,7 +2,6 @@ package artifacts
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -10,9 +9,15 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	orasauth "oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/auth"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 )
 
 // RegistryClientConfig defines how the client communicates with the remote server
@@ -20,7 +25,11 @@ type RegistryClientConfig struct {
 	UsePlainHTTP     bool
 	UseInsecureHTTPS bool
 	Auth             Auth
+	TLS              registries.TLS
+	RegistriesFile   string
+	CredentialsFile  string
 	TempDir          string
+	RepoURL          string
 }
 
 // RegistryClientOption defines a RegistryClientConfig setting
@@ -38,6 +47,27 @@ func WithRegistryAuth(username, password string) func(c *RegistryClientConfig) {
 	}
 }
 
+// WithRegistryTLS configures the TLS certificate authorities and client certificate of the RegistryClientConfig
+func WithRegistryTLS(caFile, certFile, keyFile string) func(c *RegistryClientConfig) {
+	return func(c *RegistryClientConfig) {
+		c.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
+// WithRegistryRegistriesFile configures the registries file defining the per-registry settings of the RegistryClientConfig
+func WithRegistryRegistriesFile(file string) func(c *RegistryClientConfig) {
+	return func(c *RegistryClientConfig) {
+		c.RegistriesFile = file
+	}
+}
+
+// WithRegistryCredentialsFile configures the per-registry credentials file used when no Auth is provided
+func WithRegistryCredentialsFile(file string) func(c *RegistryClientConfig) {
+	return func(c *RegistryClientConfig) {
+		c.CredentialsFile = file
+	}
+}
+
 // Insecure asks the tool to allow insecure HTTPS connections to the remote server.
 func Insecure(c *RegistryClientConfig) {
 	c.UseInsecureHTTPS = true
@@ -64,6 +94,13 @@ func WithTempDir(dir string) func(c *RegistryClientConfig) {
 	}
 }
 
+// WithRepoURL configures the URL of the Helm repository to pull charts from, instead of an OCI registry
+func WithRepoURL(repoURL string) func(c *RegistryClientConfig) {
+	return func(c *RegistryClientConfig) {
+		c.RepoURL = repoURL
+	}
+}
+
 // NewRegistryClientConfig returns a new RegistryClientConfig with default values
 func NewRegistryClientConfig(opts ...RegistryClientOption) *RegistryClientConfig {
 	cfg := &RegistryClientConfig{
@@ -77,28 +114,64 @@ func NewRegistryClientConfig(opts ...RegistryClientOption) *RegistryClientConfig
 	return cfg
 }
 
-func getRegistryClientWrap(cfg *RegistryClientConfig) (*registryClientWrap, error) {
+// credentialsFileAuthorizer returns the authorizer of the credentials for host in the per-registry
+// credentials file of cfg, if any
+func credentialsFileAuthorizer(cfg *RegistryClientConfig, host string, httpClient *http.Client) (*orasauth.Client, error) {
+	if cfg.CredentialsFile == "" || host == "" {
+		return nil, nil
+	}
+	cf, err := auth.LoadCredentialsFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := name.NewRegistry(host)
+	if err != nil {
+		return nil, nil
+	}
+	c, found := cf.Find(reg.RegistryStr())
+	if !found {
+		return nil, nil
+	}
+	return &orasauth.Client{
+		Client: httpClient,
+		Credential: orasauth.StaticCredential(host, orasauth.Credential{
+			Username: c.Username, Password: c.Password, AccessToken: c.Token, RefreshToken: c.IdentityToken,
+		}),
+	}, nil
+}
+
+// getRegistryClientWrap returns the registry client accessing host with the cfg settings
+func getRegistryClientWrap(cfg *RegistryClientConfig, host string) (*registryClientWrap, error) {
 	var credentialsFile string
 	opts := []registry.ClientOption{}
 
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: cfg.UseInsecureHTTPS, // #nosec G402
-			},
-		},
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return nil, err
 	}
-
+	transport, err := regs.Transport(cfg.TLS, cfg.UseInsecureHTTPS)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{Transport: transport}
+	opts = append(opts, registry.ClientOptHTTPClient(httpClient))
 	if cfg.UsePlainHTTP {
 		opts = append(opts, registry.ClientOptPlainHTTP())
-	} else {
-		opts = append(opts, registry.ClientOptHTTPClient(httpClient))
 	}
 	if cfg.Auth.Username != "" && cfg.Auth.Password != "" {
 		opts = append(
 			opts,
 			registry.ClientOptBasicAuth(cfg.Auth.Username, cfg.Auth.Password),
 		)
+	} else {
+		// Registries not in the credentials file use the Helm and Docker credentials
+		authorizer, err := credentialsFileAuthorizer(cfg, host, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		if authorizer != nil {
+			opts = append(opts, registry.ClientOptAuthorizer(*authorizer))
+		}
 	}
 	r, err := registry.NewClient(opts...)
 	if err != nil {
@@ -120,7 +193,7 @@ func PullChart(chartURL, version string, destDir string, opts ...RegistryClientO
 	}
 	cfg := &action.Configuration{}
 	cc := NewRegistryClientConfig(opts...)
-	reg, err := getRegistryClientWrap(cc)
+	reg, err := getRegistryClientWrap(cc, u.Host)
 	if err != nil {
 		return "", fmt.Errorf("missing registry client: %w", err)
 	}
@@ -144,6 +217,17 @@ func PullChart(chartURL, version string, destDir string, opts ...RegistryClientO
 	client.DestDir = dir
 	client.Untar = true
 	client.Version = version
+	if cc.RepoURL != "" {
+		client.RepoURL = cc.RepoURL
+		client.Username = cc.Auth.Username
+		client.Password = cc.Auth.Password
+		repoTLS, insecure, err := repoTLS(cc, cc.RepoURL)
+		if err != nil {
+			return "", err
+		}
+		client.InsecureSkipTLSverify = insecure
+		client.CaFile, client.CertFile, client.KeyFile = repoTLS.CAFile, repoTLS.CertFile, repoTLS.KeyFile
+	}
 	if _, err = client.Run(chartURL); err != nil {
 		return "", fmt.Errorf("failed to pull Helm chart: %w", err)
 	}
@@ -161,9 +245,27 @@ func PullChart(chartURL, version string, destDir string, opts ...RegistryClientO
 	return filepath.Dir(charts[0]), nil
 }
 
+// IsRepoChart returns true if chartRef refers to a chart in one of the Helm repositories
+// configured in the repositories.yaml file, using the REPO_NAME/CHART_NAME format
+func IsRepoChart(chartRef string) bool {
+	repoName, chartName, found := strings.Cut(chartRef, "/")
+	if !found || repoName == "" || chartName == "" || strings.Contains(chartName, "/") {
+		return false
+	}
+	repositories, err := repo.LoadFile(cli.New().RepositoryConfig)
+	if err != nil {
+		return false
+	}
+	return repositories.Has(repoName)
+}
+
 // PushChart pushes the local chart tarFile to the remote URL provided
 func PushChart(tarFile string, pushChartURL string, opts ...RegistryClientOption) error {
-	reg, err := getRegistryClientWrap(NewRegistryClientConfig(opts...))
+	u, err := url.Parse(pushChartURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	reg, err := getRegistryClientWrap(NewRegistryClientConfig(opts...), u.Host)
 	if err != nil {
 		return fmt.Errorf("missing registry client: %w", err)
 	}
@@ -179,8 +281,12 @@ func PushChart(tarFile string, pushChartURL string, opts ...RegistryClientOption
 }
 
 func showRemoteHelmChart(chartURL string, version string, cfg *RegistryClientConfig) (string, error) {
+	u, err := url.Parse(chartURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
 	client := action.NewShowWithConfig(action.ShowChart, &action.Configuration{})
-	reg, err := getRegistryClientWrap(cfg)
+	reg, err := getRegistryClientWrap(cfg, u.Host)
 	if err != nil {
 		return "", fmt.Errorf("missing registry client: %w", err)
 	}
@@ -0,0 +1,186 @@
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+)
+
+// OpenLayout returns the OCI image layout at dir, creating an empty one if it does not exist yet
+func OpenLayout(dir string) (layout.Path, error) {
+	if utils.FileExists(filepath.Join(dir, "index.json")) {
+		lp, err := layout.FromPath(dir)
+		if err != nil {
+			return "", fmt.Errorf("failed to open OCI layout %q: %w", dir, err)
+		}
+		return lp, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create OCI layout directory: %w", err)
+	}
+	lp, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return "", fmt.Errorf("failed to create OCI layout %q: %w", dir, err)
+	}
+	return lp, nil
+}
+
+// refNameOptions returns the layout options annotating a descriptor with its reference.
+// Any previous entry with the same reference is replaced
+func refNameOptions(ref string) (match.Matcher, layout.Option) {
+	annotations := map[string]string{ocispec.AnnotationRefName: ref}
+	return match.Annotation(ocispec.AnnotationRefName, ref), layout.WithAnnotations(annotations)
+}
+
+// WriteIndexToLayout adds the image index idx to the OCI layout lp, referenced as ref
+func WriteIndexToLayout(lp layout.Path, idx v1.ImageIndex, ref string) error {
+	matcher, opt := refNameOptions(ref)
+	if err := lp.ReplaceIndex(idx, matcher, opt); err != nil {
+		return fmt.Errorf("failed to write %q to the OCI layout: %w", ref, err)
+	}
+	return nil
+}
+
+func writeArtifactToLayout(lp layout.Path, dir string, ref string) (v1.Hash, error) {
+	if !utils.FileExists(dir) {
+		return v1.Hash{}, ErrLocalArtifactNotExist
+	}
+	artifact, err := loadImage(dir)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	img, ok := artifact.(v1.Image)
+	if !ok {
+		return v1.Hash{}, fmt.Errorf("unsupported image type %T", artifact)
+	}
+	matcher, opt := refNameOptions(ref)
+	if err := lp.ReplaceImage(img, matcher, opt); err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to write %q to the OCI layout: %w", ref, err)
+	}
+	return img.Digest()
+}
+
+// writeAssetMetadataToLayout mirrors pushAssetMetadata, tagging the metadata stored at dir
+// both by the asset digest and by its tag
+func writeAssetMetadataToLayout(lp layout.Path, repo string, tag string, dgst v1.Hash, dir string) error {
+	metadataDigest, err := writeArtifactToLayout(lp, dir, fmt.Sprintf("%s:sha256-%s.metadata", repo, dgst.Hex))
+	if err != nil {
+		return err
+	}
+	if _, err := writeArtifactToLayout(lp, dir, fmt.Sprintf("%s:%s-metadata", repo, tag)); err != nil {
+		return err
+	}
+	_, err = writeArtifactToLayout(lp, fmt.Sprintf("%s.sig", dir), fmt.Sprintf("%s:sha256-%s.sig", repo, metadataDigest.Hex))
+	return err
+}
+
+// WriteImageArtifactsToLayout adds the signature and metadata of image stored in destDir to the OCI layout lp,
+// tagged as they would be in a registry next to the image index with digest dgst.
+// Artifacts not stored locally are skipped
+func WriteImageArtifactsToLayout(lp layout.Path, image *imagelock.ChartImage, destDir string, dgst v1.Hash) error {
+	ref, err := name.ParseReference(image.Image)
+	if err != nil {
+		return fmt.Errorf("failed to parse image reference: %w", err)
+	}
+	repo := ref.Context().Name()
+	// Images referenced by digest are stored using the digest as their tag
+	tag := strings.TrimPrefix(ref.Identifier(), "sha256:")
+	artifactsDir := filepath.Join(destDir, image.Chart, image.Name)
+
+	if _, err := writeArtifactToLayout(lp, filepath.Join(artifactsDir, fmt.Sprintf("%s.sig", tag)),
+		fmt.Sprintf("%s:sha256-%s.sig", repo, dgst.Hex)); err != nil && err != ErrLocalArtifactNotExist {
+		return fmt.Errorf("failed to write image signature: %w", err)
+	}
+	if err := writeAssetMetadataToLayout(lp, repo, tag, dgst, filepath.Join(artifactsDir, fmt.Sprintf("%s.metadata", tag))); err != nil && err != ErrLocalArtifactNotExist {
+		return fmt.Errorf("failed to write image metadata: %w", err)
+	}
+	return nil
+}
+
+func writeBlobToLayout(lp layout.Path, data []byte, mediaType types.MediaType) (v1.Descriptor, error) {
+	h, size, err := v1.SHA256(bytes.NewReader(data))
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	if err := lp.WriteBlob(h, io.NopCloser(bytes.NewReader(data))); err != nil {
+		return v1.Descriptor{}, err
+	}
+	return v1.Descriptor{MediaType: mediaType, Digest: h, Size: size}, nil
+}
+
+// WriteChartToLayout adds the packaged chart tarFile to the OCI layout lp as a Helm chart artifact
+// referenced as ref, along with the chart metadata stored at metadataDir, if any
+func WriteChartToLayout(lp layout.Path, tarFile string, ref string, metadataDir string) error {
+	chart, err := loader.Load(tarFile)
+	if err != nil {
+		return fmt.Errorf("failed to load Helm chart: %w", err)
+	}
+	configData, err := json.Marshal(chart.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to serialize Helm chart metadata: %w", err)
+	}
+	chartData, err := os.ReadFile(tarFile)
+	if err != nil {
+		return fmt.Errorf("failed to read Helm chart: %w", err)
+	}
+
+	config, err := writeBlobToLayout(lp, configData, registry.ConfigMediaType)
+	if err != nil {
+		return fmt.Errorf("failed to write Helm chart config: %w", err)
+	}
+	layer, err := writeBlobToLayout(lp, chartData, registry.ChartLayerMediaType)
+	if err != nil {
+		return fmt.Errorf("failed to write Helm chart content: %w", err)
+	}
+	manifestData, err := json.Marshal(&v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        config,
+		Layers:        []v1.Descriptor{layer},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize Helm chart manifest: %w", err)
+	}
+	desc, err := writeBlobToLayout(lp, manifestData, types.OCIManifestSchema1)
+	if err != nil {
+		return fmt.Errorf("failed to write Helm chart manifest: %w", err)
+	}
+
+	matcher, _ := refNameOptions(ref)
+	if err := lp.RemoveDescriptors(matcher); err != nil {
+		return fmt.Errorf("failed to update OCI layout: %w", err)
+	}
+	desc.Annotations = map[string]string{ocispec.AnnotationRefName: ref}
+	if err := lp.AppendDescriptor(desc); err != nil {
+		return fmt.Errorf("failed to write %q to the OCI layout: %w", ref, err)
+	}
+
+	if metadataDir == "" || !utils.FileExists(metadataDir) {
+		return nil
+	}
+	chartRef, err := name.ParseReference(ref)
+	if err != nil {
+		return fmt.Errorf("failed to parse chart reference: %w", err)
+	}
+	if err := writeAssetMetadataToLayout(lp, chartRef.Context().Name(), chartRef.Identifier(), desc.Digest, metadataDir); err != nil {
+		return fmt.Errorf("failed to write Helm chart metadata: %w", err)
+	}
+	return nil
+}
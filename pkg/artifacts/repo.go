@@ -0,0 +1,120 @@
+package artifacts
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+)
+
+// IsHTTPRepoURL returns true if url points to a classic Helm HTTP repository instead of an OCI registry
+func IsHTTPRepoURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// repoTLS returns the TLS settings used to access the Helm repository at repoURL, and whether
+// to skip the verification of its certificate
+func repoTLS(cfg *RegistryClientConfig, repoURL string) (registries.TLS, bool, error) {
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return registries.TLS{}, false, err
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return registries.TLS{}, false, fmt.Errorf("invalid Helm repository URL: %w", err)
+	}
+	return regs.TLSFor(u.Host, cfg.TLS), cfg.UseInsecureHTTPS || regs.Registry(u.Host).Insecure, nil
+}
+
+// AddChartToRepoDir copies the packaged chart tarFile into repoDir, creating or updating
+// the index.yaml of the Helm repository it contains
+func AddChartToRepoDir(tarFile string, repoDir string) error {
+	chart, err := loader.Load(tarFile)
+	if err != nil {
+		return fmt.Errorf("failed to load Helm chart: %w", err)
+	}
+	if err := os.MkdirAll(repoDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create Helm repository directory: %w", err)
+	}
+	name, version := chart.Name(), chart.Metadata.Version
+	filename := fmt.Sprintf("%s-%s.tgz", name, version)
+	if err := utils.CopyFile(tarFile, filepath.Join(repoDir, filename)); err != nil {
+		return fmt.Errorf("failed to copy Helm chart: %w", err)
+	}
+	digest, err := provenance.DigestFile(filepath.Join(repoDir, filename))
+	if err != nil {
+		return fmt.Errorf("failed to compute Helm chart digest: %w", err)
+	}
+
+	indexFile := filepath.Join(repoDir, "index.yaml")
+	index := repo.NewIndexFile()
+	if utils.FileExists(indexFile) {
+		if index, err = repo.LoadIndexFile(indexFile); err != nil {
+			return fmt.Errorf("failed to load Helm repository index: %w", err)
+		}
+	}
+	// Unwrapping the same chart again replaces its previous entry
+	versions := index.Entries[name][:0]
+	for _, v := range index.Entries[name] {
+		if v.Version != version {
+			versions = append(versions, v)
+		}
+	}
+	index.Entries[name] = versions
+	if err := index.MustAdd(chart.Metadata, filename, "", digest); err != nil {
+		return fmt.Errorf("failed to add Helm chart to the repository index: %w", err)
+	}
+	index.SortEntries()
+	index.Generated = time.Now()
+	return index.WriteFile(indexFile, 0o644)
+}
+
+// UploadChart uploads the packaged chart tarFile to the ChartMuseum compatible Helm repository at repoURL
+func UploadChart(tarFile string, repoURL string, opts ...RegistryClientOption) error {
+	cfg := NewRegistryClientConfig(opts...)
+
+	fh, err := os.Open(tarFile)
+	if err != nil {
+		return fmt.Errorf("failed to open Helm chart: %w", err)
+	}
+	defer fh.Close()
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(repoURL, "/")+"/api/charts", fh)
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if cfg.Auth.Username != "" && cfg.Auth.Password != "" {
+		req.SetBasicAuth(cfg.Auth.Username, cfg.Auth.Password)
+	}
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return err
+	}
+	transport, err := regs.Transport(cfg.TLS, cfg.UseInsecureHTTPS)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload Helm chart: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload Helm chart: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
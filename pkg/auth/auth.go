@@ -0,0 +1,89 @@
+// Package auth resolves the credentials used to access OCI registries
+package auth
+
+import (
+	"os"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// Keychain returns the keychain resolving the registry credentials when none are explicitly provided.
+// It looks them up in the Docker config, including its docker-credential-* helpers, as written by
+// dt auth login or docker login, and then in the Helm registry config, as written by helm registry login
+func Keychain() authn.Keychain {
+	return authn.NewMultiKeychain(authn.DefaultKeychain, NewConfigFileKeychain(cli.New().RegistryConfig))
+}
+
+// CraneOption returns the crane option authenticating with username and password, if both are provided.
+// Otherwise, credentials are looked up in the per-registry credentialsFile, if any, and then in Keychain.
+// The credentials file is loaded and validated once, when creating the option
+func CraneOption(username, password string, credentialsFile string) (crane.Option, error) {
+	if username != "" && password != "" {
+		return crane.WithAuth(&authn.Basic{Username: username, Password: password}), nil
+	}
+	if credentialsFile != "" {
+		cf, err := LoadCredentialsFile(credentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		return crane.WithAuthFromKeychain(authn.NewMultiKeychain(NewCredentialsFileKeychain(cf), Keychain())), nil
+	}
+	return crane.WithAuthFromKeychain(Keychain()), nil
+}
+
+// configFileKeychain resolves credentials from a Docker-formatted config file
+type configFileKeychain struct {
+	filename string
+}
+
+// NewConfigFileKeychain returns a keychain resolving credentials from the Docker-formatted config file
+// filename, and the credential helpers it configures. Missing files resolve to anonymous access
+func NewConfigFileKeychain(filename string) authn.Keychain {
+	return &configFileKeychain{filename: filename}
+}
+
+// Resolve implements authn.Keychain
+func (k *configFileKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	f, err := os.Open(k.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return authn.Anonymous, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	cf, err := config.LoadFromReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg, empty types.AuthConfig
+	for _, key := range []string{target.String(), target.RegistryStr()} {
+		if key == name.DefaultRegistry {
+			key = authn.DefaultAuthKey
+		}
+		if cfg, err = cf.GetAuthConfig(key); err != nil {
+			return nil, err
+		}
+		// GetAuthConfig always sets the ServerAddress, which we do not use
+		cfg.ServerAddress = ""
+		if cfg != empty {
+			break
+		}
+	}
+	if cfg == empty {
+		return authn.Anonymous, nil
+	}
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      cfg.Username,
+		Password:      cfg.Password,
+		Auth:          cfg.Auth,
+		IdentityToken: cfg.IdentityToken,
+		RegistryToken: cfg.RegistryToken,
+	}), nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, filename string, host, username, password string) {
+	t.Helper()
+	credentials := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+	data := fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, credentials)
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.NoError(t, os.WriteFile(filename, []byte(data), 0600))
+}
+
+// isolateConfig points the Docker and Helm config locations to an empty directory
+func isolateConfig(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("DOCKER_CONFIG", filepath.Join(dir, ".docker"))
+	t.Setenv("REGISTRY_AUTH_FILE", "")
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(dir, "helm", "registry", "config.json"))
+	return dir
+}
+
+func TestConfigFileKeychain(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, configFile, "registry.example.com", "user", "pass")
+	keychain := NewConfigFileKeychain(configFile)
+
+	t.Run("Resolves the registry credentials", func(t *testing.T) {
+		reg, err := name.NewRegistry("registry.example.com")
+		require.NoError(t, err)
+		a, err := keychain.Resolve(reg)
+		require.NoError(t, err)
+		cfg, err := a.Authorization()
+		require.NoError(t, err)
+		assert.Equal(t, "user", cfg.Username)
+		assert.Equal(t, "pass", cfg.Password)
+	})
+	t.Run("Resolves unknown registries to anonymous", func(t *testing.T) {
+		reg, err := name.NewRegistry("other.example.com")
+		require.NoError(t, err)
+		a, err := keychain.Resolve(reg)
+		require.NoError(t, err)
+		assert.Equal(t, authn.Anonymous, a)
+	})
+	t.Run("Resolves missing files to anonymous", func(t *testing.T) {
+		reg, err := name.NewRegistry("registry.example.com")
+		require.NoError(t, err)
+		a, err := NewConfigFileKeychain(filepath.Join(t.TempDir(), "missing.json")).Resolve(reg)
+		require.NoError(t, err)
+		assert.Equal(t, authn.Anonymous, a)
+	})
+}
+
+// digest fetches the digest of image authenticating with the CraneOption of the given credentials
+func digest(t *testing.T, image string, username, password string, credentialsFile string) error {
+	t.Helper()
+	opt, err := CraneOption(username, password, credentialsFile)
+	require.NoError(t, err)
+	_, err = crane.Digest(image, opt)
+	return err
+}
+
+func TestCraneOption(t *testing.T) {
+	silentLog := log.New(io.Discard, "", 0)
+	regHandler := registry.New(registry.Logger(silentLog))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	image := fmt.Sprintf("%s/test:1.0", u.Host)
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, image, crane.WithAuth(&authn.Basic{Username: "user", Password: "pass"})))
+
+	t.Run("Uses the explicit credentials", func(t *testing.T) {
+		isolateConfig(t)
+		assert.NoError(t, digest(t, image, "user", "pass", ""))
+		assert.Error(t, digest(t, image, "user", "wrong", ""))
+	})
+	t.Run("Fails without credentials", func(t *testing.T) {
+		isolateConfig(t)
+		assert.ErrorContains(t, digest(t, image, "", "", ""), "401 Unauthorized")
+	})
+	t.Run("Uses the Docker config credentials", func(t *testing.T) {
+		dir := isolateConfig(t)
+		writeConfigFile(t, filepath.Join(dir, ".docker", "config.json"), u.Host, "user", "pass")
+		assert.NoError(t, digest(t, image, "", "", ""))
+	})
+	t.Run("Uses the Helm registry config credentials", func(t *testing.T) {
+		dir := isolateConfig(t)
+		writeConfigFile(t, filepath.Join(dir, "helm", "registry", "config.json"), u.Host, "user", "pass")
+		assert.NoError(t, digest(t, image, "", "", ""))
+	})
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials defines the authentication to use for the registries matching Registry, either a
+// registry host, such as docker.io or registry.example.com:5000, or a wildcard such as *.example.com
+type Credentials struct {
+	Registry string `yaml:"registry"`
+	// Username and Password configure basic authentication
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Token is a bearer token sent as is to the registry
+	Token string `yaml:"token,omitempty"`
+	// IdentityToken is an OAuth2 refresh token exchanged for registry tokens
+	IdentityToken string `yaml:"identityToken,omitempty"`
+}
+
+// CredentialsFile defines the per-registry credentials file
+type CredentialsFile struct {
+	Credentials []Credentials `yaml:"credentials"`
+}
+
+// LoadCredentialsFile reads the per-registry credentials file filename
+func LoadCredentialsFile(filename string) (*CredentialsFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	cf := &CredentialsFile{}
+	if err := yaml.Unmarshal(data, cf); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %q: %w", filename, err)
+	}
+	for i, c := range cf.Credentials {
+		if c.Registry == "" {
+			return nil, fmt.Errorf("credentials file %q: entry %d does not define a registry", filename, i)
+		}
+		if _, err := path.Match(c.Registry, ""); err != nil {
+			return nil, fmt.Errorf("credentials file %q: invalid registry pattern %q: %w", filename, c.Registry, err)
+		}
+	}
+	return cf, nil
+}
+
+// Find returns the credentials for registry. Exact host entries take precedence over wildcards,
+// which are tried in file order
+func (cf *CredentialsFile) Find(registry string) (Credentials, bool) {
+	for _, c := range cf.Credentials {
+		if strings.Contains(c.Registry, "*") {
+			continue
+		}
+		if reg, err := name.NewRegistry(c.Registry); err == nil && reg.RegistryStr() == registry {
+			return c, true
+		}
+	}
+	for _, c := range cf.Credentials {
+		if !strings.Contains(c.Registry, "*") {
+			continue
+		}
+		if matched, _ := path.Match(c.Registry, registry); matched {
+			return c, true
+		}
+	}
+	return Credentials{}, false
+}
+
+// Authenticator returns the authenticator for the credentials
+func (c Credentials) Authenticator() authn.Authenticator {
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      c.Username,
+		Password:      c.Password,
+		RegistryToken: c.Token,
+		IdentityToken: c.IdentityToken,
+	})
+}
+
+// credentialsFileKeychain resolves credentials from a per-registry credentials file
+type credentialsFileKeychain struct {
+	credentials *CredentialsFile
+}
+
+// NewCredentialsFileKeychain returns a keychain resolving credentials from the per-registry credentials
+// file cf, as loaded by LoadCredentialsFile. Registries not listed in it resolve to anonymous access
+func NewCredentialsFileKeychain(cf *CredentialsFile) authn.Keychain {
+	return &credentialsFileKeychain{credentials: cf}
+}
+
+// Resolve implements authn.Keychain
+func (k *credentialsFileKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	c, found := k.credentials.Find(target.RegistryStr())
+	if !found {
+		return authn.Anonymous, nil
+	}
+	return c.Authenticator(), nil
+}
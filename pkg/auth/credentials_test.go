@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCredentialsFile(t *testing.T, data string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "credentials.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(data), 0600))
+	return filename
+}
+
+func TestLoadCredentialsFile(t *testing.T) {
+	t.Run("Loads the credentials", func(t *testing.T) {
+		cf, err := LoadCredentialsFile(writeCredentialsFile(t, `
+credentials:
+  - registry: docker.io
+    username: user
+    password: pass
+  - registry: "*.example.com"
+    token: abc
+  - registry: registry.example.com:5000
+    identityToken: xyz
+`))
+		require.NoError(t, err)
+		assert.Equal(t, []Credentials{
+			{Registry: "docker.io", Username: "user", Password: "pass"},
+			{Registry: "*.example.com", Token: "abc"},
+			{Registry: "registry.example.com:5000", IdentityToken: "xyz"},
+		}, cf.Credentials)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		_, err := LoadCredentialsFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorContains(t, err, "failed to read credentials file")
+
+		_, err = LoadCredentialsFile(writeCredentialsFile(t, "credentials: [\n"))
+		assert.ErrorContains(t, err, "failed to parse credentials file")
+
+		_, err = LoadCredentialsFile(writeCredentialsFile(t, "credentials:\n  - username: user\n"))
+		assert.ErrorContains(t, err, "does not define a registry")
+
+		_, err = LoadCredentialsFile(writeCredentialsFile(t, "credentials:\n  - registry: \"[registry\"\n"))
+		assert.ErrorContains(t, err, "invalid registry pattern")
+	})
+}
+
+func TestCredentialsFile_Find(t *testing.T) {
+	cf := &CredentialsFile{Credentials: []Credentials{
+		{Registry: "*.example.com", Token: "wildcard"},
+		{Registry: "registry.example.com", Token: "exact"},
+		{Registry: "docker.io", Token: "docker"},
+		{Registry: "*", Token: "any"},
+	}}
+	tests := []struct {
+		registry string
+		want     string
+	}{
+		{registry: "registry.example.com", want: "exact"},
+		{registry: "other.example.com", want: "wildcard"},
+		{registry: "index.docker.io", want: "docker"},
+		{registry: "quay.io", want: "any"},
+	}
+	for _, tc := range tests {
+		c, found := cf.Find(tc.registry)
+		assert.True(t, found, tc.registry)
+		assert.Equal(t, tc.want, c.Token, tc.registry)
+	}
+
+	_, found := (&CredentialsFile{}).Find("quay.io")
+	assert.False(t, found)
+}
+
+func TestCredentials_Authenticator(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials Credentials
+		want        authn.AuthConfig
+	}{
+		{name: "basic", credentials: Credentials{Username: "user", Password: "pass"},
+			want: authn.AuthConfig{Username: "user", Password: "pass"}},
+		{name: "bearer token", credentials: Credentials{Token: "abc"}, want: authn.AuthConfig{RegistryToken: "abc"}},
+		{name: "identity token", credentials: Credentials{IdentityToken: "xyz"}, want: authn.AuthConfig{IdentityToken: "xyz"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := tc.credentials.Authenticator().Authorization()
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, *cfg)
+		})
+	}
+}
+
+func TestCredentialsFileKeychain(t *testing.T) {
+	silentLog := log.New(io.Discard, "", 0)
+	newRegistry := func(username, password string) string {
+		regHandler := registry.New(registry.Logger(silentLog))
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if u, p, ok := r.BasicAuth(); !ok || u != username || p != password {
+				w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			regHandler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(s.Close)
+		u, err := url.Parse(s.URL)
+		require.NoError(t, err)
+		return u.Host
+	}
+	isolateConfig(t)
+
+	// Two registries with different credentials
+	images := make([]string, 0)
+	for _, creds := range [][]string{{"user1", "pass1"}, {"user2", "pass2"}} {
+		image := fmt.Sprintf("%s/test:1.0", newRegistry(creds[0], creds[1]))
+		img, err := random.Image(64, 1)
+		require.NoError(t, err)
+		require.NoError(t, crane.Push(img, image, crane.WithAuth(&authn.Basic{Username: creds[0], Password: creds[1]})))
+		images = append(images, image)
+	}
+	registryHost := func(image string) string {
+		ref, err := name.ParseReference(image)
+		require.NoError(t, err)
+		return ref.Context().RegistryStr()
+	}
+	credentialsFile := writeCredentialsFile(t, fmt.Sprintf(`
+credentials:
+  - registry: %q
+    username: user1
+    password: pass1
+  - registry: %q
+    username: user2
+    password: pass2
+`, registryHost(images[0]), registryHost(images[1])))
+
+	for _, image := range images {
+		assert.NoError(t, digest(t, image, "", "", credentialsFile), image)
+		assert.Error(t, digest(t, image, "", "", ""), image)
+	}
+
+	t.Run("Loads the credentials file once", func(t *testing.T) {
+		opt, err := CraneOption("", "", credentialsFile)
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(credentialsFile))
+		_, err = crane.Digest(images[0], opt)
+		assert.NoError(t, err)
+	})
+	t.Run("Fails on invalid credentials files", func(t *testing.T) {
+		_, err := CraneOption("", "", writeCredentialsFile(t, "credentials: [\n"))
+		assert.ErrorContains(t, err, "failed to parse credentials file")
+	})
+}
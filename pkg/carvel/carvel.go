@@ -148,7 +148,7 @@ func CreateImagesLock(lock *imagelock.ImagesLock) (lockconfig.ImagesLock, error)
 func getIntelImageWithDigest(name string, img *imagelock.ChartImage) string {
 
 	for _, digest := range img.Digests {
-		if digest.Arch == "linux/amd64" {
+		if digest.MatchesPlatform("linux/amd64") {
 			return fmt.Sprintf("%s@%s", name, digest.Digest.String())
 		}
 	}
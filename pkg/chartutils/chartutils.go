@@ -167,6 +167,16 @@ func ReadLockFromChart(chartPath string) (*imagelock.ImagesLock, error) {
 		}, utils.TarConfig{StripComponents: 2}); err != nil {
 			return nil, err
 		}
+		if lock == nil {
+			// Multi-chart wraps keep their aggregate Images.lock at the top level
+			if err := utils.FindFileInTar(context.Background(), chartPath, "Images.lock", func(tr *tar.Reader) error {
+				var err error
+				lock, err = imagelock.FromYAML(tr)
+				return err
+			}, utils.TarConfig{StripComponents: 1}); err != nil {
+				return nil, err
+			}
+		}
 		if lock == nil {
 			return nil, fmt.Errorf("wrap does not contain Images.lock")
 		}
@@ -0,0 +1,171 @@
+package chartutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+)
+
+// ArchiveFormat defines the format of the image archives created by ExportImages
+type ArchiveFormat string
+
+const (
+	// DockerArchive creates archives loadable with "docker load"
+	DockerArchive ArchiveFormat = "docker-archive"
+	// OCIArchive creates tarred OCI image layouts, loadable with "ctr import" or "podman load"
+	OCIArchive ArchiveFormat = "oci-archive"
+)
+
+// containerdImageNameAnnotation is the annotation containerd reads the image name from when importing archives
+const containerdImageNameAnnotation = "io.containerd.image.name"
+
+// ParseArchiveFormat returns the ArchiveFormat matching the provided name
+func ParseArchiveFormat(name string) (ArchiveFormat, error) {
+	switch f := ArchiveFormat(strings.ToLower(name)); f {
+	case DockerArchive, OCIArchive:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q (supported: %s, %s)", name, DockerArchive, OCIArchive)
+	}
+}
+
+// Extension returns the conventional file extension for archives of the format
+func (f ArchiveFormat) Extension() string {
+	if f == OCIArchive {
+		return ".oci.tar"
+	}
+	return ".docker.tar"
+}
+
+// LockPlatforms returns the platforms of the images in the provided ImagesLock, in order of appearance
+func LockPlatforms(lock *imagelock.ImagesLock) []string {
+	platforms := make([]string, 0)
+	for _, img := range lock.Images {
+		for _, dgst := range img.Digests {
+			if platform := dgst.PlatformSpec(); !slices.Contains(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+	}
+	return platforms
+}
+
+// ExportImages writes the platform variant of the images in the provided ImagesLock, stored as OCI layouts
+// in imagesDir, into the archive destFile, tagged with their Images.lock references.
+// Images not available for platform are skipped, and those only pinned by digest are left untagged
+// in Docker archives. It returns the number of exported images
+func ExportImages(lock *imagelock.ImagesLock, imagesDir string, destFile string, platform string, format ArchiveFormat, opts ...Option) (int, error) {
+	cfg := NewConfiguration(opts...)
+	ctx := cfg.Context
+	l := cfg.Log
+
+	p, _ := cfg.ProgressBar.WithTotal(len(lock.Images)).UpdateTitle(fmt.Sprintf("Exporting %s images", platform)).Start()
+	defer p.Stop()
+
+	refs := make([]name.Reference, 0, len(lock.Images))
+	images := make(map[name.Reference]v1.Image, len(lock.Images))
+	for _, imgData := range lock.Images {
+		select {
+		// Early abort if the context is done
+		case <-ctx.Done():
+			return 0, fmt.Errorf("cancelled execution")
+		default:
+			p.Add(1)
+			dgst, found := findPlatformDigest(imgData, platform)
+			if !found {
+				l.Debugf("image %q is not available for platform %q", imgData.Image, platform)
+				continue
+			}
+			ref, err := archiveReference(imgData.Image)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse image reference %q: %w", imgData.Image, err)
+			}
+			if _, tagged := ref.(name.Tag); !tagged && format != OCIArchive {
+				p.Warnf("Image %q is only pinned by digest: it will be exported untagged", imgData.Image)
+			}
+			imgDir := getImageLayoutDir(imagesDir, dgst)
+			img, err := loadImage(imgDir)
+			if err != nil {
+				return 0, fmt.Errorf("failed to load image %q: %w", imgDir, err)
+			}
+			refs = append(refs, ref)
+			images[ref] = img
+		}
+	}
+	if len(refs) == 0 {
+		return 0, fmt.Errorf("no images found for platform %q", platform)
+	}
+
+	p.UpdateTitle(fmt.Sprintf("Writing %q", filepath.Base(destFile)))
+	var err error
+	switch format {
+	case OCIArchive:
+		err = writeOCIArchive(refs, images, destFile)
+	default:
+		err = tarball.MultiRefWriteToFile(destFile, images)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %q: %w", destFile, err)
+	}
+	return len(refs), nil
+}
+
+// archiveReference returns the reference to name the image with in the archives. References pinned by digest
+// but also carrying a tag, such as repo:tag@sha256:..., use the tag, as Docker archives can only tag images
+func archiveReference(image string) (name.Reference, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	if _, pinned := ref.(name.Digest); !pinned {
+		return ref, nil
+	}
+	tagged, _, _ := strings.Cut(image, "@")
+	if tag, err := name.NewTag(tagged); err == nil && strings.HasSuffix(tagged, ":"+tag.TagStr()) {
+		return tag, nil
+	}
+	return ref, nil
+}
+
+func findPlatformDigest(imgData *imagelock.ChartImage, platform string) (imagelock.DigestInfo, bool) {
+	for _, dgst := range imgData.Digests {
+		if dgst.MatchesPlatform(platform) {
+			return dgst, true
+		}
+	}
+	return imagelock.DigestInfo{}, false
+}
+
+func writeOCIArchive(refs []name.Reference, images map[name.Reference]v1.Image, destFile string) error {
+	layoutDir, err := os.MkdirTemp(filepath.Dir(destFile), ".oci-archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(layoutDir)
+
+	lp, err := layout.Write(layoutDir, empty.Index)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if err := lp.AppendImage(images[ref], layout.WithAnnotations(map[string]string{
+			ocispec.AnnotationRefName:     ref.Name(),
+			containerdImageNameAnnotation: ref.Name(),
+		})); err != nil {
+			return err
+		}
+	}
+	// The "." prefix stores the layout files at the root of the archive
+	return utils.Tar(layoutDir, destFile, utils.TarConfig{Prefix: ".", Compression: utils.CompressionNone})
+}
@@ -1,13 +1,19 @@
 package chartutils
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -18,9 +24,13 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/auth"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func getNumberOfArtifacts(images imagelock.ImageList) int {
@@ -31,6 +41,23 @@ func getNumberOfArtifacts(images imagelock.ImageList) int {
 	return n
 }
 
+func getCraneOpts(cfg *Configuration, regs *registries.Config) ([]crane.Option, error) {
+	craneOpts, err := regs.CraneOptions(cfg.TLS, cfg.InsecureMode)
+	if err != nil {
+		return nil, err
+	}
+	craneOpts = append(craneOpts, crane.WithContext(cfg.Context))
+	authOpt, err := auth.CraneOption(cfg.Auth.Username, cfg.Auth.Password, cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	craneOpts = append(craneOpts, authOpt)
+	if cfg.IncludeNondistributable {
+		craneOpts = append(craneOpts, crane.WithNondistributable())
+	}
+	return craneOpts, nil
+}
+
 func getArtifactsDir(defaultValue string, cfg *Configuration) string {
 	if cfg.ArtifactsDir != "" {
 		return cfg.ArtifactsDir
@@ -38,23 +65,34 @@ func getArtifactsDir(defaultValue string, cfg *Configuration) string {
 	return defaultValue
 }
 
+// imageAttributes returns the telemetry attributes identifying the image, and its platform if any
+func imageAttributes(img *imagelock.ChartImage, platform string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("dt.chart", img.Chart), attribute.String("dt.image.name", img.Name), attribute.String("dt.image", img.Image),
+	}
+	if platform != "" {
+		attrs = append(attrs, attribute.String("dt.image.platform", platform))
+	}
+	return attrs
+}
+
 // PullImages downloads the list of images specified in the provided ImagesLock
-func PullImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) error {
+func PullImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) (err error) {
 
 	cfg := NewConfiguration(opts...)
-	ctx := cfg.Context
+	ctx, span := telemetry.StartSpan(cfg.Context, "PullImages", attribute.Int("dt.images", len(lock.Images)))
+	defer func() { telemetry.EndSpan(span, err) }()
 
 	artifactsDir := getArtifactsDir(filepath.Join(imagesDir, "artifacts"), cfg)
-	allOpts := []crane.Option{
-		crane.WithContext(ctx),
-	}
-	if cfg.InsecureMode {
-		allOpts = append(allOpts, crane.Insecure)
+	mirrors, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return err
 	}
-	if cfg.Auth.Username != "" && cfg.Auth.Password != "" {
-		allOpts = append(allOpts, crane.WithAuth(&authn.Basic{Username: cfg.Auth.Username, Password: cfg.Auth.Password}))
+	craneOpts, err := getCraneOpts(cfg, mirrors)
+	if err != nil {
+		return err
 	}
-	o := crane.GetOptions(allOpts...)
+	o := crane.GetOptions(craneOpts...)
 
 	if err := os.MkdirAll(imagesDir, 0755); err != nil {
 		return fmt.Errorf("failed to create bundle directory: %v", err)
@@ -78,6 +116,7 @@ func PullImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 			default:
 				p.Add(1)
 				p.UpdateTitle(fmt.Sprintf("Saving image %s/%s %s (%s)", imgDesc.Chart, imgDesc.Name, imgDesc.Image, dgst.Arch))
+				imgCtx, imgSpan := telemetry.StartSpan(ctx, "PullImage", imageAttributes(imgDesc, dgst.Arch)...)
 				err := utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
 					if try > 0 {
 						// The context is done, so we are not retrying, just return the error
@@ -86,65 +125,301 @@ func PullImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 						}
 						l.Debugf("Failed to pull image: %v", prevErr)
 						p.Warnf("Failed to pull image: retrying %d/%d", try, maxRetries)
+						telemetry.RecordRetry(imgCtx, "pull", try, prevErr)
 					}
-					if _, err := pullImage(imgDesc.Image, dgst, imagesDir, o); err != nil {
+					if _, err := pullImage(imgDesc.Image, dgst, imagesDir, cfg.IncludeNondistributable, o, mirrors); err != nil {
 						return err
 					}
 					return nil
 				})
+				telemetry.EndSpan(imgSpan, err)
 
 				if err != nil {
 					return fmt.Errorf("failed to pull image %q: %w", imgDesc.Name, err)
 				}
 			}
 		}
+		if err := utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
+			if try > 0 {
+				if ctx.Err() != nil {
+					return prevErr
+				}
+				l.Debugf("Failed to pull image index: %v", prevErr)
+				telemetry.RecordRetry(ctx, "pull_index", try, prevErr)
+			}
+			return pullOriginalIndex(imgDesc, imagesDir, o, mirrors, l)
+		}); err != nil {
+			return fmt.Errorf("failed to pull image %q index: %w", imgDesc.Name, err)
+		}
 		if cfg.FetchArtifacts {
-			p.UpdateTitle(fmt.Sprintf("Saving image %s/%s signature", imgDesc.Chart, imgDesc.Name))
-			if err := artifacts.PullImageSignatures(context.Background(), imgDesc, artifactsDir, artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password)); err != nil {
-				if err == artifacts.ErrTagDoesNotExist {
-					l.Debugf("image %q does not have an associated signature", imgDesc.Image)
-				} else {
-					return fmt.Errorf("failed to fetch image signatures: %w", err)
+			if err := pullImageArtifacts(imgDesc, artifactsDir, p, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PullImagesArtifacts downloads the signature and metadata artifacts of the images specified in the
+// provided ImagesLock into artifactsDir, without pulling the images themselves
+func PullImagesArtifacts(lock *imagelock.ImagesLock, artifactsDir string, opts ...Option) error {
+	cfg := NewConfiguration(opts...)
+	ctx := cfg.Context
+
+	p, _ := cfg.ProgressBar.WithTotal(len(lock.Images)).UpdateTitle("Pulling image artifacts").Start()
+	defer p.Stop()
+
+	for _, imgDesc := range lock.Images {
+		select {
+		// Early abort if the context is done
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled execution")
+		default:
+			p.Add(1)
+			if err := pullImageArtifacts(imgDesc, artifactsDir, p, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func pullImageArtifacts(imgDesc *imagelock.ChartImage, artifactsDir string, p dtlog.ProgressBar, cfg *Configuration) error {
+	l := cfg.Log
+
+	p.UpdateTitle(fmt.Sprintf("Saving image %s/%s signature", imgDesc.Chart, imgDesc.Name))
+	if err := artifacts.PullImageSignatures(context.Background(), imgDesc, artifactsDir,
+		artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password), artifacts.WithCredentialsFile(cfg.CredentialsFile),
+		artifacts.WithRegistriesFile(cfg.RegistriesFile),
+		artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile)); err != nil {
+		if err == artifacts.ErrTagDoesNotExist {
+			l.Debugf("image %q does not have an associated signature", imgDesc.Image)
+		} else {
+			return fmt.Errorf("failed to fetch image signatures: %w", err)
+		}
+	} else {
+		l.Debugf("image %q signature fetched", imgDesc.Image)
+	}
+	p.UpdateTitle(fmt.Sprintf("Saving image %s/%s metadata", imgDesc.Chart, imgDesc.Name))
+	if err := artifacts.PullImageMetadata(context.Background(), imgDesc, artifactsDir,
+		artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password), artifacts.WithCredentialsFile(cfg.CredentialsFile),
+		artifacts.WithRegistriesFile(cfg.RegistriesFile),
+		artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile)); err != nil {
+		if err == artifacts.ErrTagDoesNotExist {
+			l.Debugf("image %q does not have an associated metadata artifact", imgDesc.Image)
+		} else {
+			return fmt.Errorf("failed to fetch image metadata: %w", err)
+		}
+	} else {
+		l.Debugf("image %q metadata fetched", imgDesc.Image)
+	}
+	return nil
+}
+
+// StreamImages downloads the list of images specified in the provided ImagesLock and writes them,
+// as OCI layouts, directly into the provided TarWriter under imagesDir. Image blobs are streamed from
+// the registry into the archive without being staged on disk. As the archive cannot be rewound, only
+// failures happening before an image starts being written are retried
+func StreamImages(lock *imagelock.ImagesLock, tw *utils.TarWriter, imagesDir string, opts ...Option) error {
+	cfg := NewConfiguration(opts...)
+	ctx := cfg.Context
+	l := cfg.Log
+
+	mirrors, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return err
+	}
+	craneOpts, err := getCraneOpts(cfg, mirrors)
+	if err != nil {
+		return err
+	}
+	o := crane.GetOptions(craneOpts...)
+
+	if len(lock.Images) == 0 {
+		return fmt.Errorf("no images found in Images.lock")
+	}
+
+	p, _ := cfg.ProgressBar.WithTotal(getNumberOfArtifacts(lock.Images)).UpdateTitle("Streaming Images").Start()
+	defer p.Stop()
+	maxRetries := cfg.MaxRetries
+
+	written := make(map[string]struct{})
+	for _, imgDesc := range lock.Images {
+		for _, dgst := range imgDesc.Digests {
+			select {
+			// Early abort if the context is done
+			case <-ctx.Done():
+				return fmt.Errorf("cancelled execution")
+			default:
+				p.Add(1)
+				layoutDir := filepath.ToSlash(getImageLayoutDir(imagesDir, dgst))
+				// Images shared between charts are only stored once
+				if _, found := written[layoutDir]; found {
+					continue
 				}
-			} else {
-				l.Debugf("image %q signature fetched", imgDesc.Image)
+				p.UpdateTitle(fmt.Sprintf("Streaming image %s/%s %s (%s)", imgDesc.Chart, imgDesc.Name, imgDesc.Image, dgst.Arch))
+				var img v1.Image
+				err := utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
+					if try > 0 {
+						// The context is done, so we are not retrying, just return the error
+						if ctx.Err() != nil {
+							return prevErr
+						}
+						l.Debugf("Failed to fetch image: %v", prevErr)
+						p.Warnf("Failed to fetch image: retrying %d/%d", try, maxRetries)
+						telemetry.RecordRetry(ctx, "pull", try, prevErr)
+					}
+					var err error
+					img, err = getRemoteImage(imgDesc.Image, dgst, o, mirrors)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("failed to pull image %q: %w", imgDesc.Name, err)
+				}
+				if !cfg.IncludeNondistributable {
+					img = withoutNondistributableLayers(img)
+				}
+				if err := writeImageLayoutToTar(img, tw, layoutDir); err != nil {
+					return fmt.Errorf("failed to stream image %q: %w", imgDesc.Name, err)
+				}
+				written[layoutDir] = struct{}{}
 			}
-			p.UpdateTitle(fmt.Sprintf("Saving image %s/%s metadata", imgDesc.Chart, imgDesc.Name))
-			if err := artifacts.PullImageMetadata(context.Background(), imgDesc, artifactsDir, artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password)); err != nil {
-				if err == artifacts.ErrTagDoesNotExist {
-					l.Debugf("image %q does not have an associated metadata artifact", imgDesc.Image)
-				} else {
-					return fmt.Errorf("failed to fetch image metadata: %w", err)
+		}
+		var index []byte
+		if err := utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
+			if try > 0 {
+				if ctx.Err() != nil {
+					return prevErr
 				}
-			} else {
-				l.Debugf("image %q metadata fetched", imgDesc.Image)
+				l.Debugf("Failed to fetch image index: %v", prevErr)
+				telemetry.RecordRetry(ctx, "pull_index", try, prevErr)
 			}
+			var err error
+			index, err = fetchOriginalIndex(imgDesc, o, mirrors, l)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to pull image %q index: %w", imgDesc.Name, err)
+		}
+		if index == nil {
+			continue
+		}
+		h, _, err := v1.SHA256(bytes.NewReader(index))
+		if err != nil {
+			return fmt.Errorf("failed to compute image index digest: %w", err)
 		}
+		indexFile := path.Join(imagesDir, indexesDir, fmt.Sprintf("%s.json", h.Hex))
+		if _, found := written[indexFile]; found {
+			continue
+		}
+		if err := tw.AddData(indexFile, index); err != nil {
+			return fmt.Errorf("failed to stream image %q index: %w", imgDesc.Name, err)
+		}
+		written[indexFile] = struct{}{}
 	}
 	return nil
 }
 
+// ociLayoutFile defines the contents of the oci-layout file, as written by crane
+const ociLayoutFile = `{"imageLayoutVersion": "1.0.0"}`
+
+func writeImageLayoutToTar(img v1.Image, tw *utils.TarWriter, layoutDir string) error {
+	blobPath := func(h v1.Hash) string {
+		return path.Join(layoutDir, "blobs", h.Algorithm, h.Hex)
+	}
+	if err := tw.AddData(path.Join(layoutDir, "oci-layout"), []byte(ociLayoutFile)); err != nil {
+		return err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("failed to obtain image layers: %w", err)
+	}
+	writtenBlobs := make(map[v1.Hash]struct{})
+	for _, layer := range layers {
+		h, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("failed to obtain layer digest: %w", err)
+		}
+		if _, found := writtenBlobs[h]; found {
+			continue
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return fmt.Errorf("failed to obtain layer size: %w", err)
+		}
+		if err := writeLayerToTar(layer, tw, blobPath(h), size); err != nil {
+			return err
+		}
+		writtenBlobs[h] = struct{}{}
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return fmt.Errorf("failed to obtain image config digest: %w", err)
+	}
+	rawConfig, err := img.RawConfigFile()
+	if err != nil {
+		return fmt.Errorf("failed to obtain image config file: %w", err)
+	}
+	if err := tw.AddData(blobPath(configName), rawConfig); err != nil {
+		return err
+	}
+
+	desc, err := partial.Descriptor(img)
+	if err != nil {
+		return fmt.Errorf("failed to create descriptor: %w", err)
+	}
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return fmt.Errorf("failed to obtain image manifest: %w", err)
+	}
+	if err := tw.AddData(blobPath(desc.Digest), rawManifest); err != nil {
+		return err
+	}
+
+	index, err := json.MarshalIndent(v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     []v1.Descriptor{*desc},
+	}, "", "   ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize layout index: %w", err)
+	}
+	return tw.AddData(path.Join(layoutDir, "index.json"), index)
+}
+
+func writeLayerToTar(layer v1.Layer, tw *utils.TarWriter, name string, size int64) error {
+	rc, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("failed to read layer: %w", err)
+	}
+	defer rc.Close()
+	return tw.AddReader(name, size, rc)
+}
+
 // PushImages push the list of images in imagesDir to the destination specified in the ImagesLock
-func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) error {
+func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) (err error) {
 	cfg := NewConfiguration(opts...)
 	l := cfg.Log
 
-	ctx := cfg.Context
+	ctx, span := telemetry.StartSpan(cfg.Context, "PushImages", attribute.Int("dt.images", len(lock.Images)))
+	defer func() { telemetry.EndSpan(span, err) }()
 
 	artifactsDir := getArtifactsDir(filepath.Join(imagesDir, "artifacts"), cfg)
 
 	p, _ := cfg.ProgressBar.WithTotal(len(lock.Images)).UpdateTitle("Pushing images").Start()
 	defer p.Stop()
 
-	craneOpts := make([]crane.Option, 0)
-	craneOpts = append(craneOpts, crane.WithContext(ctx))
-	if cfg.InsecureMode {
-		craneOpts = append(craneOpts, crane.Insecure)
+	// Non-distributable layers are pushed whenever they were included in the wrap
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return err
 	}
-	if cfg.Auth.Username != "" && cfg.Auth.Password != "" {
-		craneOpts = append(craneOpts, crane.WithAuth(&authn.Basic{Username: cfg.Auth.Username, Password: cfg.Auth.Password}))
+	craneOpts, err := getCraneOpts(cfg, regs)
+	if err != nil {
+		return err
 	}
-	o := crane.GetOptions(craneOpts...)
+	o := crane.GetOptions(append(craneOpts, crane.WithNondistributable())...)
 
 	maxRetries := cfg.MaxRetries
 	for _, imgData := range lock.Images {
@@ -156,6 +431,7 @@ func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 		default:
 			p.Add(1)
 			p.UpdateTitle(fmt.Sprintf("Pushing image %q", imgData.Image))
+			imgCtx, imgSpan := telemetry.StartSpan(ctx, "PushImage", imageAttributes(imgData, "")...)
 			err := utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
 				if try > 0 {
 					// The context is done, so we are not retrying, just return the error
@@ -164,6 +440,7 @@ func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 					}
 					l.Debugf("Failed to push image: %v", prevErr)
 					p.Warnf("Failed to push image: retrying %d/%d", try, maxRetries)
+					telemetry.RecordRetry(imgCtx, "push", try, prevErr)
 				}
 				if err := pushImage(imgData, imagesDir, l, o); err != nil {
 					return err
@@ -172,6 +449,9 @@ func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 					imgData,
 					artifactsDir,
 					artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+					artifacts.WithCredentialsFile(cfg.CredentialsFile),
+					artifacts.WithRegistriesFile(cfg.RegistriesFile),
+					artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 					artifacts.WithInsecureMode(cfg.InsecureMode)); err != nil {
 					if err == artifacts.ErrLocalArtifactNotExist {
 						l.Debugf("image %q does not have a local signature stored", imgData.Image)
@@ -186,6 +466,9 @@ func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 					imgData,
 					artifactsDir,
 					artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+					artifacts.WithCredentialsFile(cfg.CredentialsFile),
+					artifacts.WithRegistriesFile(cfg.RegistriesFile),
+					artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
 					artifacts.WithInsecureMode(cfg.InsecureMode)); err != nil {
 					if err == artifacts.ErrLocalArtifactNotExist {
 						l.Debugf("image %q does not have a local metadata artifact stored", imgData.Image)
@@ -197,6 +480,7 @@ func PushImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) er
 				}
 				return nil
 			})
+			telemetry.EndSpan(imgSpan, err)
 			if err != nil {
 				return fmt.Errorf("failed to push image %q: %w", imgData.Name, err)
 			}
@@ -238,17 +522,40 @@ func loadImage(path string) (v1.Image, error) {
 	return nil, fmt.Errorf("layout contains non-image (mediaType: %q)", desc.MediaType)
 }
 
+// buildImageIndex returns the index of image, with the images stored in imagesDir. The original index
+// stored with them is used if it lists exactly the image platforms, so the index keeps its digest.
+// Non-distributable layers not stored in imagesDir are left out of the index images
 func buildImageIndex(image *imagelock.ChartImage, imagesDir string) (v1.ImageIndex, error) {
-	adds := make([]mutate.IndexAddendum, 0, len(image.Digests))
-
-	base := mutate.IndexMediaType(empty.Index, types.DockerManifestList)
-	for _, dgstData := range image.Digests {
+	getImage := func(dgstData imagelock.DigestInfo) (v1.Image, error) {
 		imgDir := getImageLayoutDir(imagesDir, dgstData)
 
 		img, err := loadImage(imgDir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load image %q: %w", imgDir, err)
 		}
+		return withLayoutNondistributableLayers(img, imgDir), nil
+	}
+	index, err := findOriginalIndex(image, imagesDir)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil {
+		return newRawIndex(index, image, getImage)
+	}
+	return newImageIndex(image, getImage)
+}
+
+// newImageIndex returns a new index of the image digests, loaded with getImage. The index is an OCI image
+// index if all its images are OCI manifests, and a Docker manifest list otherwise
+func newImageIndex(image *imagelock.ChartImage, getImage func(imagelock.DigestInfo) (v1.Image, error)) (v1.ImageIndex, error) {
+	adds := make([]mutate.IndexAddendum, 0, len(image.Digests))
+
+	indexMediaType := types.OCIImageIndex
+	for _, dgstData := range image.Digests {
+		img, err := getImage(dgstData)
+		if err != nil {
+			return nil, err
+		}
 		newDesc, err := partial.Descriptor(img)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create descriptor: %w", err)
@@ -258,13 +565,16 @@ func buildImageIndex(image *imagelock.ChartImage, imagesDir string) (v1.ImageInd
 			return nil, fmt.Errorf("failed to obtain image config file: %w", err)
 		}
 		newDesc.Platform = cf.Platform()
+		if newDesc.MediaType != types.OCIManifestSchema1 {
+			indexMediaType = types.DockerManifestList
+		}
 
 		adds = append(adds, mutate.IndexAddendum{
 			Add:        img,
 			Descriptor: *newDesc,
 		})
 	}
-	return mutate.AppendManifests(base, adds...), nil
+	return mutate.AppendManifests(mutate.IndexMediaType(empty.Index, indexMediaType), adds...), nil
 }
 
 func pushImage(imgData *imagelock.ChartImage, imagesDir string, log dtlog.Logger, o crane.Options) error {
@@ -287,27 +597,393 @@ func pushImage(imgData *imagelock.ChartImage, imagesDir string, log dtlog.Logger
 	return nil
 }
 
+// WriteImagesToLayout writes the images in the provided ImagesLock, along with their signatures and metadata,
+// into the OCI image layout at layoutDir instead of pushing them. Each image index is annotated with its
+// reference in org.opencontainers.image.ref.name, so the layout can later be copied into the registry
+func WriteImagesToLayout(lock *imagelock.ImagesLock, imagesDir string, layoutDir string, opts ...Option) error {
+	cfg := NewConfiguration(opts...)
+	ctx := cfg.Context
+
+	artifactsDir := getArtifactsDir(filepath.Join(imagesDir, "artifacts"), cfg)
+
+	lp, err := artifacts.OpenLayout(layoutDir)
+	if err != nil {
+		return err
+	}
+
+	p, _ := cfg.ProgressBar.WithTotal(len(lock.Images)).UpdateTitle("Writing images").Start()
+	defer p.Stop()
+
+	for _, imgData := range lock.Images {
+		select {
+		// Early abort if the context is done
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled execution")
+		default:
+			p.Add(1)
+			p.UpdateTitle(fmt.Sprintf("Writing image %q", imgData.Image))
+			idx, err := buildImageIndex(imgData, imagesDir)
+			if err != nil {
+				return fmt.Errorf("failed to build image index for %q: %w", imgData.Image, err)
+			}
+			if err := artifacts.WriteIndexToLayout(lp, idx, imgData.Image); err != nil {
+				return err
+			}
+			dgst, err := idx.Digest()
+			if err != nil {
+				return fmt.Errorf("failed to compute image index digest: %w", err)
+			}
+			if err := artifacts.WriteImageArtifactsToLayout(lp, imgData, artifactsDir, dgst); err != nil {
+				return fmt.Errorf("failed to write artifacts of image %q: %w", imgData.Image, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PushImagesFromTar pushes the images in the provided ImagesLock reading their OCI layouts directly
+// from the imagesDir directory inside the tarFile wrap, without extracting them to disk.
+// Blobs are streamed to the registry as the archive is read, so the archive is only decompressed
+// once per attempt. The image signatures and metadata are read from the configured artifacts directory
+func PushImagesFromTar(lock *imagelock.ImagesLock, tarFile string, imagesDir string, opts ...Option) (err error) {
+	cfg := NewConfiguration(opts...)
+	l := cfg.Log
+
+	ctx, span := telemetry.StartSpan(cfg.Context, "PushImagesFromTar",
+		attribute.Int("dt.images", len(lock.Images)), attribute.String("dt.file", tarFile))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return err
+	}
+	craneOpts, err := getCraneOpts(cfg, regs)
+	if err != nil {
+		return err
+	}
+	o := crane.GetOptions(craneOpts...)
+
+	imagesDir = path.Clean(filepath.ToSlash(imagesDir))
+
+	// Repositories each layout has to be pushed to
+	layoutRepos := make(map[string][]name.Repository)
+	for _, imgData := range lock.Images {
+		ref, err := name.ParseReference(imgData.Image, o.Name...)
+		if err != nil {
+			return fmt.Errorf("failed to parse image reference %q: %w", imgData.Image, err)
+		}
+		for _, dgst := range imgData.Digests {
+			layoutName := fmt.Sprintf("%s.layout", dgst.Digest.Encoded())
+			if !slices.Contains(layoutRepos[layoutName], ref.Context()) {
+				layoutRepos[layoutName] = append(layoutRepos[layoutName], ref.Context())
+			}
+		}
+	}
+
+	p, _ := cfg.ProgressBar.WithTotal(len(lock.Images)).UpdateTitle(fmt.Sprintf("Streaming images from %q", tarFile)).Start()
+	defer p.Stop()
+
+	var manifests map[string]*rawManifest
+	var indexes [][]byte
+	err = utils.ExecuteWithRetry(cfg.MaxRetries, func(try int, prevErr error) error {
+		if try > 0 {
+			// The context is done, so we are not retrying, just return the error
+			if ctx.Err() != nil {
+				return prevErr
+			}
+			l.Debugf("Failed to stream images: %v", prevErr)
+			p.Warnf("Failed to stream images: retrying %d/%d", try, cfg.MaxRetries)
+			telemetry.RecordRetry(ctx, "push_blobs", try, prevErr)
+		}
+		var err error
+		manifests, indexes, err = pushTarBlobs(ctx, tarFile, imagesDir, layoutRepos, p, o)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stream images from %q: %w", tarFile, err)
+	}
+
+	artifactsDir := cfg.ArtifactsDir
+	for _, imgData := range lock.Images {
+		select {
+		// Early abort if the context is done
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled execution")
+		default:
+			p.Add(1)
+			p.UpdateTitle(fmt.Sprintf("Pushing image %q", imgData.Image))
+			imgCtx, imgSpan := telemetry.StartSpan(ctx, "PushImage", imageAttributes(imgData, "")...)
+			err := utils.ExecuteWithRetry(cfg.MaxRetries, func(try int, prevErr error) error {
+				if try > 0 {
+					if ctx.Err() != nil {
+						return prevErr
+					}
+					l.Debugf("Failed to push image: %v", prevErr)
+					p.Warnf("Failed to push image: retrying %d/%d", try, cfg.MaxRetries)
+					telemetry.RecordRetry(imgCtx, "push", try, prevErr)
+				}
+				if err := pushImageManifests(imgData, manifests, indexes, l, o); err != nil {
+					return err
+				}
+				if artifactsDir == "" {
+					return nil
+				}
+				if err := artifacts.PushImageSignatures(context.Background(),
+					imgData,
+					artifactsDir,
+					artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+					artifacts.WithCredentialsFile(cfg.CredentialsFile),
+					artifacts.WithRegistriesFile(cfg.RegistriesFile),
+					artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+					artifacts.WithInsecureMode(cfg.InsecureMode)); err != nil && err != artifacts.ErrLocalArtifactNotExist {
+					return fmt.Errorf("failed to push image signatures: %w", err)
+				}
+				if err := artifacts.PushImageMetadata(context.Background(),
+					imgData,
+					artifactsDir,
+					artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+					artifacts.WithCredentialsFile(cfg.CredentialsFile),
+					artifacts.WithRegistriesFile(cfg.RegistriesFile),
+					artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+					artifacts.WithInsecureMode(cfg.InsecureMode)); err != nil && err != artifacts.ErrLocalArtifactNotExist {
+					return fmt.Errorf("failed to push image metadata: %w", err)
+				}
+				return nil
+			})
+			telemetry.EndSpan(imgSpan, err)
+			if err != nil {
+				return fmt.Errorf("failed to push image %q: %w", imgData.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pushTarBlobs walks the tarFile uploading the blobs of every OCI layout under imagesDir to
+// its target repositories. The image manifests are kept in memory, indexed by layout name, as
+// they can only be pushed once all their blobs are available in the registry. The original image
+// indexes stored in the wrap are returned too
+func pushTarBlobs(ctx context.Context, tarFile string, imagesDir string, layoutRepos map[string][]name.Repository,
+	p dtlog.ProgressBar, o crane.Options) (map[string]*rawManifest, [][]byte, error) {
+	manifests := make(map[string]*rawManifest)
+	indexes := make([][]byte, 0)
+	// Repository each blob was first pushed to, so it can be mounted in the rest
+	uploaded := make(map[v1.Hash]name.Repository)
+
+	err := utils.WalkTarFile(ctx, tarFile, func(tr *tar.Reader, header *tar.Header) error {
+		rel, found := strings.CutPrefix(path.Clean(header.Name), imagesDir+"/")
+		if !found || !header.FileInfo().Mode().IsRegular() {
+			return nil
+		}
+		if path.Dir(rel) == indexesDir {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("failed to read image index from %q: %w", header.Name, err)
+			}
+			indexes = append(indexes, data)
+			return nil
+		}
+		// <digest>.layout/blobs/<algorithm>/<hex>
+		elems := strings.Split(rel, "/")
+		if len(elems) != 4 || elems[1] != "blobs" {
+			return nil
+		}
+		layoutName := elems[0]
+		repos, ok := layoutRepos[layoutName]
+		if !ok {
+			return nil
+		}
+		h := v1.Hash{Algorithm: elems[2], Hex: elems[3]}
+
+		if fmt.Sprintf("%s.layout", h.Hex) == layoutName {
+			m, err := readRawManifest(tr, h)
+			if err != nil {
+				return fmt.Errorf("failed to read manifest from %q: %w", header.Name, err)
+			}
+			manifests[layoutName] = m
+			return nil
+		}
+
+		p.UpdateTitle(fmt.Sprintf("Pushing blob %q", h))
+		for _, repo := range repos {
+			var layer v1.Layer
+			if src, ok := uploaded[h]; ok {
+				srcRef := src.Digest(h.String())
+				remoteLayer, err := remote.Layer(srcRef, o.Remote...)
+				if err != nil {
+					return err
+				}
+				layer = &remote.MountableLayer{Layer: remoteLayer, Reference: srcRef}
+			} else {
+				layer = &tarBlob{r: tr, digest: h, size: header.Size}
+			}
+			if err := remote.WriteLayer(repo, layer, o.Remote...); err != nil {
+				return fmt.Errorf("failed to push blob %q to %q: %w", h, repo, err)
+			}
+			if _, ok := uploaded[h]; !ok {
+				uploaded[h] = repo
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifests, indexes, nil
+}
+
+func pushImageManifests(imgData *imagelock.ChartImage, manifests map[string]*rawManifest, indexes [][]byte, log dtlog.Logger, o crane.Options) error {
+	ref, err := name.ParseReference(imgData.Image, o.Name...)
+	if err != nil {
+		return fmt.Errorf("failed to parse image reference %q: %w", imgData.Image, err)
+	}
+	repo := ref.Context()
+	for _, dgst := range imgData.Digests {
+		layoutName := fmt.Sprintf("%s.layout", dgst.Digest.Encoded())
+		m, ok := manifests[layoutName]
+		if !ok {
+			return fmt.Errorf("missing OCI layout for platform %q", dgst.Arch)
+		}
+		if err := remote.Put(repo.Digest(dgst.Digest.String()), m, o.Remote...); err != nil {
+			return fmt.Errorf("failed to push manifest %q: %w", dgst.Digest, err)
+		}
+	}
+
+	// The original index is pushed as is, as all its manifests are already in the registry
+	for _, index := range indexes {
+		if !indexMatchesImage(index, imgData) {
+			continue
+		}
+		m, err := v1.ParseIndexManifest(bytes.NewReader(index))
+		if err != nil {
+			return fmt.Errorf("failed to parse image index: %w", err)
+		}
+		mediaType := m.MediaType
+		if mediaType == "" {
+			mediaType = types.OCIImageIndex
+		}
+		if err := remote.Put(ref, &rawManifest{data: index, mediaType: mediaType}, o.Remote...); err != nil {
+			return fmt.Errorf("failed to write image index: %w", err)
+		}
+		log.Debugf("Image pushed to %q", ref)
+		return nil
+	}
+
+	idx, err := newImageIndex(imgData, func(dgst imagelock.DigestInfo) (v1.Image, error) {
+		img, err := remote.Image(repo.Digest(dgst.Digest.String()), o.Remote...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pushed image %q: %w", dgst.Digest, err)
+		}
+		return img, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build image index: %w", err)
+	}
+	if err := remote.WriteIndex(ref, idx, o.Remote...); err != nil {
+		return fmt.Errorf("failed to write image index: %w", err)
+	}
+	log.Debugf("Image pushed to %q", ref)
+	return nil
+}
+
+// rawManifest is an image manifest pushed as-is to the registry
+type rawManifest struct {
+	data      []byte
+	mediaType types.MediaType
+}
+
+func (m *rawManifest) RawManifest() ([]byte, error) {
+	return m.data, nil
+}
+
+func (m *rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
+
+func readRawManifest(r io.Reader, h v1.Hash) (*rawManifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	got, _, err := v1.SHA256(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if got != h {
+		return nil, fmt.Errorf("digest mismatch: got %q", got)
+	}
+	m, err := v1.ParseManifest(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType = types.OCIManifestSchema1
+	}
+	return &rawManifest{data: data, mediaType: mediaType}, nil
+}
+
+// tarBlob is a v1.Layer serving the contents of the current tar entry.
+// As the tar is read sequentially, its contents can only be read once
+type tarBlob struct {
+	r      io.Reader
+	digest v1.Hash
+	size   int64
+	read   bool
+}
+
+func (b *tarBlob) Digest() (v1.Hash, error) {
+	return b.digest, nil
+}
+
+func (b *tarBlob) DiffID() (v1.Hash, error) {
+	return v1.Hash{}, fmt.Errorf("diff ID not available for blob %q", b.digest)
+}
+
+func (b *tarBlob) Compressed() (io.ReadCloser, error) {
+	if b.read {
+		return nil, fmt.Errorf("blob %q was already read", b.digest)
+	}
+	b.read = true
+	return io.NopCloser(b.r), nil
+}
+
+func (b *tarBlob) Uncompressed() (io.ReadCloser, error) {
+	return nil, fmt.Errorf("uncompressed contents not available for blob %q", b.digest)
+}
+
+func (b *tarBlob) Size() (int64, error) {
+	return b.size, nil
+}
+
+func (b *tarBlob) MediaType() (types.MediaType, error) {
+	return types.OCILayer, nil
+}
+
 func getImageLayoutDir(imagesDir string, dgst imagelock.DigestInfo) string {
 	return filepath.Join(imagesDir, fmt.Sprintf("%s.layout", dgst.Digest.Encoded()))
 }
 
-func pullImage(image string, digest imagelock.DigestInfo, imagesDir string, o crane.Options) (string, error) {
-	imgDir := getImageLayoutDir(imagesDir, digest)
+func getRemoteImage(image string, digest imagelock.DigestInfo, o crane.Options, mirrors *registries.Config) (v1.Image, error) {
 	src := fmt.Sprintf("%s@%s", image, digest.Digest)
 	if strings.Contains(image, string(digest.Digest)) {
 		src = image
 	}
-	ref, err := name.ParseReference(src, o.Name...)
+	rmt, err := mirrors.Get(src, o)
 	if err != nil {
-		return "", fmt.Errorf("parsing reference %q: %w", src, err)
+		return nil, err
 	}
-	rmt, err := remote.Get(ref, o.Remote...)
+	return rmt.Image()
+}
+
+func pullImage(image string, digest imagelock.DigestInfo, imagesDir string, includeNondistributable bool, o crane.Options, mirrors *registries.Config) (string, error) {
+	imgDir := getImageLayoutDir(imagesDir, digest)
+	img, err := getRemoteImage(image, digest, o, mirrors)
 	if err != nil {
 		return "", err
 	}
-	img, err := rmt.Image()
-	if err != nil {
-		return "", err
+	if !includeNondistributable {
+		img = withoutNondistributableLayers(img)
 	}
 	// We do not want to keep adding images to the index so we
 	// start fresh
@@ -321,3 +997,126 @@ func pullImage(image string, digest imagelock.DigestInfo, imagesDir string, o cr
 	}
 	return imgDir, nil
 }
+
+// VerifyImages checks the integrity of the images stored in imagesDir against the provided ImagesLock
+// without accessing any registry. It recomputes the manifest and blob digests of every OCI layout and
+// reports missing or extra layouts and platforms
+func VerifyImages(lock *imagelock.ImagesLock, imagesDir string, opts ...Option) error {
+	cfg := NewConfiguration(opts...)
+	ctx := cfg.Context
+	l := cfg.Log
+
+	p, _ := cfg.ProgressBar.WithTotal(getNumberOfArtifacts(lock.Images)).UpdateTitle("Verifying images").Start()
+	defer p.Stop()
+
+	var allErrors error
+	expectedLayouts := make(map[string]struct{})
+	for _, imgDesc := range lock.Images {
+		for _, dgst := range imgDesc.Digests {
+			select {
+			// Early abort if the context is done
+			case <-ctx.Done():
+				return fmt.Errorf("cancelled execution")
+			default:
+				p.Add(1)
+				p.UpdateTitle(fmt.Sprintf("Verifying image %s/%s %s (%s)", imgDesc.Chart, imgDesc.Name, imgDesc.Image, dgst.Arch))
+				imgDir := getImageLayoutDir(imagesDir, dgst)
+				expectedLayouts[filepath.Base(imgDir)] = struct{}{}
+				if err := verifyImageLayout(imgDir, dgst); err != nil {
+					allErrors = errors.Join(allErrors, fmt.Errorf("image %q (%s): %w", imgDesc.Image, dgst.Arch, err))
+					continue
+				}
+				l.Debugf("Image %q (%s) layout is valid", imgDesc.Image, dgst.Arch)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read images directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".layout") {
+			continue
+		}
+		if _, found := expectedLayouts[entry.Name()]; !found {
+			allErrors = errors.Join(allErrors, fmt.Errorf("layout %q is not referenced in Images.lock", entry.Name()))
+		}
+	}
+	return allErrors
+}
+
+func verifyImageLayout(imgDir string, dgst imagelock.DigestInfo) error {
+	if !utils.FileExists(imgDir) {
+		return fmt.Errorf("missing OCI layout for platform %q", dgst.Arch)
+	}
+	lp, err := layout.FromPath(imgDir)
+	if err != nil {
+		return fmt.Errorf("failed to load OCI layout: %w", err)
+	}
+	idx, err := lp.ImageIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load OCI layout index: %w", err)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("failed to read OCI layout index: %w", err)
+	}
+	if len(m.Manifests) != 1 {
+		return fmt.Errorf("layout contains unexpected number of entries (%d)", len(m.Manifests))
+	}
+	desc := m.Manifests[0]
+	if desc.Digest.String() != dgst.Digest.String() {
+		return fmt.Errorf("layout manifest digest %q does not match %q", desc.Digest, dgst.Digest)
+	}
+	if err := verifyLayoutBlob(lp, desc.Digest, desc.Size); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	img, err := idx.Image(desc.Digest)
+	if err != nil {
+		return fmt.Errorf("failed to load image: %w", err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("failed to parse image manifest: %w", err)
+	}
+	if err := verifyLayoutBlob(lp, manifest.Config.Digest, manifest.Config.Size); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	for _, layer := range manifest.Layers {
+		// Non-distributable layers are only stored if they were included when pulling the image
+		if !layer.MediaType.IsDistributable() && !layoutHasBlob(lp, layer.Digest) {
+			continue
+		}
+		if err := verifyLayoutBlob(lp, layer.Digest, layer.Size); err != nil {
+			return fmt.Errorf("invalid layer: %w", err)
+		}
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("failed to obtain image config file: %w", err)
+	}
+	if arch := imagelock.PlatformArch(cf.Platform()); !dgst.MatchesPlatform(arch) {
+		return fmt.Errorf("layout platform %q does not match %q", arch, dgst.Arch)
+	}
+	return nil
+}
+
+func verifyLayoutBlob(lp layout.Path, h v1.Hash, size int64) error {
+	rc, err := lp.Blob(h)
+	if err != nil {
+		return fmt.Errorf("missing blob %q: %w", h, err)
+	}
+	defer rc.Close()
+	got, n, err := v1.SHA256(rc)
+	if err != nil {
+		return fmt.Errorf("failed to read blob %q: %w", h, err)
+	}
+	if got != h {
+		return fmt.Errorf("blob %q digest mismatch: got %q", h, got)
+	}
+	if n != size {
+		return fmt.Errorf("blob %q size mismatch: expected %d but got %d", h, size, n)
+	}
+	return nil
+}
@@ -8,12 +8,23 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/logrus"
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
 )
 
 func (suite *ChartUtilsTestSuite) TestPullImages() {
@@ -169,5 +180,364 @@ func (suite *ChartUtilsTestSuite) TestPushImages() {
 				}
 			}
 		})
+
+		t.Run("Push images from tar", func(t *testing.T) {
+			s := httptest.NewServer(registry.New(registry.Logger(silentLog)))
+			defer s.Close()
+
+			targetURL, err := url.Parse(s.URL)
+			require.NoError(err)
+
+			tarFile := filepath.Join(sb.TempFile(), "images.tgz")
+			require.NoError(utils.Tar(imagesDir, tarFile, utils.TarConfig{Prefix: "test/images"}))
+
+			lock, err := imagelock.FromYAMLFile(filepath.Join(chartDir, "Images.lock"))
+			require.NoError(err)
+			for _, img := range lock.Images {
+				img.Image = strings.Replace(img.Image, u.Host, targetURL.Host, 1)
+			}
+			require.NoError(PushImagesFromTar(lock, tarFile, "test/images"))
+
+			for _, img := range images {
+				src := fmt.Sprintf("%s/%s", targetURL.Host, img.Image)
+				remoteDigests, err := tu.ReadRemoteImageManifest(src)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, dgstData := range img.Digests {
+					assert.Equal(dgstData.Digest.Hex(), remoteDigests[dgstData.Arch].Digest.Hex())
+				}
+			}
+		})
+
+		t.Run("Write images to an OCI layout", func(t *testing.T) {
+			lock, err := imagelock.FromYAMLFile(filepath.Join(chartDir, "Images.lock"))
+			require.NoError(err)
+
+			artifactsDir := sb.TempFile()
+			sig, err := random.Image(64, 1)
+			require.NoError(err)
+			img := lock.Images[0]
+			require.NoError(crane.SaveOCI(sig, filepath.Join(artifactsDir, img.Chart, img.Name, "mytag.sig")))
+
+			layoutDir := sb.TempFile()
+			require.NoError(WriteImagesToLayout(lock, imagesDir, layoutDir, WithArtifactsDir(artifactsDir)))
+
+			idx, err := layout.ImageIndexFromPath(layoutDir)
+			require.NoError(err)
+			manifest, err := idx.IndexManifest()
+			require.NoError(err)
+			refs := make(map[string]v1.Hash)
+			for _, desc := range manifest.Manifests {
+				refs[desc.Annotations["org.opencontainers.image.ref.name"]] = desc.Digest
+			}
+			require.Contains(refs, img.Image)
+			imgIdx, err := idx.ImageIndex(refs[img.Image])
+			require.NoError(err)
+			imgManifest, err := imgIdx.IndexManifest()
+			require.NoError(err)
+			assert.Len(imgManifest.Manifests, len(architectures))
+
+			sigDigest, err := sig.Digest()
+			require.NoError(err)
+			sigRef := fmt.Sprintf("%s/test:sha256-%s.sig", serverURL, refs[img.Image].Hex)
+			assert.Equal(sigDigest, refs[sigRef])
+		})
+
+		t.Run("Push images from tar fails with missing layouts", func(t *testing.T) {
+			s := httptest.NewServer(registry.New(registry.Logger(silentLog)))
+			defer s.Close()
+
+			targetURL, err := url.Parse(s.URL)
+			require.NoError(err)
+
+			tarFile := filepath.Join(sb.TempFile(), "images.tgz")
+			require.NoError(utils.Tar(imagesDir, tarFile, utils.TarConfig{Prefix: "test/images"}))
+
+			lock, err := imagelock.FromYAMLFile(filepath.Join(chartDir, "Images.lock"))
+			require.NoError(err)
+			for _, img := range lock.Images {
+				img.Image = strings.Replace(img.Image, u.Host, targetURL.Host, 1)
+			}
+			require.ErrorContains(PushImagesFromTar(lock, tarFile, "other/images", WithMaxRetries(0)), "missing OCI layout for platform")
+		})
+	})
+}
+
+func (suite *ChartUtilsTestSuite) TestOriginalImageIndex() {
+	t := suite.T()
+	sb := suite.sb
+	require := suite.Require()
+	assert := suite.Assert()
+
+	silentLog := log.New(io.Discard, "", 0)
+	newRegistry := func() string {
+		s := httptest.NewServer(registry.New(registry.Logger(silentLog)))
+		t.Cleanup(s.Close)
+		u, err := url.Parse(s.URL)
+		require.NoError(err)
+		return u.Host
+	}
+	srcRegistry := newRegistry()
+
+	// An OCI index with annotations, which a rebuilt index would not keep
+	adds := make([]mutate.IndexAddendum, 0)
+	for _, arch := range []string{"amd64", "arm64"} {
+		img, err := random.Image(64, 1)
+		require.NoError(err)
+		cf, err := img.ConfigFile()
+		require.NoError(err)
+		cf.OS, cf.Architecture = "linux", arch
+		img, err = mutate.ConfigFile(img, cf)
+		require.NoError(err)
+		adds = append(adds, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: arch}},
+		})
+	}
+	idx := mutate.Annotations(
+		mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), adds...),
+		map[string]string{"org.opencontainers.image.source": "https://example.com/test"},
+	).(v1.ImageIndex)
+	srcImage := fmt.Sprintf("%s/test:1.0", srcRegistry)
+	ref, err := name.ParseReference(srcImage)
+	require.NoError(err)
+	require.NoError(remote.WriteIndex(ref, idx))
+	idxDigest, err := idx.Digest()
+	require.NoError(err)
+
+	newLock := func() *imagelock.ImagesLock {
+		lock := imagelock.NewImagesLock()
+		img := &imagelock.ChartImage{Name: "test", Image: srcImage, Chart: "test"}
+		require.NoError(img.FetchDigests(imagelock.NewImagesLockConfig()))
+		lock.Images = append(lock.Images, img)
+		return lock
+	}
+	relocate := func(lock *imagelock.ImagesLock, registry string) string {
+		lock.Images[0].Image = strings.Replace(lock.Images[0].Image, srcRegistry, registry, 1)
+		return lock.Images[0].Image
+	}
+
+	imagesDir := filepath.Join(sb.TempFile(), "images")
+	require.NoError(PullImages(newLock(), imagesDir))
+	assert.FileExists(filepath.Join(imagesDir, "indexes", fmt.Sprintf("%s.json", idxDigest.Hex)))
+
+	t.Run("Pushes the original index", func(_ *testing.T) {
+		lock := newLock()
+		target := relocate(lock, newRegistry())
+		require.NoError(PushImages(lock, imagesDir))
+
+		dgst, err := crane.Digest(target)
+		require.NoError(err)
+		assert.Equal(idxDigest.String(), dgst)
+	})
+	t.Run("Pushes the original index from tar", func(_ *testing.T) {
+		tarFile := filepath.Join(sb.TempFile(), "images.tgz")
+		require.NoError(utils.Tar(imagesDir, tarFile, utils.TarConfig{Prefix: "test/images"}))
+
+		lock := newLock()
+		target := relocate(lock, newRegistry())
+		require.NoError(PushImagesFromTar(lock, tarFile, "test/images"))
+
+		dgst, err := crane.Digest(target)
+		require.NoError(err)
+		assert.Equal(idxDigest.String(), dgst)
+	})
+	t.Run("Mirrors the original index", func(_ *testing.T) {
+		targetRegistry := newRegistry()
+		require.NoError(MirrorImages(newLock(), targetRegistry))
+
+		target, err := utils.RelocateImageURL(srcImage, targetRegistry, true, true)
+		require.NoError(err)
+		dgst, err := crane.Digest(target)
+		require.NoError(err)
+		assert.Equal(idxDigest.String(), dgst)
+	})
+	t.Run("Rebuilds the index when platforms are filtered", func(_ *testing.T) {
+		lock := newLock()
+		require.NoError(lock.FilterPlatforms([]string{"linux/arm64"}))
+		target := relocate(lock, newRegistry())
+		require.NoError(PushImages(lock, imagesDir))
+
+		remoteDigests, err := tu.ReadRemoteImageManifest(target)
+		require.NoError(err)
+		assert.Len(remoteDigests, 1)
+		assert.Equal(lock.Images[0].Digests[0].Digest.Hex(), remoteDigests["linux/arm64"].Digest.Hex())
+		dgst, err := crane.Digest(target)
+		require.NoError(err)
+		assert.NotEqual(idxDigest.String(), dgst)
+	})
+	t.Run("Warns when attestations prevent preserving the index", func(_ *testing.T) {
+		attestation, err := random.Image(64, 1)
+		require.NoError(err)
+		attestedIdx := mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add: attestation,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "unknown", Architecture: "unknown"},
+				Annotations: map[string]string{
+					"vnd.docker.reference.type":   "attestation-manifest",
+					"vnd.docker.reference.digest": adds[0].Descriptor.Digest.String(),
+				},
+			},
+		})
+		attestedImage := fmt.Sprintf("%s/attested:1.0", srcRegistry)
+		attestedRef, err := name.ParseReference(attestedImage)
+		require.NoError(err)
+		require.NoError(remote.WriteIndex(attestedRef, attestedIdx))
+		attestedDigest, err := attestedIdx.Digest()
+		require.NoError(err)
+
+		lock := imagelock.NewImagesLock()
+		img := &imagelock.ChartImage{Name: "attested", Image: attestedImage, Chart: "test"}
+		require.NoError(img.FetchDigests(imagelock.NewImagesLockConfig()))
+		lock.Images = append(lock.Images, img)
+		assert.Len(img.Digests, 2)
+
+		buf := &strings.Builder{}
+		l := logrus.NewLogger()
+		l.SetWriter(buf)
+		attestedImagesDir := filepath.Join(sb.TempFile(), "images")
+		require.NoError(PullImages(lock, attestedImagesDir, WithLog(l)))
+		assert.NoFileExists(filepath.Join(attestedImagesDir, "indexes", fmt.Sprintf("%s.json", attestedDigest.Hex)))
+		assert.Contains(buf.String(), "cannot be preserved, as it lists 1 attestation manifests not stored in the Images.lock")
+
+		target := strings.Replace(attestedImage, srcRegistry, newRegistry(), 1)
+		img.Image = target
+		require.NoError(PushImages(lock, attestedImagesDir))
+		remoteDigests, err := tu.ReadRemoteImageManifest(target)
+		require.NoError(err)
+		assert.Len(remoteDigests, 2)
+		dgst, err := crane.Digest(target)
+		require.NoError(err)
+		assert.NotEqual(attestedDigest.String(), dgst)
+	})
+}
+
+func (suite *ChartUtilsTestSuite) TestNondistributableLayers() {
+	t := suite.T()
+	sb := suite.sb
+	require := suite.Require()
+	assert := suite.Assert()
+
+	silentLog := log.New(io.Discard, "", 0)
+	newRegistry := func() string {
+		s := httptest.NewServer(registry.New(registry.Logger(silentLog)))
+		t.Cleanup(s.Close)
+		u, err := url.Parse(s.URL)
+		require.NoError(err)
+		return u.Host
+	}
+	srcRegistry := newRegistry()
+
+	// A Windows image with a foreign base layer next to a Linux image
+	foreignLayer, err := random.Layer(64, types.DockerForeignLayer)
+	require.NoError(err)
+	foreignDigest, err := foreignLayer.Digest()
+	require.NoError(err)
+	windowsPlatform := v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.5329"}
+
+	newImage := func(platform v1.Platform, layers ...mutate.Addendum) v1.Image {
+		img, err := random.Image(64, 1)
+		require.NoError(err)
+		img, err = mutate.Append(img, layers...)
+		require.NoError(err)
+		cf, err := img.ConfigFile()
+		require.NoError(err)
+		cf.OS, cf.Architecture, cf.OSVersion = platform.OS, platform.Architecture, platform.OSVersion
+		img, err = mutate.ConfigFile(img, cf)
+		require.NoError(err)
+		return img
+	}
+	idx := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.DockerManifestList),
+		mutate.IndexAddendum{
+			Add: newImage(windowsPlatform, mutate.Addendum{
+				Layer:     foreignLayer,
+				MediaType: types.DockerForeignLayer,
+				URLs:      []string{"https://example.com/windows/base"},
+			}),
+			Descriptor: v1.Descriptor{Platform: &windowsPlatform},
+		},
+		mutate.IndexAddendum{
+			Add:        newImage(v1.Platform{OS: "linux", Architecture: "amd64"}),
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+	)
+	srcImage := fmt.Sprintf("%s/windows:1.0", srcRegistry)
+	ref, err := name.ParseReference(srcImage)
+	require.NoError(err)
+	require.NoError(remote.WriteIndex(ref, idx, remote.WithNondistributable))
+	idxDigest, err := idx.Digest()
+	require.NoError(err)
+
+	newLock := func() *imagelock.ImagesLock {
+		lock := imagelock.NewImagesLock()
+		img := &imagelock.ChartImage{Name: "windows", Image: srcImage, Chart: "test"}
+		require.NoError(img.FetchDigests(imagelock.NewImagesLockConfig()))
+		lock.Images = append(lock.Images, img)
+		return lock
+	}
+	pushAndReadForeignLayer := func(imagesDir string) error {
+		lock := newLock()
+		targetRegistry := newRegistry()
+		lock.Images[0].Image = strings.Replace(srcImage, srcRegistry, targetRegistry, 1)
+		require.NoError(PushImages(lock, imagesDir))
+
+		dgst, err := crane.Digest(lock.Images[0].Image)
+		require.NoError(err)
+		assert.Equal(idxDigest.String(), dgst)
+
+		target, err := name.ParseReference(lock.Images[0].Image)
+		require.NoError(err)
+		layer, err := remote.Layer(target.Context().Digest(foreignDigest.String()))
+		require.NoError(err)
+		rc, err := layer.Compressed()
+		if err != nil {
+			return err
+		}
+		return rc.Close()
+	}
+
+	lock := newLock()
+	windowsDigest, err := lock.Images[0].GetDigestForArch("windows/amd64:10.0.17763.5329")
+	require.NoError(err)
+	assert.Equal("windows/amd64", windowsDigest.Arch)
+	assert.Equal("10.0.17763.5329", windowsDigest.OSVersion)
+
+	t.Run("Skips foreign layers by default", func(_ *testing.T) {
+		imagesDir := filepath.Join(sb.TempFile(), "images")
+		require.NoError(PullImages(newLock(), imagesDir))
+
+		lp, err := layout.FromPath(getImageLayoutDir(imagesDir, *windowsDigest))
+		require.NoError(err)
+		assert.False(layoutHasBlob(lp, foreignDigest))
+		require.NoError(VerifyImages(newLock(), imagesDir))
+
+		assert.Error(pushAndReadForeignLayer(imagesDir))
+	})
+	t.Run("Includes foreign layers if requested", func(_ *testing.T) {
+		imagesDir := filepath.Join(sb.TempFile(), "images")
+		require.NoError(PullImages(newLock(), imagesDir, WithIncludeNondistributable(true)))
+
+		lp, err := layout.FromPath(getImageLayoutDir(imagesDir, *windowsDigest))
+		require.NoError(err)
+		assert.True(layoutHasBlob(lp, foreignDigest))
+		require.NoError(VerifyImages(newLock(), imagesDir))
+
+		assert.NoError(pushAndReadForeignLayer(imagesDir))
+	})
+	t.Run("Streams foreign layers if requested", func(_ *testing.T) {
+		for _, include := range []bool{false, true} {
+			tarFile := filepath.Join(sb.TempFile(), "images.tgz")
+			tw, err := utils.NewTarWriter(tarFile, utils.TarConfig{})
+			require.NoError(err)
+			require.NoError(StreamImages(newLock(), tw, "images", WithIncludeNondistributable(include)))
+			require.NoError(tw.Close())
+
+			destDir := sb.TempFile()
+			require.NoError(utils.Untar(tarFile, destDir, utils.TarConfig{}))
+			lp, err := layout.FromPath(getImageLayoutDir(filepath.Join(destDir, "images"), *windowsDigest))
+			require.NoError(err)
+			assert.Equal(include, layoutHasBlob(lp, foreignDigest))
+		}
 	})
 }
@@ -0,0 +1,173 @@
+package chartutils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+)
+
+// indexesDir is the directory, relative to the images directory, storing the original image indexes
+const indexesDir = "indexes"
+
+func getIndexFile(imagesDir string, h v1.Hash) string {
+	return filepath.Join(imagesDir, indexesDir, fmt.Sprintf("%s.json", h.Hex))
+}
+
+// indexMismatch returns why the index manifest m does not list exactly the Images.lock digests of image,
+// or an empty string if it does
+func indexMismatch(m *v1.IndexManifest, image *imagelock.ChartImage) string {
+	listed := func(h v1.Hash) bool {
+		return slices.ContainsFunc(image.Digests, func(dgst imagelock.DigestInfo) bool {
+			return dgst.Digest.String() == h.String()
+		})
+	}
+	attestations, others := 0, 0
+	for _, desc := range m.Manifests {
+		switch {
+		case listed(desc.Digest):
+		case desc.Annotations["vnd.docker.reference.type"] == "attestation-manifest":
+			attestations++
+		default:
+			others++
+		}
+	}
+	switch {
+	case attestations > 0:
+		return fmt.Sprintf("it lists %d attestation manifests not stored in the Images.lock", attestations)
+	case others > 0:
+		return fmt.Sprintf("it lists %d manifests not in the Images.lock, such as filtered platforms", others)
+	case len(m.Manifests) != len(image.Digests):
+		return "it does not list all the Images.lock digests"
+	}
+	return ""
+}
+
+// indexMatchesImage returns true if the index manifest data lists exactly the Images.lock digests of image
+func indexMatchesImage(data []byte, image *imagelock.ChartImage) bool {
+	m, err := v1.ParseIndexManifest(bytes.NewReader(data))
+	return err == nil && indexMismatch(m, image) == ""
+}
+
+// fetchOriginalIndex returns the index manifest of image in its registry, or nil if the image is not
+// an index or the index lists other platforms or manifests, such as attestations, than its Images.lock
+// digests. The index pushed then is rebuilt from the digests, so l is warned about it changing its digest
+func fetchOriginalIndex(image *imagelock.ChartImage, o crane.Options, mirrors *registries.Config, l dtlog.Logger) ([]byte, error) {
+	desc, err := mirrors.Get(image.Image, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image index: %w", err)
+	}
+	if !desc.MediaType.IsIndex() {
+		return nil, nil
+	}
+	m, err := v1.ParseIndexManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image index: %w", err)
+	}
+	if reason := indexMismatch(m, image); reason != "" {
+		l.Warnf("The index of image %q cannot be preserved, as %s: it will be rebuilt with a different digest", image.Image, reason)
+		return nil, nil
+	}
+	return desc.Manifest, nil
+}
+
+// pullOriginalIndex stores the original index manifest of image in imagesDir, so it can be pushed byte-for-byte
+func pullOriginalIndex(image *imagelock.ChartImage, imagesDir string, o crane.Options, mirrors *registries.Config, l dtlog.Logger) error {
+	data, err := fetchOriginalIndex(image, o, mirrors, l)
+	if err != nil || data == nil {
+		return err
+	}
+	h, _, err := v1.SHA256(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to compute image index digest: %w", err)
+	}
+	indexFile := getIndexFile(imagesDir, h)
+	if err := os.MkdirAll(filepath.Dir(indexFile), 0755); err != nil {
+		return fmt.Errorf("failed to create indexes directory: %w", err)
+	}
+	if err := os.WriteFile(indexFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write image index: %w", err)
+	}
+	return nil
+}
+
+// findOriginalIndex returns the original index manifest stored in imagesDir for image, or nil if there is
+// none listing exactly its Images.lock digests, as happens when its platforms were filtered
+func findOriginalIndex(image *imagelock.ChartImage, imagesDir string) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(imagesDir, indexesDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list image indexes: %w", err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index: %w", err)
+		}
+		if indexMatchesImage(data, image) {
+			return data, nil
+		}
+	}
+	return nil, nil
+}
+
+// rawIndex is an image index served byte-for-byte from its original manifest
+type rawIndex struct {
+	data     []byte
+	manifest *v1.IndexManifest
+	getImage func(imagelock.DigestInfo) (v1.Image, error)
+	image    *imagelock.ChartImage
+}
+
+// newRawIndex returns the image index with manifest data, loading its images with getImage
+func newRawIndex(data []byte, image *imagelock.ChartImage, getImage func(imagelock.DigestInfo) (v1.Image, error)) (v1.ImageIndex, error) {
+	m, err := v1.ParseIndexManifest(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image index: %w", err)
+	}
+	return &rawIndex{data: data, manifest: m, getImage: getImage, image: image}, nil
+}
+
+func (i *rawIndex) MediaType() (types.MediaType, error) {
+	if i.manifest.MediaType == "" {
+		return types.OCIImageIndex, nil
+	}
+	return i.manifest.MediaType, nil
+}
+
+func (i *rawIndex) Digest() (v1.Hash, error) {
+	h, _, err := v1.SHA256(bytes.NewReader(i.data))
+	return h, err
+}
+
+func (i *rawIndex) Size() (int64, error) {
+	return int64(len(i.data)), nil
+}
+
+func (i *rawIndex) IndexManifest() (*v1.IndexManifest, error) {
+	return i.manifest.DeepCopy(), nil
+}
+
+func (i *rawIndex) RawManifest() ([]byte, error) {
+	return i.data, nil
+}
+
+func (i *rawIndex) Image(h v1.Hash) (v1.Image, error) {
+	for _, dgst := range i.image.Digests {
+		if dgst.Digest.String() == h.String() {
+			return i.getImage(dgst)
+		}
+	}
+	return nil, fmt.Errorf("image %q not found in index", h)
+}
+
+func (i *rawIndex) ImageIndex(h v1.Hash) (v1.ImageIndex, error) {
+	return nil, fmt.Errorf("nested image index %q is not supported", h)
+}
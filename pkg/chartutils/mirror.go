@@ -0,0 +1,114 @@
+package chartutils
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MirrorImages copies the images in the provided ImagesLock straight from their source registries into
+// the registry at prefix, relocated as their charts would be, without storing them locally. Only the platforms
+// in the Images.lock digests are copied. Layers are mounted across repositories when both live in the same registry.
+// The image signatures and metadata are copied too
+func MirrorImages(lock *imagelock.ImagesLock, prefix string, opts ...Option) (err error) {
+	cfg := NewConfiguration(opts...)
+	l := cfg.Log
+	ctx, span := telemetry.StartSpan(cfg.Context, "MirrorImages",
+		attribute.Int("dt.images", len(lock.Images)), attribute.String("dt.prefix", prefix))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	mirrors, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return err
+	}
+	craneOpts, err := getCraneOpts(cfg, mirrors)
+	if err != nil {
+		return err
+	}
+	o := crane.GetOptions(craneOpts...)
+
+	p, _ := cfg.ProgressBar.WithTotal(len(lock.Images)).UpdateTitle("Mirroring images").Start()
+	defer p.Stop()
+
+	maxRetries := cfg.MaxRetries
+	for _, imgData := range lock.Images {
+		select {
+		// Early abort if the context is done
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled execution")
+		default:
+			p.Add(1)
+			p.UpdateTitle(fmt.Sprintf("Mirroring image %q", imgData.Image))
+			target, err := utils.RelocateImageURL(imgData.Image, prefix, true, cfg.PreserveRepository)
+			if err != nil {
+				return err
+			}
+			imgCtx, imgSpan := telemetry.StartSpan(ctx, "MirrorImage", append(imageAttributes(imgData, ""), attribute.String("dt.target", target))...)
+			err = utils.ExecuteWithRetry(maxRetries, func(try int, prevErr error) error {
+				if try > 0 {
+					// The context is done, so we are not retrying, just return the error
+					if ctx.Err() != nil {
+						return prevErr
+					}
+					l.Debugf("Failed to mirror image: %v", prevErr)
+					p.Warnf("Failed to mirror image: retrying %d/%d", try, maxRetries)
+					telemetry.RecordRetry(imgCtx, "mirror", try, prevErr)
+				}
+				dgst, err := mirrorImage(imgData, target, o, mirrors, l)
+				if err != nil {
+					return err
+				}
+				return artifacts.CopyImageArtifacts(ctx, imgData.Image, target, dgst,
+					artifacts.WithAuth(cfg.Auth.Username, cfg.Auth.Password),
+					artifacts.WithCredentialsFile(cfg.CredentialsFile),
+					artifacts.WithRegistriesFile(cfg.RegistriesFile),
+					artifacts.WithTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile),
+					artifacts.WithInsecureMode(cfg.InsecureMode))
+			})
+			telemetry.EndSpan(imgSpan, err)
+			if err != nil {
+				return fmt.Errorf("failed to mirror image %q: %w", imgData.Name, err)
+			}
+			l.Debugf("Image %q mirrored to %q", imgData.Image, target)
+		}
+	}
+	return nil
+}
+
+func mirrorImage(imgData *imagelock.ChartImage, target string, o crane.Options, mirrors *registries.Config, l dtlog.Logger) (v1.Hash, error) {
+	getImage := func(dgst imagelock.DigestInfo) (v1.Image, error) {
+		return getRemoteImage(imgData.Image, dgst, o, mirrors)
+	}
+	// The source index is copied as is unless its platforms were filtered
+	index, err := fetchOriginalIndex(imgData, o, mirrors, l)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	var idx v1.ImageIndex
+	if index != nil {
+		idx, err = newRawIndex(index, imgData, getImage)
+	} else {
+		idx, err = newImageIndex(imgData, getImage)
+	}
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to build image index: %w", err)
+	}
+	ref, err := name.ParseReference(target, o.Name...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to parse image reference %q: %w", target, err)
+	}
+	if err := remote.WriteIndex(ref, idx, o.Remote...); err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to write image index: %w", err)
+	}
+	return idx.Digest()
+}
@@ -0,0 +1,65 @@
+package chartutils
+
+import (
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+// filteredLayersImage is an image that only transfers the non-distributable (foreign) layers accepted
+// by include. Its manifest keeps referencing all of them, so the image digest does not change and the
+// skipped layers are fetched from their own URLs, as Windows base layers usually are
+type filteredLayersImage struct {
+	v1.Image
+	include func(h v1.Hash) bool
+}
+
+// Layers returns the distributable layers of the image and the non-distributable ones accepted by include
+func (i *filteredLayersImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]v1.Layer, 0, len(layers))
+	for _, layer := range layers {
+		mt, err := layer.MediaType()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain layer media type: %w", err)
+		}
+		if !mt.IsDistributable() {
+			h, err := layer.Digest()
+			if err != nil {
+				return nil, fmt.Errorf("failed to obtain layer digest: %w", err)
+			}
+			if !i.include(h) {
+				continue
+			}
+		}
+		filtered = append(filtered, layer)
+	}
+	return filtered, nil
+}
+
+// withoutNondistributableLayers returns img skipping all its non-distributable layers
+func withoutNondistributableLayers(img v1.Image) v1.Image {
+	return &filteredLayersImage{Image: img, include: func(v1.Hash) bool { return false }}
+}
+
+// withLayoutNondistributableLayers returns img, loaded from the OCI layout at imgDir, skipping the
+// non-distributable layers not stored in it because they were not included when pulling the image
+func withLayoutNondistributableLayers(img v1.Image, imgDir string) v1.Image {
+	lp := layout.Path(imgDir)
+	return &filteredLayersImage{Image: img, include: func(h v1.Hash) bool {
+		return layoutHasBlob(lp, h)
+	}}
+}
+
+func layoutHasBlob(lp layout.Path, h v1.Hash) bool {
+	rc, err := lp.Blob(h)
+	if err != nil {
+		return false
+	}
+	rc.Close()
+	return true
+}
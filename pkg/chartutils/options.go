@@ -7,6 +7,7 @@ import (
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog/silent"
 
 	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 )
 
 // Auth defines the authentication settings
@@ -26,8 +27,14 @@ type Configuration struct {
 	MaxRetries         int
 	InsecureMode       bool
 	Auth               Auth
+	CredentialsFile    string
+	RegistriesFile     string
+	TLS                registries.TLS
 	ValuesFiles        []string
 	PreserveRepository bool
+	// IncludeNondistributable pulls the non-distributable (foreign) image layers too, instead of
+	// leaving them to be fetched from their own URLs
+	IncludeNondistributable bool
 }
 
 // WithInsecureMode configures Insecure transport
@@ -37,6 +44,13 @@ func WithInsecureMode(insecure bool) func(cfg *Configuration) {
 	}
 }
 
+// WithIncludeNondistributable configures whether to include non-distributable image layers
+func WithIncludeNondistributable(include bool) func(cfg *Configuration) {
+	return func(cfg *Configuration) {
+		cfg.IncludeNondistributable = include
+	}
+}
+
 // WithAuth configures the Auth
 func WithAuth(username, password string) func(cfg *Configuration) {
 	return func(cfg *Configuration) {
@@ -47,6 +61,27 @@ func WithAuth(username, password string) func(cfg *Configuration) {
 	}
 }
 
+// WithCredentialsFile configures the per-registry credentials file used when no Auth is provided
+func WithCredentialsFile(file string) func(cfg *Configuration) {
+	return func(cfg *Configuration) {
+		cfg.CredentialsFile = file
+	}
+}
+
+// WithRegistriesFile configures the registries file defining the mirrors to pull images from
+func WithRegistriesFile(file string) func(cfg *Configuration) {
+	return func(cfg *Configuration) {
+		cfg.RegistriesFile = file
+	}
+}
+
+// WithTLS configures the TLS certificate authorities and client certificate used to access the registries
+func WithTLS(caFile, certFile, keyFile string) func(cfg *Configuration) {
+	return func(cfg *Configuration) {
+		cfg.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
 // WithArtifactsDir configures the ArtifactsDir
 func WithArtifactsDir(dir string) func(cfg *Configuration) {
 	return func(cfg *Configuration) {
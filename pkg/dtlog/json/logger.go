@@ -0,0 +1,153 @@
+// Package json provides a logger implementation emitting one JSON event per line
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+)
+
+// EventType defines the type of an Event
+type EventType string
+
+const (
+	// LogEvent is a log message
+	LogEvent EventType = "log"
+	// SectionStartEvent is emitted when a section starts
+	SectionStartEvent EventType = "section_start"
+	// SectionEndEvent is emitted when a section ends, with its duration and status
+	SectionEndEvent EventType = "section_end"
+	// StepStartEvent is emitted when a step starts
+	StepStartEvent EventType = "step_start"
+	// StepEndEvent is emitted when a step ends, with its duration and status
+	StepEndEvent EventType = "step_end"
+	// ProgressEvent is emitted when a progress bar is started, updated or stopped
+	ProgressEvent EventType = "progress"
+)
+
+// Status defines the result of a section or step
+type Status string
+
+const (
+	// StatusSuccess indicates the section or step succeeded, or the message reports a success
+	StatusSuccess Status = "success"
+	// StatusError indicates the section or step failed
+	StatusError Status = "error"
+)
+
+// Progress defines the state of a progress bar
+type Progress struct {
+	Current int `json:"current"`
+	Total   int `json:"total"`
+}
+
+// Event defines the JSON object written for each log line
+type Event struct {
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+	// Section is the path of titles of the nested sections the event belongs to
+	Section []string `json:"section,omitempty"`
+	// Level is the level of log events
+	Level string `json:"level,omitempty"`
+	// Message is the message of log events
+	Message string `json:"message,omitempty"`
+	// Title is the title of the section, step or progress bar
+	Title  string `json:"title,omitempty"`
+	Status Status `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Duration is the duration, in seconds, of ended sections and steps
+	Duration *float64  `json:"duration,omitempty"`
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// output serializes the events written by a Logger and its nested loggers
+type output struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (o *output) write(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, _ = o.writer.Write(append(data, '\n'))
+}
+
+// NewLogger returns a new Logger writing JSON events
+func NewLogger() *Logger {
+	return &Logger{out: &output{writer: os.Stdout}, level: dtlog.InfoLevel}
+}
+
+// Logger defines a logger writing each message as a JSON event
+type Logger struct {
+	out     *output
+	level   dtlog.Level
+	section []string
+}
+
+func (l *Logger) emit(e Event) {
+	e.Section = l.section
+	l.out.write(e)
+}
+
+func (l *Logger) log(messageLevel dtlog.Level, levelName string, status Status, format string, args ...interface{}) {
+	if messageLevel > l.level {
+		return
+	}
+	l.emit(Event{Type: LogEvent, Level: levelName, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// SetWriter sets the internal writer used by the log
+func (l *Logger) SetWriter(w io.Writer) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.writer = w
+}
+
+// SetLevel sets the log level
+func (l *Logger) SetLevel(level dtlog.Level) {
+	l.level = level
+}
+
+// Failf logs a formatted error and returns it back
+func (l *Logger) Failf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	l.Errorf("%v", err)
+	return &dtlog.LoggedError{Err: err}
+}
+
+// Printf prints a message in the log
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.log(dtlog.AlwaysLevel, "info", "", format, args...)
+}
+
+// Errorf logs an error message
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(dtlog.ErrorLevel, "error", "", format, args...)
+}
+
+// Infof logs an information message
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(dtlog.InfoLevel, "info", "", format, args...)
+}
+
+// Debugf logs a debug message
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(dtlog.DebugLevel, "debug", "", format, args...)
+}
+
+// Warnf logs a warning message
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(dtlog.WarnLevel, "warn", "", format, args...)
+}
@@ -0,0 +1,112 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+)
+
+func readEvents(t *testing.T, buff *bytes.Buffer) []Event {
+	t.Helper()
+	events := make([]Event, 0)
+	scanner := bufio.NewScanner(buff)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e), "invalid JSON line %q", scanner.Text())
+		assert.False(t, e.Time.IsZero())
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestSectionLogger(t *testing.T) {
+	t.Run("Emits log events", func(t *testing.T) {
+		var buff bytes.Buffer
+		l := NewSectionLogger()
+		l.SetWriter(&buff)
+		l.Debugf("hidden")
+		l.Infof("hello %s", "world")
+		l.Successf("done")
+		err := l.Failf("failed: %v", fmt.Errorf("boom"))
+		var loggedErr *dtlog.LoggedError
+		assert.ErrorAs(t, err, &loggedErr)
+
+		l.SetLevel(dtlog.DebugLevel)
+		l.Debugf("shown")
+
+		events := readEvents(t, &buff)
+		require.Len(t, events, 4)
+		assert.Equal(t, Event{Time: events[0].Time, Type: LogEvent, Level: "info", Message: "hello world"}, events[0])
+		assert.Equal(t, Event{Time: events[1].Time, Type: LogEvent, Level: "info", Status: StatusSuccess, Message: "done"}, events[1])
+		assert.Equal(t, Event{Time: events[2].Time, Type: LogEvent, Level: "error", Message: "failed: boom"}, events[2])
+		assert.Equal(t, Event{Time: events[3].Time, Type: LogEvent, Level: "debug", Message: "shown"}, events[3])
+	})
+	t.Run("Emits section and step events", func(t *testing.T) {
+		var buff bytes.Buffer
+		l := NewSectionLogger()
+		l.SetWriter(&buff)
+		err := l.Section("Wrapping chart", func(sl dtlog.SectionLogger) error {
+			sl.Infof("inside")
+			_ = sl.ExecuteStep("Pulling images", func() error { return nil })
+			return sl.ExecuteStep("Pushing images", func() error { return fmt.Errorf("denied") })
+		})
+		require.ErrorContains(t, err, "denied")
+
+		events := readEvents(t, &buff)
+		require.Len(t, events, 7)
+		types := make([]EventType, 0)
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		assert.Equal(t, []EventType{
+			SectionStartEvent, LogEvent, StepStartEvent, StepEndEvent, StepStartEvent, StepEndEvent, SectionEndEvent,
+		}, types)
+
+		assert.Equal(t, "Wrapping chart", events[0].Title)
+		assert.Empty(t, events[0].Section)
+		assert.Equal(t, []string{"Wrapping chart"}, events[1].Section)
+
+		assert.Equal(t, StatusSuccess, events[3].Status)
+		assert.NotNil(t, events[3].Duration)
+		assert.Equal(t, StatusError, events[5].Status)
+		assert.Equal(t, "denied", events[5].Error)
+
+		assert.Equal(t, "Wrapping chart", events[6].Title)
+		assert.Equal(t, StatusError, events[6].Status)
+		assert.NotNil(t, events[6].Duration)
+	})
+	t.Run("Emits progress events", func(t *testing.T) {
+		var buff bytes.Buffer
+		l := NewSectionLogger()
+		l.SetWriter(&buff)
+		pb, err := l.StartSection("Pushing").ProgressBar().WithTotal(2).Start("Pushing images")
+		require.NoError(t, err)
+		pb.Add(1)
+		pb.UpdateTitle("Pushing image b")
+		pb.Add(5)
+		pb.Stop()
+
+		events := readEvents(t, &buff)
+		require.Len(t, events, 6)
+		assert.Equal(t, SectionStartEvent, events[0].Type)
+		expected := []struct {
+			title   string
+			current int
+		}{
+			{"Pushing images", 0}, {"Pushing images", 1}, {"Pushing image b", 1}, {"Pushing image b", 2}, {"Pushing image b", 2},
+		}
+		for i, exp := range expected {
+			e := events[i+1]
+			assert.Equal(t, ProgressEvent, e.Type)
+			assert.Equal(t, []string{"Pushing"}, e.Section)
+			assert.Equal(t, exp.title, e.Title)
+			assert.Equal(t, &Progress{Current: exp.current, Total: 2}, e.Progress)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package json
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+)
+
+// ProgressBar defines a progress bar emitting its updates as JSON events
+type ProgressBar struct {
+	logger  *Logger
+	mu      sync.Mutex
+	title   string
+	current int
+	total   int
+}
+
+// emit writes the progress event with the current state of the progress bar
+func (p *ProgressBar) emit() {
+	p.mu.Lock()
+	e := Event{Type: ProgressEvent, Title: p.title, Progress: &Progress{Current: p.current, Total: p.total}}
+	p.mu.Unlock()
+	p.logger.emit(e)
+}
+
+// Stop stops the progress bar, emitting its final state
+func (p *ProgressBar) Stop() {
+	p.emit()
+}
+
+// Start initiates the progress bar
+func (p *ProgressBar) Start(title ...interface{}) (dtlog.ProgressBar, error) {
+	if len(title) > 0 {
+		p.mu.Lock()
+		p.title = fmt.Sprint(title...)
+		p.mu.Unlock()
+	}
+	p.emit()
+	return p, nil
+}
+
+// WithTotal sets the progress bar total steps
+func (p *ProgressBar) WithTotal(total int) dtlog.ProgressBar {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+	return p
+}
+
+// Errorf shows an error message
+func (p *ProgressBar) Errorf(format string, args ...interface{}) {
+	p.logger.Errorf(format, args...)
+}
+
+// Infof shows an info message
+func (p *ProgressBar) Infof(format string, args ...interface{}) {
+	p.logger.Infof(format, args...)
+}
+
+// Successf displays a success message
+func (p *ProgressBar) Successf(format string, args ...interface{}) {
+	p.logger.log(dtlog.InfoLevel, "info", StatusSuccess, format, args...)
+}
+
+// Warnf displays a warning message
+func (p *ProgressBar) Warnf(format string, args ...interface{}) {
+	p.logger.Warnf(format, args...)
+}
+
+// UpdateTitle updates the progress bar title
+func (p *ProgressBar) UpdateTitle(title string) dtlog.ProgressBar {
+	p.mu.Lock()
+	p.title = title
+	p.mu.Unlock()
+	p.emit()
+	return p
+}
+
+// Add increments the progress bar the specified amount
+func (p *ProgressBar) Add(inc int) dtlog.ProgressBar {
+	p.mu.Lock()
+	p.current += inc
+	if p.total > 0 && p.current > p.total {
+		p.current = p.total
+	}
+	p.mu.Unlock()
+	p.emit()
+	return p
+}
@@ -0,0 +1,73 @@
+package json
+
+import (
+	"time"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/dtlog"
+)
+
+// NewSectionLogger returns a new SectionLogger writing JSON events
+func NewSectionLogger() *SectionLogger {
+	return &SectionLogger{Logger: NewLogger()}
+}
+
+// SectionLogger defines a SectionLogger writing JSON events, tagged with the titles of their sections
+type SectionLogger struct {
+	*Logger
+}
+
+// result returns the status, error message and duration of a section or step started at start
+func result(start time.Time, err error) (Status, string, *float64) {
+	duration := time.Since(start).Seconds()
+	if err != nil {
+		return StatusError, err.Error(), &duration
+	}
+	return StatusSuccess, "", &duration
+}
+
+// ProgressBar returns a new ProgressBar
+func (l *SectionLogger) ProgressBar() dtlog.ProgressBar {
+	return &ProgressBar{logger: l.Logger}
+}
+
+// Successf logs a new success message (more efusive than Infof)
+func (l *SectionLogger) Successf(format string, args ...interface{}) {
+	l.log(dtlog.InfoLevel, "info", StatusSuccess, format, args...)
+}
+
+// PrefixText returns the indented version of the provided text
+func (l *SectionLogger) PrefixText(txt string) string {
+	return txt
+}
+
+// ExecuteStep executes a function emitting its start and its result
+func (l *SectionLogger) ExecuteStep(title string, fn func() error) error {
+	l.emit(Event{Type: StepStartEvent, Title: title})
+	start := time.Now()
+	err := fn()
+	status, errMsg, duration := result(start, err)
+	l.emit(Event{Type: StepEndEvent, Title: title, Status: status, Error: errMsg, Duration: duration})
+	return err
+}
+
+// Section executes the provided function inside a new section, emitting its start and its result
+func (l *SectionLogger) Section(title string, fn func(dtlog.SectionLogger) error) error {
+	childLog := l.StartSection(title)
+	start := time.Now()
+	err := fn(childLog)
+	status, errMsg, duration := result(start, err)
+	l.emit(Event{Type: SectionEndEvent, Title: title, Status: status, Error: errMsg, Duration: duration})
+	return err
+}
+
+// StartSection starts a new log section, whose events include its title
+func (l *SectionLogger) StartSection(title string) dtlog.SectionLogger {
+	l.emit(Event{Type: SectionStartEvent, Title: title})
+	return l.nest(title)
+}
+
+func (l *SectionLogger) nest(title string) *SectionLogger {
+	section := make([]string, 0, len(l.section)+1)
+	section = append(append(section, l.section...), title)
+	return &SectionLogger{Logger: &Logger{out: l.out, level: l.level, section: section}}
+}
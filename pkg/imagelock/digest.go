@@ -4,33 +4,116 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/opencontainers/go-digest"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/auth"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 )
 
 // DigestInfo defines the digest information for an Architecture
 type DigestInfo struct {
 	Digest digest.Digest
-	Arch   string
+	// Arch is the image platform, in os/architecture[/variant] format
+	Arch string
+	// OSVersion is the operating system version required by the image, mostly used by Windows images
+	OSVersion string `yaml:"osVersion,omitempty"`
+	// OSFeatures lists the operating system features required by the image
+	OSFeatures []string `yaml:"osFeatures,omitempty"`
+	// Features lists the CPU features required by the image
+	Features []string `yaml:",omitempty"`
+}
+
+// defaultVariants defines the variant assumed for architectures recorded without one
+var defaultVariants = map[string]string{
+	"arm64": "v8",
+	"arm":   "v7",
+}
+
+// newDigestInfo returns the DigestInfo for the image with digest dgst built for platform
+func newDigestInfo(dgst digest.Digest, platform *v1.Platform) DigestInfo {
+	return DigestInfo{
+		Digest:     dgst,
+		Arch:       PlatformArch(platform),
+		OSVersion:  platform.OSVersion,
+		OSFeatures: platform.OSFeatures,
+		Features:   platform.Features,
+	}
+}
+
+// PlatformArch returns the os/architecture[/variant] string identifying platform in Images.lock files
+func PlatformArch(platform *v1.Platform) string {
+	arch := fmt.Sprintf("%s/%s", platform.OS, platform.Architecture)
+	if platform.Variant != "" {
+		arch = fmt.Sprintf("%s/%s", arch, platform.Variant)
+	}
+	return arch
+}
+
+// Platform returns the full platform the digest was built for
+func (d DigestInfo) Platform() v1.Platform {
+	p := v1.Platform{OSVersion: d.OSVersion, OSFeatures: d.OSFeatures, Features: d.Features}
+	parts := strings.SplitN(d.Arch, "/", 3)
+	p.OS = parts[0]
+	if len(parts) > 1 {
+		p.Architecture = parts[1]
+	}
+	if len(parts) > 2 {
+		p.Variant = parts[2]
+	}
+	return p
+}
+
+// PlatformSpec returns the platform spec identifying the digest, in os/architecture[/variant][:os.version]
+// format. The OS version tells apart Windows images built for the same architecture
+func (d DigestInfo) PlatformSpec() string {
+	if d.OSVersion == "" {
+		return d.Arch
+	}
+	return fmt.Sprintf("%s:%s", d.Arch, d.OSVersion)
+}
+
+// MatchesPlatform returns true if the digest satisfies the platform spec, in
+// os/architecture[/variant][:os.version] format. Fields missing in the spec are not compared,
+// so linux/arm64 matches linux/arm64/v8. Digests recorded without variant, as in older Images.lock
+// files, get the default variant of their architecture
+func (d DigestInfo) MatchesPlatform(spec string) bool {
+	if d.Arch == spec {
+		return true
+	}
+	want, err := v1.ParsePlatform(spec)
+	if err != nil {
+		return false
+	}
+	p := d.Platform()
+	if p.Variant == "" {
+		p.Variant = defaultVariants[p.Architecture]
+	}
+	return p.Satisfies(*want)
 }
 
 func fetchImageDigests(r string, cfg *Config) ([]DigestInfo, error) {
-	opts := make([]crane.Option, 0)
-	if cfg.InsecureMode {
-		opts = append(opts, crane.Insecure)
+	regs, err := registries.Load(cfg.RegistriesFile)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := regs.CraneOptions(cfg.TLS, cfg.InsecureMode)
+	if err != nil {
+		return nil, err
 	}
 	opts = append(opts, crane.WithContext(cfg.Context))
-	if cfg.Auth.Username != "" && cfg.Auth.Password != "" {
-		opts = append(opts, crane.WithAuth(&authn.Basic{Username: cfg.Auth.Username, Password: cfg.Auth.Password}))
+	authOpt, err := auth.CraneOption(cfg.Auth.Username, cfg.Auth.Password, cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
 	}
+	opts = append(opts, authOpt)
 
-	desc, err := GetImageRemoteDescriptor(r, opts...)
+	desc, err := regs.Get(r, crane.GetOptions(opts...))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get descriptor: %v", err)
 	}
@@ -92,11 +175,7 @@ func readDigestsInfoFromIndex(idx v1.IndexManifest) ([]DigestInfo, error) {
 				allErrors = errors.Join(allErrors, fmt.Errorf("image does not define a platform"))
 				continue
 			}
-			imgDigest := DigestInfo{
-				Digest: digest.Digest(img.Digest.String()),
-				Arch:   fmt.Sprintf("%s/%s", platform.OS, platform.Architecture),
-			}
-			digests = append(digests, imgDigest)
+			digests = append(digests, newDigestInfo(digest.Digest(img.Digest.String()), platform))
 		default:
 			allErrors = errors.Join(allErrors, fmt.Errorf("unknown media type %q", img.MediaType))
 			continue
@@ -121,8 +200,5 @@ func readDigestInfoFromImage(img v1.Image) (DigestInfo, error) {
 		return DigestInfo{}, fmt.Errorf("failed to get image digest: %w", err)
 	}
 
-	return DigestInfo{
-		Arch:   fmt.Sprintf("%s/%s", platform.OS, platform.Architecture),
-		Digest: digest.Digest(digestData.String()),
-	}, nil
+	return newDigestInfo(digest.Digest(digestData.String()), platform), nil
 }
@@ -0,0 +1,123 @@
+package imagelock
+
+import (
+	"bytes"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDigestsInfoFromIndex(t *testing.T) {
+	newHash := func(hex string) v1.Hash {
+		return v1.Hash{Algorithm: "sha256", Hex: hex}
+	}
+	idx := v1.IndexManifest{
+		Manifests: []v1.Descriptor{
+			{
+				MediaType: types.OCIManifestSchema1,
+				Digest:    newHash("0000000000000000000000000000000000000000000000000000000000000001"),
+				Platform:  &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"},
+			},
+			{
+				MediaType: types.OCIManifestSchema1,
+				Digest:    newHash("0000000000000000000000000000000000000000000000000000000000000002"),
+				Platform:  &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+			},
+			{
+				MediaType: types.DockerManifestSchema2,
+				Digest:    newHash("0000000000000000000000000000000000000000000000000000000000000003"),
+				Platform: &v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.5329",
+					OSFeatures: []string{"win32k"}},
+			},
+		},
+	}
+	digests, err := readDigestsInfoFromIndex(idx)
+	require.NoError(t, err)
+	require.Len(t, digests, 3)
+
+	assert.Equal(t, "linux/arm/v6", digests[0].Arch)
+	assert.Equal(t, "linux/arm/v7", digests[1].Arch)
+	assert.Equal(t, "windows/amd64", digests[2].Arch)
+	assert.Equal(t, "10.0.17763.5329", digests[2].OSVersion)
+	assert.Equal(t, []string{"win32k"}, digests[2].OSFeatures)
+
+	img := &ChartImage{Digests: digests}
+	dgst, err := img.GetDigestForArch("linux/arm/v7")
+	require.NoError(t, err)
+	assert.Equal(t, digests[1].Digest, dgst.Digest)
+}
+
+func TestDigestInfo_MatchesPlatform(t *testing.T) {
+	tests := []struct {
+		arch      string
+		osVersion string
+		spec      string
+		want      bool
+	}{
+		{arch: "linux/amd64", spec: "linux/amd64", want: true},
+		{arch: "linux/amd64", spec: "linux/arm64", want: false},
+		{arch: "linux/arm/v7", spec: "linux/arm/v7", want: true},
+		{arch: "linux/arm/v6", spec: "linux/arm/v7", want: false},
+		{arch: "linux/arm/v6", spec: "linux/arm", want: true},
+		{arch: "linux/arm64/v8", spec: "linux/arm64", want: true},
+		// Images.lock files created before variants were recorded
+		{arch: "linux/arm64", spec: "linux/arm64/v8", want: true},
+		{arch: "linux/arm", spec: "linux/arm/v6", want: false},
+		{arch: "windows/amd64", osVersion: "10.0.17763.5329", spec: "windows/amd64:10.0.17763.5329", want: true},
+		{arch: "windows/amd64", osVersion: "10.0.20348.2227", spec: "windows/amd64:10.0.17763.5329", want: false},
+	}
+	for _, tc := range tests {
+		d := DigestInfo{Arch: tc.arch, OSVersion: tc.osVersion}
+		assert.Equal(t, tc.want, d.MatchesPlatform(tc.spec), "%s (%s) matching %s", tc.arch, tc.osVersion, tc.spec)
+	}
+}
+
+func TestDigestInfo_YAML(t *testing.T) {
+	t.Run("Omits the missing platform details", func(t *testing.T) {
+		il := NewImagesLock()
+		il.Images = ImageList{{Name: "app", Image: "registry.io/app:1.0", Digests: []DigestInfo{
+			{Arch: "linux/amd64", Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000001"},
+		}}}
+		buf := &bytes.Buffer{}
+		require.NoError(t, il.ToYAML(buf))
+		assert.NotContains(t, buf.String(), "osVersion")
+		assert.NotContains(t, buf.String(), "features")
+	})
+	t.Run("Round trips the platform details", func(t *testing.T) {
+		il := NewImagesLock()
+		il.Images = ImageList{{Name: "app", Image: "registry.io/app:1.0", Digests: []DigestInfo{
+			{Arch: "windows/amd64", OSVersion: "10.0.17763.5329", OSFeatures: []string{"win32k"},
+				Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000001"},
+			{Arch: "linux/arm64/v8", Features: []string{"sve"},
+				Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000002"},
+		}}}
+		buf := &bytes.Buffer{}
+		require.NoError(t, il.ToYAML(buf))
+		newLock, err := FromYAML(buf)
+		require.NoError(t, err)
+		assert.Equal(t, il.Images, newLock.Images)
+	})
+}
+
+func TestDigestInfo_PlatformSpec(t *testing.T) {
+	assert.Equal(t, "linux/arm64/v8", DigestInfo{Arch: "linux/arm64/v8"}.PlatformSpec())
+	assert.Equal(t, "windows/amd64:10.0.17763.5329", DigestInfo{Arch: "windows/amd64", OSVersion: "10.0.17763.5329"}.PlatformSpec())
+
+	t.Run("Tells apart Windows versions when comparing digests", func(t *testing.T) {
+		img := &ChartImage{Image: "registry.io/app:1.0", Digests: []DigestInfo{
+			{Arch: "windows/amd64", OSVersion: "10.0.17763.5329",
+				Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000001"},
+			{Arch: "windows/amd64", OSVersion: "10.0.20348.2227",
+				Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000002"},
+		}}
+		other := *img
+		assert.NoError(t, img.Diff(&other))
+
+		other.Digests = []DigestInfo{img.Digests[1], img.Digests[0]}
+		other.Digests[0].Digest = "sha256:0000000000000000000000000000000000000000000000000000000000000003"
+		assert.ErrorContains(t, img.Diff(&other), "digests do not match")
+	})
+}
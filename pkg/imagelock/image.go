@@ -64,7 +64,7 @@ func (i *ChartImage) Diff(other *ChartImage) error {
 		return fmt.Errorf("images do not match")
 	}
 	for _, digest := range other.Digests {
-		existingDigest, err := i.GetDigestForArch(digest.Arch)
+		existingDigest, err := i.GetDigestForArch(digest.PlatformSpec())
 		if err != nil {
 			allErrors = errors.Join(allErrors, fmt.Errorf("chart %q: image %q: %v", other.Chart, other.Image, err))
 			continue
@@ -80,7 +80,8 @@ func (i *ChartImage) Diff(other *ChartImage) error {
 }
 
 // GetDigestForArch returns the image digest for the specified architecture.
-// It searches through the image's digests and returns the first digest that matches the given architecture.
+// It searches through the image's digests and returns the digest with that exact architecture or,
+// if there is none, the first one matching it as a platform spec (see DigestInfo.MatchesPlatform).
 // If no matching digest is found, it returns an error.
 func (i *ChartImage) GetDigestForArch(arch string) (*DigestInfo, error) {
 	for _, digest := range i.Digests {
@@ -88,6 +89,11 @@ func (i *ChartImage) GetDigestForArch(arch string) (*DigestInfo, error) {
 			return &digest, nil
 		}
 	}
+	for _, digest := range i.Digests {
+		if digest.MatchesPlatform(arch) {
+			return &digest, nil
+		}
+	}
 	return nil, fmt.Errorf("failed to find digest for arch %q", arch)
 }
 
@@ -164,7 +170,7 @@ func filterDigestsByPlatforms(digests []DigestInfo, platforms []string) []Digest
 
 	filteredDigests := make([]DigestInfo, 0)
 	for _, d := range digests {
-		if slices.Contains(platforms, d.Arch) {
+		if slices.ContainsFunc(platforms, d.MatchesPlatform) {
 			filteredDigests = append(filteredDigests, d)
 		}
 	}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -82,6 +83,31 @@ func (il *ImagesLock) Validate(expectedImages ImageList) error {
 	return allErrors
 }
 
+// FilterPlatforms keeps only the image digests matching the provided platforms. It fails if any
+// of the platforms is not available in the ImagesLock, or if an image is not available for any of them.
+// ImagesLocks without images, such as those of charts not using any, have nothing to filter
+func (il *ImagesLock) FilterPlatforms(platforms []string) error {
+	if len(platforms) == 0 || len(il.Images) == 0 {
+		return nil
+	}
+	var allErrors error
+	for _, platform := range platforms {
+		found := slices.ContainsFunc(il.Images, func(img *ChartImage) bool {
+			return len(filterDigestsByPlatforms(img.Digests, []string{platform})) > 0
+		})
+		if !found {
+			allErrors = errors.Join(allErrors, fmt.Errorf("platform %q is not available", platform))
+		}
+	}
+	for _, img := range il.Images {
+		img.Digests = filterDigestsByPlatforms(img.Digests, platforms)
+		if len(img.Digests) == 0 {
+			allErrors = errors.Join(allErrors, fmt.Errorf("image %q is not available for platforms %s", img.Image, strings.Join(platforms, ", ")))
+		}
+	}
+	return allErrors
+}
+
 // ToYAML writes the serialized YAML representation of the ImagesLock to w
 func (il *ImagesLock) ToYAML(w io.Writer) error {
 	enc := yaml.NewEncoder(w)
@@ -565,3 +565,48 @@ images:
 		})
 	})
 }
+
+func (suite *ImageLockTestSuite) TestFilterPlatforms() {
+	t := suite.T()
+	newLock := func() *ImagesLock {
+		il := NewImagesLock()
+		il.Images = ImageList{
+			{Name: "app", Image: "registry.io/app:1.0", Digests: []DigestInfo{
+				{Arch: "linux/amd64", Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000001"},
+				{Arch: "linux/arm64", Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000002"},
+			}},
+			{Name: "tool", Image: "registry.io/tool:1.0", Digests: []DigestInfo{
+				{Arch: "linux/amd64", Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000003"},
+			}},
+		}
+		return il
+	}
+
+	t.Run("Keeps only the requested platforms", func(t *testing.T) {
+		il := newLock()
+		require.NoError(t, il.FilterPlatforms([]string{"linux/amd64"}))
+		for _, img := range il.Images {
+			require.Len(t, img.Digests, 1)
+			assert.Equal(t, "linux/amd64", img.Digests[0].Arch)
+		}
+	})
+	t.Run("Keeps everything when no platforms are requested", func(t *testing.T) {
+		il := newLock()
+		require.NoError(t, il.FilterPlatforms(nil))
+		assert.Len(t, il.Images[0].Digests, 2)
+	})
+	t.Run("Matches platforms with variants", func(t *testing.T) {
+		il := newLock()
+		require.NoError(t, il.FilterPlatforms([]string{"linux/arm64/v8", "linux/amd64"}))
+		assert.Len(t, il.Images[0].Digests, 2)
+	})
+	t.Run("Accepts any platform for locks without images", func(t *testing.T) {
+		require.NoError(t, NewImagesLock().FilterPlatforms([]string{"linux/s390x"}))
+	})
+	t.Run("Fails when a platform is not available", func(t *testing.T) {
+		require.ErrorContains(t, newLock().FilterPlatforms([]string{"linux/amd64", "linux/s390x"}), `platform "linux/s390x" is not available`)
+	})
+	t.Run("Fails when an image is left without platforms", func(t *testing.T) {
+		require.ErrorContains(t, newLock().FilterPlatforms([]string{"linux/arm64"}), `image "registry.io/tool:1.0" is not available for platforms linux/arm64`)
+	})
+}
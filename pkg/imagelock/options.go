@@ -2,6 +2,8 @@ package imagelock
 
 import (
 	"context"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/registries"
 )
 
 // Auth defines the authentication information to access the container registry
@@ -16,6 +18,9 @@ type Config struct {
 	AnnotationsKey            string
 	Context                   context.Context
 	Auth                      Auth
+	CredentialsFile           string
+	RegistriesFile            string
+	TLS                       registries.TLS
 	Platforms                 []string
 	SkipImageDigestResolution bool
 	PreserveRepository        bool
@@ -52,6 +57,27 @@ func WithAuth(username, password string) func(ic *Config) {
 	}
 }
 
+// WithCredentialsFile configures the per-registry credentials file used when no Auth is provided
+func WithCredentialsFile(file string) func(ic *Config) {
+	return func(ic *Config) {
+		ic.CredentialsFile = file
+	}
+}
+
+// WithRegistriesFile configures the registries file defining the mirrors to resolve images from
+func WithRegistriesFile(file string) func(ic *Config) {
+	return func(ic *Config) {
+		ic.RegistriesFile = file
+	}
+}
+
+// WithTLS configures the TLS certificate authorities and client certificate used to access the registries
+func WithTLS(caFile, certFile, keyFile string) func(ic *Config) {
+	return func(ic *Config) {
+		ic.TLS = registries.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	}
+}
+
 // WithPlatforms configures the Platforms of the Config
 func WithPlatforms(platforms []string) func(ic *Config) {
 	return func(ic *Config) {
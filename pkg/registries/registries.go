@@ -0,0 +1,170 @@
+// Package registries implements the registries configuration, defining the mirrors to pull images from
+// and the settings used to access each registry
+package registries
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMirrorKey is the Mirrors key applying to the registries without mirrors of their own
+const DefaultMirrorKey = "*"
+
+// Mirror defines the mirrors of a registry
+type Mirror struct {
+	// Endpoints lists the mirrors, tried in order before the registry itself. Each is a registry host,
+	// optionally followed by a repository prefix, such as cache.example.com/dockerhub. Mirrors served
+	// over plain HTTP must be prefixed by http://
+	Endpoints []string `yaml:"endpoints"`
+}
+
+// Config defines the registries configuration
+type Config struct {
+	// Mirrors maps registry hosts, such as docker.io, to their mirrors
+	Mirrors map[string]Mirror `yaml:"mirrors"`
+	// Registries maps registry hosts to their specific settings
+	Registries map[string]Registry `yaml:"registries"`
+}
+
+// Load reads the registries configuration file filename. It returns a nil Config, which pulls
+// everything from its own registry with the default settings, if filename is empty
+func Load(filename string) (*Config, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registries configuration: %w", err)
+	}
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse registries configuration %q: %w", filename, err)
+	}
+	for registry, mirror := range c.Mirrors {
+		if registry != DefaultMirrorKey {
+			if _, err := name.NewRegistry(registry); err != nil {
+				return nil, fmt.Errorf("registries configuration %q: invalid registry %q: %w", filename, registry, err)
+			}
+		}
+		for _, endpoint := range mirror.Endpoints {
+			host, _, _ := parseEndpoint(endpoint)
+			if _, err := name.NewRegistry(host); err != nil {
+				return nil, fmt.Errorf("registries configuration %q: invalid mirror %q: %w", filename, endpoint, err)
+			}
+		}
+	}
+	for registry, settings := range c.Registries {
+		if _, err := name.NewRegistry(registry); err != nil {
+			return nil, fmt.Errorf("registries configuration %q: invalid registry %q: %w", filename, registry, err)
+		}
+		if err := settings.validate(); err != nil {
+			return nil, fmt.Errorf("registries configuration %q: registry %q: %w", filename, registry, err)
+		}
+	}
+	return c, nil
+}
+
+// parseEndpoint splits endpoint into its registry host and repository prefix, and whether it uses plain HTTP
+func parseEndpoint(endpoint string) (host string, prefix string, plainHTTP bool) {
+	if strings.HasPrefix(endpoint, "http://") {
+		plainHTTP = true
+	}
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+	host, prefix, _ = strings.Cut(strings.TrimSuffix(endpoint, "/"), "/")
+	return host, prefix, plainHTTP
+}
+
+// mirrors returns the mirror endpoints of registry
+func (c *Config) mirrors(registry string) []string {
+	if c == nil {
+		return nil
+	}
+	for key, mirror := range c.Mirrors {
+		if key == DefaultMirrorKey {
+			continue
+		}
+		if reg, err := name.NewRegistry(key); err == nil && reg.RegistryStr() == registry {
+			return mirror.Endpoints
+		}
+	}
+	return c.Mirrors[DefaultMirrorKey].Endpoints
+}
+
+// Repositories returns the repositories to try, in order, to pull from repo: its mirrors followed by repo itself
+func (c *Config) Repositories(repo name.Repository) ([]name.Repository, error) {
+	repos := make([]name.Repository, 0)
+	for _, endpoint := range c.mirrors(repo.RegistryStr()) {
+		host, prefix, plainHTTP := parseEndpoint(endpoint)
+		opts := []name.Option{name.StrictValidation}
+		if plainHTTP {
+			opts = append(opts, name.Insecure)
+		}
+		mirrorRepo, err := name.NewRepository(path.Join(host, prefix, repo.RepositoryStr()), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mirror %q in %q: %w", repo, endpoint, err)
+		}
+		repos = append(repos, mirrorRepo)
+	}
+	return append(repos, repo), nil
+}
+
+// References returns the references to try, in order, to pull ref: its mirrors followed by ref itself
+func (c *Config) References(ref name.Reference) ([]name.Reference, error) {
+	repos, err := c.Repositories(ref.Context())
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]name.Reference, 0, len(repos))
+	for _, repo := range repos[:len(repos)-1] {
+		switch ref.(type) {
+		case name.Digest:
+			refs = append(refs, repo.Digest(ref.Identifier()))
+		default:
+			refs = append(refs, repo.Tag(ref.Identifier()))
+		}
+	}
+	return append(refs, ref), nil
+}
+
+// Try calls fn with the references of image, as returned by References, until one succeeds
+func (c *Config) Try(image string, o crane.Options, fn func(ref name.Reference) error) error {
+	ref, err := name.ParseReference(image, o.Name...)
+	if err != nil {
+		return fmt.Errorf("failed to parse reference %q: %w", image, err)
+	}
+	refs, err := c.References(ref)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 1 {
+		return fn(ref)
+	}
+	var allErrors error
+	for _, r := range refs {
+		err := fn(r)
+		if err == nil {
+			return nil
+		}
+		allErrors = errors.Join(allErrors, fmt.Errorf("%s: %w", r, err))
+	}
+	return allErrors
+}
+
+// Get returns the remote descriptor of image, fetched from the first of its mirrors, or its own registry, serving it
+func (c *Config) Get(image string, o crane.Options) (*remote.Descriptor, error) {
+	var desc *remote.Descriptor
+	err := c.Try(image, o, func(ref name.Reference) error {
+		var err error
+		desc, err = remote.Get(ref, o.Remote...)
+		return err
+	})
+	return desc, err
+}
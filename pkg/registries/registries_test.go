@@ -0,0 +1,176 @@
+package registries
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRegistriesFile(t *testing.T, data string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "registries.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(data), 0644))
+	return filename
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Loads the mirrors", func(t *testing.T) {
+		c, err := Load(writeRegistriesFile(t, `
+mirrors:
+  docker.io:
+    endpoints:
+      - cache.example.com/dockerhub
+      - http://localcache:5000
+  "*":
+    endpoints:
+      - cache.example.com
+`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]Mirror{
+			"docker.io": {Endpoints: []string{"cache.example.com/dockerhub", "http://localcache:5000"}},
+			"*":         {Endpoints: []string{"cache.example.com"}},
+		}, c.Mirrors)
+	})
+	t.Run("Returns no configuration without file", func(t *testing.T) {
+		c, err := Load("")
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorContains(t, err, "failed to read registries configuration")
+
+		_, err = Load(writeRegistriesFile(t, "mirrors: [\n"))
+		assert.ErrorContains(t, err, "failed to parse registries configuration")
+
+		_, err = Load(writeRegistriesFile(t, "mirrors:\n  \"docker.io/library\":\n    endpoints: [cache.example.com]\n"))
+		assert.ErrorContains(t, err, "invalid registry")
+
+		_, err = Load(writeRegistriesFile(t, "mirrors:\n  docker.io:\n    endpoints: [\"cache example\"]\n"))
+		assert.ErrorContains(t, err, "invalid mirror")
+
+		_, err = Load(writeRegistriesFile(t, "registries:\n  registry.example.com:\n    proxy: http://proxy:3128\n    noProxy: true\n"))
+		assert.ErrorContains(t, err, "proxy and noProxy cannot be used together")
+
+		_, err = Load(writeRegistriesFile(t, "registries:\n  registry.example.com:\n    proxy: proxy\n"))
+		assert.ErrorContains(t, err, "invalid proxy URL")
+	})
+	t.Run("Loads the registries settings", func(t *testing.T) {
+		c, err := Load(writeRegistriesFile(t, `
+registries:
+  registry.example.com:
+    plainHTTP: true
+    proxy: http://proxy.example.com:3128
+  internal.example.com:
+    insecure: true
+    noProxy: true
+`))
+		require.NoError(t, err)
+		assert.Equal(t, Registry{PlainHTTP: true, Proxy: "http://proxy.example.com:3128"}, c.Registry("registry.example.com"))
+		assert.Equal(t, Registry{Insecure: true, NoProxy: true}, c.Registry("internal.example.com"))
+		assert.Equal(t, Registry{}, c.Registry("other.example.com"))
+	})
+}
+
+func TestConfig_References(t *testing.T) {
+	c := &Config{Mirrors: map[string]Mirror{
+		"docker.io": {Endpoints: []string{"cache.example.com/dockerhub", "http://localcache:5000"}},
+		"*":         {Endpoints: []string{"https://cache.example.com/"}},
+	}}
+	tests := []struct {
+		name  string
+		c     *Config
+		image string
+		want  []string
+	}{
+		{
+			name:  "Rewrites tags",
+			c:     c,
+			image: "nginx:1.25",
+			want: []string{
+				"cache.example.com/dockerhub/library/nginx:1.25",
+				"localcache:5000/library/nginx:1.25",
+				"index.docker.io/library/nginx:1.25",
+			},
+		},
+		{
+			name:  "Rewrites digests",
+			c:     c,
+			image: "quay.io/org/app@sha256:1e5991a54bc98871e61dd7f94697f86b5dc4e2b2560d5590ff292038a6434ba7",
+			want: []string{
+				"cache.example.com/org/app@sha256:1e5991a54bc98871e61dd7f94697f86b5dc4e2b2560d5590ff292038a6434ba7",
+				"quay.io/org/app@sha256:1e5991a54bc98871e61dd7f94697f86b5dc4e2b2560d5590ff292038a6434ba7",
+			},
+		},
+		{
+			name:  "Does not rewrite without configuration",
+			image: "quay.io/org/app:1",
+			want:  []string{"quay.io/org/app:1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := name.ParseReference(tc.image)
+			require.NoError(t, err)
+			refs, err := tc.c.References(ref)
+			require.NoError(t, err)
+			got := make([]string, 0, len(refs))
+			for _, r := range refs {
+				got = append(got, r.Name())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	t.Run("Uses plain HTTP for http:// mirrors", func(t *testing.T) {
+		refs, err := c.References(name.MustParseReference("nginx:1.25"))
+		require.NoError(t, err)
+		assert.Equal(t, "https", refs[0].Context().Scheme())
+		assert.Equal(t, "http", refs[1].Context().Scheme())
+	})
+}
+
+func TestConfig_Get(t *testing.T) {
+	silentLog := log.New(io.Discard, "", 0)
+	s := httptest.NewServer(registry.New(registry.Logger(silentLog)))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, u.Host+"/cache/library/app:1"))
+	dgst, err := img.Digest()
+	require.NoError(t, err)
+
+	// Nothing listens on the upstream registry, so images can only be pulled from the mirror
+	upstream := "127.0.0.1:1"
+	c := &Config{Mirrors: map[string]Mirror{upstream: {Endpoints: []string{u.Host + "/cache"}}}}
+	o := crane.GetOptions()
+
+	t.Run("Pulls from the mirror", func(t *testing.T) {
+		desc, err := c.Get(upstream+"/library/app:1", o)
+		require.NoError(t, err)
+		assert.Equal(t, dgst, desc.Digest)
+
+		desc, err = c.Get(upstream+"/library/app@"+dgst.String(), o)
+		require.NoError(t, err)
+		assert.Equal(t, dgst, desc.Digest)
+	})
+	t.Run("Reports the errors of all the registries", func(t *testing.T) {
+		_, err := c.Get(upstream+"/library/app:2", o)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, u.Host+"/cache/library/app:2")
+		assert.ErrorContains(t, err, upstream+"/library/app:2")
+	})
+}
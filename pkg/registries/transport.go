@@ -0,0 +1,397 @@
+package registries
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
+)
+
+// TLS defines the TLS settings used to access registries
+type TLS struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system ones
+	CAFile string `yaml:"caFile,omitempty"`
+	// CertFile and KeyFile are the PEM client certificate and key presented for mutual TLS
+	CertFile string `yaml:"certFile,omitempty"`
+	KeyFile  string `yaml:"keyFile,omitempty"`
+}
+
+// Registry defines the settings used to access a registry
+type Registry struct {
+	TLS `yaml:",inline"`
+	// PlainHTTP accesses the registry over plain HTTP instead of HTTPS
+	PlainHTTP bool `yaml:"plainHTTP,omitempty"`
+	// Insecure skips the verification of the registry certificate
+	Insecure bool `yaml:"insecure,omitempty"`
+	// Proxy is the URL of the proxy used to access the registry, instead of the one defined by the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	Proxy string `yaml:"proxy,omitempty"`
+	// NoProxy accesses the registry directly, ignoring the proxy environment variables
+	NoProxy bool `yaml:"noProxy,omitempty"`
+}
+
+func (r Registry) validate() error {
+	if err := r.TLS.validate(); err != nil {
+		return err
+	}
+	if r.Proxy != "" {
+		if r.NoProxy {
+			return fmt.Errorf("proxy and noProxy cannot be used together")
+		}
+		u, err := url.Parse(r.Proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", r.Proxy)
+		}
+	}
+	return nil
+}
+
+// merge returns t with the settings defined in other taking precedence
+func (t TLS) merge(other TLS) TLS {
+	if other.CAFile != "" {
+		t.CAFile = other.CAFile
+	}
+	if other.CertFile != "" || other.KeyFile != "" {
+		t.CertFile, t.KeyFile = other.CertFile, other.KeyFile
+	}
+	return t
+}
+
+func (t TLS) validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("the client certificate and key must be provided together")
+	}
+	return nil
+}
+
+// appendCAFile adds the certificate authorities in the PEM bundle caFile to pool
+func appendCAFile(pool *x509.CertPool, caFile string) error {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificates found in CA file %q", caFile)
+	}
+	return nil
+}
+
+// Config returns the tls.Config verifying the server certificates with the CAFile certificate
+// authorities, unless insecure, and presenting the client certificate
+func (t TLS) Config(insecure bool) (*tls.Config, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		InsecureSkipVerify: insecure, // #nosec G402
+	}
+	if t.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if err := appendCAFile(pool, t.CAFile); err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// registries returns the per-registry settings, keyed by normalized registry host
+func (c *Config) registries() map[string]Registry {
+	result := make(map[string]Registry)
+	if c == nil {
+		return result
+	}
+	for key, reg := range c.Registries {
+		// Keys are validated when loading the configuration
+		if r, err := name.NewRegistry(key); err == nil {
+			result[r.RegistryStr()] = reg
+		}
+	}
+	return result
+}
+
+// Registry returns the settings of the registry host, if any
+func (c *Config) Registry(host string) Registry {
+	if r, err := name.NewRegistry(host); err == nil {
+		return c.registries()[r.RegistryStr()]
+	}
+	return Registry{}
+}
+
+// TLSFor returns the TLS settings used to access the registry host: t overridden by its per-registry settings
+func (c *Config) TLSFor(host string, t TLS) TLS {
+	return t.merge(c.Registry(host).TLS)
+}
+
+// maxRedirects is the number of redirections followed for a registry before leaving them to the client
+const maxRedirects = 10
+
+// realmKey identifies the token realm of a registry by the realm host and the registry service
+type realmKey struct {
+	host    string
+	service string
+}
+
+// hostTransport dispatches the requests to the transport of their host, if any, or to the default one.
+// Registries with their own certificate authorities have them trusted too when sending the client to
+// other hosts, such as their token realms or the storage their downloads are redirected to. No other
+// per-registry setting applies to those hosts, as they are usually shared with other registries
+type hostTransport struct {
+	defaultTransport http.RoundTripper
+	hosts            map[string]http.RoundTripper
+	// caTransports are the default transport trusting the certificate authorities of each registry
+	caTransports map[string]http.RoundTripper
+
+	mu sync.RWMutex
+	// realms maps the token realms found in the registries challenges to the registry sending them
+	realms map[realmKey]string
+}
+
+// challengeParams returns the parameters of the WWW-Authenticate challenge
+func challengeParams(challenge string) map[string]string {
+	params := make(map[string]string)
+	_, list, _ := strings.Cut(challenge, " ")
+	for _, param := range strings.Split(list, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if found {
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return params
+}
+
+// recordRealms records the token realms in the challenges of the registry response
+func (t *hostTransport) recordRealms(req *http.Request, resp *http.Response) {
+	for _, challenge := range resp.Header.Values("WWW-Authenticate") {
+		params := challengeParams(challenge)
+		realm, err := req.URL.Parse(params["realm"])
+		if params["realm"] == "" || err != nil || realm.Host == req.URL.Host {
+			continue
+		}
+		t.mu.Lock()
+		if t.realms == nil {
+			t.realms = make(map[realmKey]string)
+		}
+		t.realms[realmKey{host: realm.Host, service: params["service"]}] = req.URL.Host
+		t.mu.Unlock()
+	}
+}
+
+// requestService returns the service a token request is for, sent either in its query or in its form
+func requestService(req *http.Request) string {
+	if service := req.URL.Query().Get("service"); service != "" {
+		return service
+	}
+	if req.Method != http.MethodPost || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return ""
+	}
+	return form.Get("service")
+}
+
+// realmTransport returns the transport of the token realm requested by req, or the default one
+func (t *hostTransport) realmTransport(req *http.Request) http.RoundTripper {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.realms) == 0 {
+		return t.defaultTransport
+	}
+	if registry, ok := t.realms[realmKey{host: req.URL.Host, service: requestService(req)}]; ok {
+		return t.caTransports[registry]
+	}
+	return t.defaultTransport
+}
+
+// followRedirects follows the redirections of the registry response with rt, as long as they do not
+// lead back to a configured registry
+func (t *hostTransport) followRedirects(req *http.Request, resp *http.Response, rt http.RoundTripper) (*http.Response, error) {
+	for i := 0; i < maxRedirects; i++ {
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+			http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return resp, nil
+		}
+		target, err := req.URL.Parse(resp.Header.Get("Location"))
+		if err != nil || resp.Header.Get("Location") == "" {
+			return resp, nil
+		}
+		if _, ok := t.hosts[target.Host]; ok {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		next := req.Clone(req.Context())
+		next.URL = target
+		next.Host = ""
+		if target.Host != req.URL.Host {
+			next.Header.Del("Authorization")
+		}
+		if resp, err = rt.RoundTrip(next); err != nil {
+			return nil, err
+		}
+		req = next
+	}
+	return resp, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt, ok := t.hosts[req.URL.Host]
+	if !ok {
+		return t.realmTransport(req).RoundTrip(req)
+	}
+	resp, err := rt.RoundTrip(req)
+	caTransport, trusted := t.caTransports[req.URL.Host]
+	if err != nil || !trusted {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		t.recordRealms(req, resp)
+	}
+	// Only the requests without body can be safely replayed
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.followRedirects(req, resp, caTransport)
+	}
+	return resp, nil
+}
+
+// plainHTTPTransport sends the HTTPS requests over plain HTTP
+type plainHTTPTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *plainHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "https" {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = "http"
+	}
+	return t.transport.RoundTrip(req)
+}
+
+func newTransport(t TLS, insecure bool) (*http.Transport, error) {
+	tlsConfig, err := t.Config(insecure)
+	if err != nil {
+		return nil, err
+	}
+	tr := remote.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsConfig
+	return tr, nil
+}
+
+// newCATransport returns the default transport also trusting the certificate authorities in caFile
+func newCATransport(t TLS, insecure bool, caFile string) (*http.Transport, error) {
+	tr, err := newTransport(t, insecure)
+	if err != nil {
+		return nil, err
+	}
+	pool := tr.TLSClientConfig.RootCAs
+	if pool == nil {
+		if pool, err = x509.SystemCertPool(); err != nil {
+			pool = x509.NewCertPool()
+		}
+	}
+	if err := appendCAFile(pool, caFile); err != nil {
+		return nil, err
+	}
+	tr.TLSClientConfig.RootCAs = pool
+	return tr, nil
+}
+
+// newRegistryTransport returns the transport used to access the registry reg
+func newRegistryTransport(t TLS, insecure bool, reg Registry) (http.RoundTripper, error) {
+	tr, err := newTransport(t.merge(reg.TLS), insecure || reg.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case reg.NoProxy:
+		tr.Proxy = nil
+	case reg.Proxy != "":
+		// The URL is validated when loading the configuration
+		proxyURL, err := url.Parse(reg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", reg.Proxy, err)
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+	if reg.PlainHTTP {
+		return &plainHTTPTransport{transport: tr}, nil
+	}
+	return tr, nil
+}
+
+// Transport returns the HTTP transport used to access the registries, applying the t TLS settings,
+// overridden by the per-registry ones, and skipping the certificates verification if insecure.
+// Registries may also be accessed over plain HTTP, through a specific proxy or bypassing it.
+// The bytes transferred are recorded in the telemetry
+func (c *Config) Transport(t TLS, insecure bool) (http.RoundTripper, error) {
+	defaultTransport, err := newTransport(t, insecure)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]http.RoundTripper)
+	caTransports := make(map[string]http.RoundTripper)
+	for host, reg := range c.registries() {
+		tr, err := newRegistryTransport(t, insecure, reg)
+		if err != nil {
+			return nil, fmt.Errorf("registry %q: %w", host, err)
+		}
+		hosts[host] = tr
+		if reg.CAFile != "" {
+			if caTransports[host], err = newCATransport(t, insecure, reg.CAFile); err != nil {
+				return nil, fmt.Errorf("registry %q: %w", host, err)
+			}
+		}
+	}
+	return telemetry.Transport(&hostTransport{
+		defaultTransport: defaultTransport, hosts: hosts, caTransports: caTransports,
+	}), nil
+}
+
+// CraneOptions returns the crane options accessing the registries with the settings of Transport
+func (c *Config) CraneOptions(t TLS, insecure bool) ([]crane.Option, error) {
+	opts := make([]crane.Option, 0)
+	if insecure {
+		opts = append(opts, crane.Insecure)
+	}
+	rt, err := c.Transport(t, insecure)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, crane.WithTransport(rt)), nil
+}
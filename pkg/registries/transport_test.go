@@ -0,0 +1,300 @@
+package registries
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes the PEM block of the given type and bytes into a new file in dir
+func writePEM(t *testing.T, dir string, filename string, blockType string, data []byte) string {
+	t.Helper()
+	file := filepath.Join(dir, filename)
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600))
+	return file
+}
+
+// newClientCertificate creates a CA and a client certificate signed by it, returning the CA pool
+// and the client certificate and key files
+func newClientCertificate(t *testing.T) (*x509.CertPool, TLS) {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, TLS{
+		CertFile: writePEM(t, dir, "client.crt", "CERTIFICATE", der),
+		KeyFile:  writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER),
+	}
+}
+
+func TestTLS_Config(t *testing.T) {
+	t.Run("Returns the default configuration", func(t *testing.T) {
+		cfg, err := TLS{}.Config(false)
+		require.NoError(t, err)
+		assert.False(t, cfg.InsecureSkipVerify)
+		assert.Nil(t, cfg.RootCAs)
+		assert.Empty(t, cfg.Certificates)
+
+		cfg, err = TLS{}.Config(true)
+		require.NoError(t, err)
+		assert.True(t, cfg.InsecureSkipVerify)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		_, err := TLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")}.Config(false)
+		assert.ErrorContains(t, err, "failed to read CA file")
+
+		invalidCA := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(invalidCA, []byte("not a certificate"), 0600))
+		_, err = TLS{CAFile: invalidCA}.Config(false)
+		assert.ErrorContains(t, err, "no certificates found in CA file")
+
+		_, err = TLS{CertFile: "client.crt"}.Config(false)
+		assert.ErrorContains(t, err, "must be provided together")
+
+		_, err = TLS{CertFile: invalidCA, KeyFile: invalidCA}.Config(false)
+		assert.ErrorContains(t, err, "failed to load client certificate")
+	})
+}
+
+func TestConfig_Transport(t *testing.T) {
+	clientCAs, clientTLS := newClientCertificate(t)
+
+	s := httptest.NewUnstartedServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	s.StartTLS()
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	serverCA := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", s.Certificate().Raw)
+
+	image := u.Host + "/test:latest"
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	fullTLS := TLS{CAFile: serverCA, CertFile: clientTLS.CertFile, KeyFile: clientTLS.KeyFile}
+
+	push := func(c *Config, tlsSettings TLS) error {
+		opts, err := c.CraneOptions(tlsSettings, false)
+		if err != nil {
+			return err
+		}
+		return crane.Push(img, image, opts...)
+	}
+
+	t.Run("Fails without the server CA", func(t *testing.T) {
+		assert.ErrorContains(t, push(nil, TLS{}), "certificate")
+	})
+	t.Run("Fails without the client certificate", func(t *testing.T) {
+		assert.Error(t, push(nil, TLS{CAFile: serverCA}))
+	})
+	t.Run("Uses the global settings", func(t *testing.T) {
+		require.NoError(t, push(nil, fullTLS))
+	})
+	t.Run("Uses the per-registry settings", func(t *testing.T) {
+		c := &Config{Registries: map[string]Registry{u.Host: {TLS: fullTLS}}}
+		require.NoError(t, push(c, TLS{}))
+
+		// Settings of other registries are ignored
+		c = &Config{Registries: map[string]Registry{"registry.example.com": {TLS: fullTLS}}}
+		assert.Error(t, push(c, TLS{}))
+	})
+	t.Run("Overrides the global settings with the per-registry ones", func(t *testing.T) {
+		c := &Config{Registries: map[string]Registry{u.Host: {TLS: clientTLS}}}
+		require.NoError(t, push(c, TLS{CAFile: serverCA}))
+	})
+}
+
+func TestConfig_Transport_Registries(t *testing.T) {
+	get := func(c *Config, insecure bool, url string) (*http.Response, error) {
+		rt, err := c.Transport(TLS{}, insecure)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := (&http.Client{Transport: rt}).Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	t.Run("Accesses the registry over plain HTTP", func(t *testing.T) {
+		s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+		defer s.Close()
+		u, err := url.Parse(s.URL)
+		require.NoError(t, err)
+
+		_, err = get(nil, false, "https://"+u.Host+"/v2/")
+		assert.Error(t, err)
+
+		resp, err := get(&Config{Registries: map[string]Registry{u.Host: {PlainHTTP: true}}}, false, "https://"+u.Host+"/v2/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+	t.Run("Skips the certificate verification of insecure registries", func(t *testing.T) {
+		s := httptest.NewTLSServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+		defer s.Close()
+		u, err := url.Parse(s.URL)
+		require.NoError(t, err)
+
+		_, err = get(nil, false, s.URL+"/v2/")
+		assert.ErrorContains(t, err, "certificate")
+
+		resp, err := get(&Config{Registries: map[string]Registry{u.Host: {Insecure: true}}}, false, s.URL+"/v2/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+	t.Run("Accesses the registry through its proxy", func(t *testing.T) {
+		var proxied []string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = append(proxied, r.URL.String())
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer proxy.Close()
+
+		c := &Config{Registries: map[string]Registry{
+			"registry.example.com": {PlainHTTP: true, Proxy: proxy.URL},
+		}}
+		resp, err := get(c, false, "https://registry.example.com/v2/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"http://registry.example.com/v2/"}, proxied)
+	})
+	// The httptest TLS servers share their certificate
+	serverCA := func(t *testing.T, s *httptest.Server) string {
+		return writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", s.Certificate().Raw)
+	}
+	t.Run("Trusts the registry CA for its redirections", func(t *testing.T) {
+		cdn := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer cdn.Close()
+		s := httptest.NewTLSServer(http.RedirectHandler(cdn.URL+"/blob", http.StatusTemporaryRedirect))
+		defer s.Close()
+		u, err := url.Parse(s.URL)
+		require.NoError(t, err)
+
+		c := &Config{Registries: map[string]Registry{u.Host: {TLS: TLS{CAFile: serverCA(t, s)}}}}
+		resp, err := get(c, false, s.URL+"/v2/test/blobs/sha256:0")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, cdn.URL+"/blob", resp.Request.URL.String())
+
+		// Skipping the verification only applies to the registry itself
+		c = &Config{Registries: map[string]Registry{u.Host: {Insecure: true}}}
+		_, err = get(c, false, s.URL+"/v2/test/blobs/sha256:0")
+		assert.ErrorContains(t, err, "certificate")
+	})
+	t.Run("Keeps HTTPS for the redirections of plain HTTP registries", func(t *testing.T) {
+		var tlsRequests int
+		cdn := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil {
+				tlsRequests++
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer cdn.Close()
+		s := httptest.NewServer(http.RedirectHandler(cdn.URL+"/blob", http.StatusTemporaryRedirect))
+		defer s.Close()
+		u, err := url.Parse(s.URL)
+		require.NoError(t, err)
+
+		c := &Config{Registries: map[string]Registry{u.Host: {PlainHTTP: true, TLS: TLS{CAFile: serverCA(t, cdn)}}}}
+		resp, err := get(c, false, "https://"+u.Host+"/v2/test/blobs/sha256:0")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, cdn.URL+"/blob", resp.Request.URL.String())
+		assert.Equal(t, 1, tlsRequests)
+
+		c = &Config{Registries: map[string]Registry{u.Host: {PlainHTTP: true}}}
+		_, err = get(c, false, "https://"+u.Host+"/v2/test/blobs/sha256:0")
+		assert.ErrorContains(t, err, "certificate")
+	})
+	t.Run("Trusts the registry CA for its token realm", func(t *testing.T) {
+		auth := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+		}))
+		defer auth.Close()
+		// newTokenRegistry returns a registry sending its clients to auth for the tokens of service
+		newTokenRegistry := func(service string) string {
+			reg := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+			s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service=%q`, auth.URL, service))
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			t.Cleanup(s.Close)
+			u, err := url.Parse(s.URL)
+			require.NoError(t, err)
+			return u.Host
+		}
+		trusted, insecure := newTokenRegistry("trusted"), newTokenRegistry("insecure")
+
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+		opts, err := (&Config{Registries: map[string]Registry{
+			trusted:  {TLS: TLS{CAFile: serverCA(t, auth)}},
+			insecure: {Insecure: true},
+		}}).CraneOptions(TLS{}, false)
+		require.NoError(t, err)
+		require.NoError(t, crane.Push(img, trusted+"/test:latest", opts...))
+
+		// The auth server is not trusted for the registries without the CA, even if shared
+		assert.ErrorContains(t, crane.Push(img, insecure+"/test:latest", opts...), "certificate")
+	})
+}
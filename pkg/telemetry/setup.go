@@ -0,0 +1,182 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/contrib/exporters/autoexport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Config defines how the telemetry is exported
+type Config struct {
+	// File is the file the traces and metrics are written to, one JSON document per line.
+	// When empty, they are exported as configured by the standard OTEL_* environment variables
+	File string
+	// ServiceName and ServiceVersion identify the tool in the exported telemetry
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Option defines a telemetry Config option
+type Option func(*Config)
+
+// WithFile configures the file the traces and metrics are written to
+func WithFile(filename string) Option {
+	return func(c *Config) {
+		c.File = filename
+	}
+}
+
+// WithService configures the name and version of the service exporting the telemetry
+func WithService(name, version string) Option {
+	return func(c *Config) {
+		c.ServiceName = name
+		c.ServiceVersion = version
+	}
+}
+
+// NewConfig returns a new Config with the provided options applied
+func NewConfig(opts ...Option) *Config {
+	cfg := &Config{ServiceName: "dt"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// ShutdownFunc flushes the pending telemetry and releases its exporters
+type ShutdownFunc func(context.Context) error
+
+func envSet(names ...string) bool {
+	for _, name := range names {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// tracesFromEnv returns whether the environment configures a traces exporter
+func tracesFromEnv() bool {
+	return envSet("OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+}
+
+// metricsFromEnv returns whether the environment configures a metrics exporter
+func metricsFromEnv() bool {
+	return envSet("OTEL_METRICS_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT")
+}
+
+// syncWriter serializes the writes of the traces and metrics exporters sharing a file
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// Setup configures the global trace and meter providers as defined in the provided options. Telemetry
+// is only enabled when a file is configured or the OTEL_* environment variables define an exporter, and
+// never when OTEL_SDK_DISABLED is true. The returned ShutdownFunc must be called before exiting
+func Setup(ctx context.Context, opts ...Option) (ShutdownFunc, error) {
+	cfg := NewConfig(opts...)
+	noop := func(context.Context) error { return nil }
+
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return noop, nil
+	}
+	var spanExporter sdktrace.SpanExporter
+	var metricReader sdkmetric.Reader
+	var closeFile func() error
+
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0755); err != nil {
+			return noop, fmt.Errorf("failed to create telemetry directory: %w", err)
+		}
+		fh, err := os.Create(cfg.File)
+		if err != nil {
+			return noop, fmt.Errorf("failed to create telemetry file: %w", err)
+		}
+		closeFile = fh.Close
+		w := &syncWriter{w: fh}
+		if spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(w)); err != nil {
+			_ = fh.Close()
+			return noop, fmt.Errorf("failed to create traces exporter: %w", err)
+		}
+		metricExporter, err := stdoutmetric.New(stdoutmetric.WithWriter(w))
+		if err != nil {
+			_ = fh.Close()
+			return noop, fmt.Errorf("failed to create metrics exporter: %w", err)
+		}
+		metricReader = sdkmetric.NewPeriodicReader(metricExporter)
+	} else {
+		var err error
+		if tracesFromEnv() {
+			if spanExporter, err = autoexport.NewSpanExporter(ctx); err != nil {
+				return noop, fmt.Errorf("failed to create traces exporter: %w", err)
+			}
+		}
+		if metricsFromEnv() {
+			if metricReader, err = autoexport.NewMetricReader(ctx); err != nil {
+				return noop, fmt.Errorf("failed to create metrics reader: %w", err)
+			}
+		}
+	}
+	if spanExporter == nil && metricReader == nil {
+		return noop, nil
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", cfg.ServiceName)}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, attribute.String("service.version", cfg.ServiceVersion))
+	}
+	// The environment takes precedence over the default service attributes
+	res, err := resource.New(ctx, resource.WithAttributes(attrs...), resource.WithTelemetrySDK(), resource.WithFromEnv())
+	if err != nil {
+		if closeFile != nil {
+			_ = closeFile()
+		}
+		return noop, fmt.Errorf("failed to create telemetry resource: %w", err)
+	}
+
+	shutdowns := make([]ShutdownFunc, 0)
+	if spanExporter != nil {
+		tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+		otel.SetTracerProvider(tp)
+		shutdowns = append(shutdowns, tp.Shutdown)
+	}
+	if metricReader != nil {
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(metricReader), sdkmetric.WithResource(res))
+		otel.SetMeterProvider(mp)
+		shutdowns = append(shutdowns, mp.Shutdown)
+	}
+	return func(ctx context.Context) error {
+		var allErrors error
+		for _, shutdown := range shutdowns {
+			allErrors = errors.Join(allErrors, shutdown(ctx))
+		}
+		if closeFile != nil {
+			allErrors = errors.Join(allErrors, closeFile())
+		}
+		if allErrors != nil {
+			return fmt.Errorf("failed to flush telemetry: %w", allErrors)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,84 @@
+// Package telemetry instruments the long running operations with OpenTelemetry traces and metrics.
+// The instrumentation uses the global providers, so it does nothing until Setup configures them
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer and meter of the tool
+const InstrumentationName = "github.com/vmware-labs/distribution-tooling-for-helm"
+
+const (
+	// BytesMetric counts the bytes sent to and received from the registries
+	BytesMetric = "dt.transfer.bytes"
+	// RetriesMetric counts the retried operations
+	RetriesMetric = "dt.retries"
+)
+
+const (
+	// DirectionSent identifies the bytes sent to the registries
+	DirectionSent = "sent"
+	// DirectionReceived identifies the bytes received from the registries
+	DirectionReceived = "received"
+)
+
+// StartSpan starts a span named name as a child of the span in ctx, returning the context holding it
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan ends the span, recording err as its status
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Trace executes fn inside a span named name, child of the span in ctx
+func Trace(ctx context.Context, name string, fn func(context.Context) error, attrs ...attribute.KeyValue) error {
+	ctx, span := StartSpan(ctx, name, attrs...)
+	err := fn(ctx)
+	EndSpan(span, err)
+	return err
+}
+
+// RecordRetry records a retry of operation, also adding it as an event of the span in ctx
+func RecordRetry(ctx context.Context, operation string, try int, prevErr error) {
+	attrs := []attribute.KeyValue{attribute.String("dt.operation", operation)}
+	if counter, err := otel.Meter(InstrumentationName).Int64Counter(RetriesMetric,
+		metric.WithDescription("Number of retried operations"),
+	); err == nil {
+		counter.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+	eventAttrs := append(attrs, attribute.Int("dt.retry", try))
+	if prevErr != nil {
+		eventAttrs = append(eventAttrs, attribute.String("dt.error", prevErr.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(eventAttrs...))
+}
+
+// RecordBytes records n bytes transferred with host in the given direction
+func RecordBytes(ctx context.Context, host string, direction string, n int64) {
+	if n <= 0 {
+		return
+	}
+	counter, err := otel.Meter(InstrumentationName).Int64Counter(BytesMetric,
+		metric.WithDescription("Number of bytes transferred from and to the registries"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return
+	}
+	counter.Add(ctx, n, metric.WithAttributes(
+		attribute.String("server.address", host), attribute.String("dt.direction", direction),
+	))
+}
@@ -0,0 +1,172 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// withMeterReader installs a global meter provider collected by the returned reader
+func withMeterReader() *sdkmetric.ManualReader {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return reader
+}
+
+// collectSums returns the values of the metric sum, keyed by the value of the attribute key
+func collectSums(t *testing.T, reader *sdkmetric.ManualReader, name string, key attribute.Key) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	sums := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "unexpected %q data type %T", name, m.Data)
+			for _, dp := range sum.DataPoints {
+				v, _ := dp.Attributes.Value(key)
+				sums[v.AsString()] += dp.Value
+			}
+		}
+	}
+	return sums
+}
+
+func TestTransport(t *testing.T) {
+	reader := withMeterReader()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "received %d bytes", len(data))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("hello"))
+		require.NoError(t, err)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, "received 5 bytes", string(data))
+	}
+
+	assert.Equal(t, map[string]int64{DirectionSent: 10, DirectionReceived: 32},
+		collectSums(t, reader, BytesMetric, "dt.direction"))
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{u.Host: 42}, collectSums(t, reader, BytesMetric, "server.address"))
+}
+
+func TestRecordRetry(t *testing.T) {
+	reader := withMeterReader()
+
+	ctx := context.Background()
+	RecordRetry(ctx, "pull", 1, fmt.Errorf("timeout"))
+	RecordRetry(ctx, "pull", 2, fmt.Errorf("timeout"))
+	RecordRetry(ctx, "push", 1, nil)
+
+	assert.Equal(t, map[string]int64{"pull": 2, "push": 1}, collectSums(t, reader, RetriesMetric, "dt.operation"))
+}
+
+// readTelemetryFile returns the names of the spans and metrics written into filename
+func readTelemetryFile(t *testing.T, filename string) (spans []string, metrics []string) {
+	t.Helper()
+	fh, err := os.Open(filename)
+	require.NoError(t, err)
+	defer fh.Close()
+
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var doc struct {
+			Name         string
+			ScopeMetrics []struct {
+				Metrics []struct {
+					Name string
+				}
+			}
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc), "invalid JSON line %q", scanner.Text())
+		if doc.Name != "" {
+			spans = append(spans, doc.Name)
+		}
+		for _, sm := range doc.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				metrics = append(metrics, m.Name)
+			}
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return spans, metrics
+}
+
+func TestSetup(t *testing.T) {
+	for _, env := range []string{
+		"OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER", "OTEL_METRICS_EXPORTER",
+		"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT",
+	} {
+		t.Setenv(env, "")
+	}
+	prevTracerProvider := otel.GetTracerProvider()
+
+	t.Run("Does nothing unless configured", func(t *testing.T) {
+		shutdown, err := Setup(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, prevTracerProvider, otel.GetTracerProvider())
+		assert.NoError(t, shutdown(context.Background()))
+	})
+	t.Run("Does nothing when the SDK is disabled", func(t *testing.T) {
+		t.Setenv("OTEL_SDK_DISABLED", "true")
+		filename := filepath.Join(t.TempDir(), "telemetry.json")
+		shutdown, err := Setup(context.Background(), WithFile(filename))
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+		assert.NoFileExists(t, filename)
+	})
+	t.Run("Writes the telemetry into a file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "telemetry.json")
+		shutdown, err := Setup(context.Background(), WithFile(filename), WithService("dt", "1.0.0"))
+		require.NoError(t, err)
+
+		err = Trace(context.Background(), "PushImages", func(ctx context.Context) error {
+			_, span := StartSpan(ctx, "PushImage", attribute.String("dt.image", "example.com/app:1.0"))
+			RecordRetry(ctx, "push", 1, fmt.Errorf("timeout"))
+			EndSpan(span, fmt.Errorf("denied"))
+			return nil
+		})
+		require.NoError(t, err)
+		RecordBytes(context.Background(), "example.com", DirectionSent, 100)
+		require.NoError(t, shutdown(context.Background()))
+
+		spans, metrics := readTelemetryFile(t, filename)
+		assert.Equal(t, []string{"PushImage", "PushImages"}, spans)
+		assert.ElementsMatch(t, []string{RetriesMetric, BytesMetric}, metrics)
+	})
+	t.Run("Fails to create the file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte{}, 0644))
+		_, err := Setup(context.Background(), WithFile(filepath.Join(dir, "file", "telemetry.json")))
+		require.ErrorContains(t, err, "failed to create telemetry directory")
+	})
+}
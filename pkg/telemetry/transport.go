@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// countingBody counts the bytes read from a request or response body, recording them once it is
+// fully read or closed. The HTTP transport may close the request body while it is still being read
+type countingBody struct {
+	io.ReadCloser
+	ctx       context.Context
+	host      string
+	direction string
+
+	once sync.Once
+	n    atomic.Int64
+}
+
+func (b *countingBody) record() {
+	b.once.Do(func() {
+		RecordBytes(b.ctx, b.host, b.direction, b.n.Load())
+	})
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	if err == io.EOF {
+		b.record()
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.record()
+	return b.ReadCloser.Close()
+}
+
+// transport records the bytes sent and received by the wrapped transport
+type transport struct {
+	transport http.RoundTripper
+}
+
+// Transport returns rt recording the bytes of the request and response bodies
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{transport: rt}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(ctx)
+		req.Body = &countingBody{ReadCloser: req.Body, ctx: ctx, host: host, direction: DirectionSent}
+	}
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &countingBody{ReadCloser: resp.Body, ctx: ctx, host: host, direction: DirectionReceived}
+	}
+	return resp, nil
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/opencontainers/go-digest"
+	"gopkg.in/yaml.v3"
+)
+
+// SplitIndexKind is the kind of the split index files
+const SplitIndexKind = "SplitIndex"
+
+// SplitIndexSuffix is appended to the name of the split file to obtain its index file name
+const SplitIndexSuffix = ".index.yaml"
+
+var partSuffixRe = regexp.MustCompile(`^(.+)\.(\d{3,})$`)
+
+// SplitIndex describes a file split into several size-limited parts
+type SplitIndex struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Name       string      `yaml:"name"` // The name of the original file
+	Size       int64       `yaml:"size"` // The size of the original file
+	Parts      []SplitPart `yaml:"parts"`
+}
+
+// SplitPart describes each of the parts of a split file
+type SplitPart struct {
+	Name   string        `yaml:"name"` // The name of the part, relative to the index file
+	Size   int64         `yaml:"size"`
+	Digest digest.Digest `yaml:"digest"`
+}
+
+// ParseSize parses human readable sizes such as 4G or 700MiB into bytes
+func ParseSize(size string) (int64, error) {
+	n, err := humanize.ParseBytes(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	if n == 0 || n > uint64(1<<62) {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(n), nil
+}
+
+// SplitPartName returns the name of the n-th part (starting at 1) of filename
+func SplitPartName(filename string, n int) string {
+	return fmt.Sprintf("%s.%03d", filename, n)
+}
+
+// SplitWriter writes its contents into consecutive part files of at most partSize bytes.
+// Closing it writes the index file describing the parts
+type SplitWriter struct {
+	filename string
+	partSize int64
+	current  *os.File
+	digester digest.Digester
+	written  int64
+	index    SplitIndex
+}
+
+// NewSplitWriter returns a SplitWriter creating the parts of filename
+func NewSplitWriter(filename string, partSize int64) (*SplitWriter, error) {
+	if partSize <= 0 {
+		return nil, fmt.Errorf("invalid part size %d", partSize)
+	}
+	w := &SplitWriter{
+		filename: filename,
+		partSize: partSize,
+		index: SplitIndex{
+			APIVersion: "v0",
+			Kind:       SplitIndexKind,
+			Name:       filepath.Base(filename),
+		},
+	}
+	if err := w.nextPart(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// IndexFile returns the path to the index file written on Close
+func (w *SplitWriter) IndexFile() string {
+	return w.filename + SplitIndexSuffix
+}
+
+func (w *SplitWriter) nextPart() error {
+	if err := w.closePart(); err != nil {
+		return err
+	}
+	partFile := SplitPartName(w.filename, len(w.index.Parts)+1)
+	fh, err := os.Create(partFile)
+	if err != nil {
+		return fmt.Errorf("failed to create part %q: %w", partFile, err)
+	}
+	w.current = fh
+	w.digester = digest.Canonical.Digester()
+	w.written = 0
+	w.index.Parts = append(w.index.Parts, SplitPart{Name: filepath.Base(partFile)})
+	return nil
+}
+
+func (w *SplitWriter) closePart() error {
+	if w.current == nil {
+		return nil
+	}
+	part := &w.index.Parts[len(w.index.Parts)-1]
+	part.Size = w.written
+	part.Digest = w.digester.Digest()
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
+
+// Write writes p into the current part, starting new parts as they get full
+func (w *SplitWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		if w.current == nil {
+			return total, fmt.Errorf("write to closed SplitWriter")
+		}
+		if w.written == w.partSize {
+			if err := w.nextPart(); err != nil {
+				return total, err
+			}
+		}
+		chunk := p
+		if remaining := w.partSize - w.written; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		n, err := w.current.Write(chunk)
+		_, _ = w.digester.Hash().Write(chunk[:n])
+		w.written += int64(n)
+		w.index.Size += int64(n)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// Close closes the last part and writes the index file
+func (w *SplitWriter) Close() error {
+	if w.current == nil {
+		return nil
+	}
+	if err := w.closePart(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(w.index)
+	if err != nil {
+		return fmt.Errorf("failed to serialize split index: %w", err)
+	}
+	return os.WriteFile(w.IndexFile(), data, 0644)
+}
+
+// Remove deletes the parts and the index file written so far
+func (w *SplitWriter) Remove() error {
+	_ = w.closePart()
+	var errs []error
+	for _, part := range w.index.Parts {
+		if err := os.Remove(filepath.Join(filepath.Dir(w.filename), part.Name)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	if err := os.Remove(w.IndexFile()); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// FindSplitIndex returns the index file of the split file filename refers to, which can
+// be either the index itself or the first of its parts. It returns an empty string if
+// filename is not part of a split file
+func FindSplitIndex(filename string) (string, error) {
+	if strings.HasSuffix(filename, SplitIndexSuffix) {
+		return filename, nil
+	}
+	m := partSuffixRe.FindStringSubmatch(filename)
+	if m == nil {
+		return "", nil
+	}
+	indexFile := m[1] + SplitIndexSuffix
+	if !FileExists(indexFile) {
+		return "", nil
+	}
+	if filename != SplitPartName(m[1], 1) {
+		return "", fmt.Errorf("%q is not the first part of %q", filename, filepath.Base(m[1]))
+	}
+	return indexFile, nil
+}
+
+// ReadSplitIndex reads the split index file and checks all its parts are available
+func ReadSplitIndex(indexFile string) (*SplitIndex, error) {
+	data, err := os.ReadFile(indexFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read split index: %w", err)
+	}
+	index := &SplitIndex{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse split index %q: %w", indexFile, err)
+	}
+	if index.Kind != SplitIndexKind {
+		return nil, fmt.Errorf("%q is not a split index", indexFile)
+	}
+	if len(index.Parts) == 0 {
+		return nil, fmt.Errorf("split index %q does not contain any part", indexFile)
+	}
+	var size int64
+	for _, part := range index.Parts {
+		if err := part.Digest.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid digest for part %q: %w", part.Name, err)
+		}
+		fi, err := os.Stat(filepath.Join(filepath.Dir(indexFile), part.Name))
+		if err != nil {
+			return nil, fmt.Errorf("missing part %q: %w", part.Name, err)
+		}
+		if fi.Size() != part.Size {
+			return nil, fmt.Errorf("part %q size mismatch: expected %d, got %d", part.Name, part.Size, fi.Size())
+		}
+		size += part.Size
+	}
+	if size != index.Size {
+		return nil, fmt.Errorf("split index %q size mismatch: expected %d, got %d", indexFile, index.Size, size)
+	}
+	return index, nil
+}
+
+// VerifySplitFile checks the digests of all the parts described in the split index file
+func VerifySplitFile(indexFile string) error {
+	r, err := OpenSplitFile(indexFile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// OpenSplitFile returns a reader for the reassembled contents of the split index file.
+// The digest of every part is verified once it is fully read
+func OpenSplitFile(indexFile string) (io.ReadCloser, error) {
+	index, err := ReadSplitIndex(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	return &splitReader{dir: filepath.Dir(indexFile), parts: index.Parts}, nil
+}
+
+type splitReader struct {
+	dir      string
+	parts    []SplitPart
+	current  *os.File
+	verifier digest.Verifier
+}
+
+func (r *splitReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			fh, err := os.Open(filepath.Join(r.dir, r.parts[0].Name))
+			if err != nil {
+				return 0, fmt.Errorf("failed to open part %q: %w", r.parts[0].Name, err)
+			}
+			r.current = fh
+			r.verifier = r.parts[0].Digest.Verifier()
+		}
+		n, err := r.current.Read(p)
+		_, _ = r.verifier.Write(p[:n])
+		if errors.Is(err, io.EOF) {
+			part := r.parts[0]
+			r.parts = r.parts[1:]
+			closeErr := r.current.Close()
+			r.current = nil
+			if !r.verifier.Verified() {
+				return n, fmt.Errorf("part %q digest mismatch: expected %q", part.Name, part.Digest)
+			}
+			if closeErr != nil {
+				return n, closeErr
+			}
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *splitReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
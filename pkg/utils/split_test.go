@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSplitFile(t *testing.T, data []byte, partSize int64) string {
+	t.Helper()
+	filename := filepath.Join(sb.TempFile(), "sample.wrap.tgz")
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	w, err := NewSplitWriter(filename, partSize)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return filename
+}
+
+func TestParseSize(t *testing.T) {
+	for size, expected := range map[string]int64{
+		"4G":     4 * 1000 * 1000 * 1000,
+		"700MiB": 700 * 1024 * 1024,
+		"1024":   1024,
+	} {
+		got, err := ParseSize(size)
+		require.NoError(t, err)
+		assert.Equal(t, expected, got)
+	}
+	for _, size := range []string{"", "0", "-1G", "4X"} {
+		_, err := ParseSize(size)
+		assert.Error(t, err, "expected %q to be rejected", size)
+	}
+}
+
+func TestSplitFile(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 10) + "abc")
+
+	t.Run("Splits and reassembles files", func(t *testing.T) {
+		filename := writeSplitFile(t, data, 10)
+
+		index, err := ReadSplitIndex(filename + SplitIndexSuffix)
+		require.NoError(t, err)
+		assert.Equal(t, "sample.wrap.tgz", index.Name)
+		assert.Equal(t, int64(len(data)), index.Size)
+		require.Len(t, index.Parts, 11)
+		assert.Equal(t, "sample.wrap.tgz.011", index.Parts[10].Name)
+		assert.Equal(t, int64(3), index.Parts[10].Size)
+		assert.NoFileExists(t, filename)
+
+		for _, input := range []string{filename + SplitIndexSuffix, SplitPartName(filename, 1)} {
+			indexFile, err := FindSplitIndex(input)
+			require.NoError(t, err)
+			r, err := OpenSplitFile(indexFile)
+			require.NoError(t, err)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, data, got)
+		}
+	})
+	t.Run("Only accepts the first part", func(t *testing.T) {
+		filename := writeSplitFile(t, data, 10)
+		_, err := FindSplitIndex(SplitPartName(filename, 2))
+		require.ErrorContains(t, err, "is not the first part")
+
+		indexFile, err := FindSplitIndex(filepath.Join(filepath.Dir(filename), "other.001"))
+		require.NoError(t, err)
+		assert.Empty(t, indexFile)
+	})
+	t.Run("Detects corrupted parts", func(t *testing.T) {
+		filename := writeSplitFile(t, data, 10)
+		part := SplitPartName(filename, 5)
+		require.NoError(t, os.WriteFile(part, bytes.Repeat([]byte("x"), 10), 0644))
+		require.ErrorContains(t, VerifySplitFile(filename+SplitIndexSuffix), `part "sample.wrap.tgz.005" digest mismatch`)
+	})
+	t.Run("Detects missing and truncated parts", func(t *testing.T) {
+		filename := writeSplitFile(t, data, 10)
+		require.NoError(t, os.Remove(SplitPartName(filename, 3)))
+		_, err := ReadSplitIndex(filename + SplitIndexSuffix)
+		require.ErrorContains(t, err, `missing part "sample.wrap.tgz.003"`)
+
+		filename = writeSplitFile(t, data, 10)
+		require.NoError(t, os.Truncate(SplitPartName(filename, 11), 1))
+		_, err = ReadSplitIndex(filename + SplitIndexSuffix)
+		require.ErrorContains(t, err, `part "sample.wrap.tgz.011" size mismatch`)
+	})
+	t.Run("Tars into split files", func(t *testing.T) {
+		sourceDir, err := sb.Mkdir(sb.TempFile(), 0755)
+		require.NoError(t, err)
+		_, err = sb.WriteFile(filepath.Join(sourceDir, "data.bin"), data, 0644)
+		require.NoError(t, err)
+
+		tarFile := filepath.Join(sb.TempFile(), "sample.wrap.tar")
+		require.NoError(t, Tar(sourceDir, tarFile, TarConfig{Prefix: "sample", Compression: CompressionNone, SplitSize: 512}))
+
+		for _, input := range []string{tarFile + SplitIndexSuffix, SplitPartName(tarFile, 1)} {
+			isTar, err := IsTarFile(input)
+			require.NoError(t, err)
+			assert.True(t, isTar)
+
+			destDir := sb.TempFile()
+			require.NoError(t, Untar(input, destDir, TarConfig{StripComponents: 1}))
+			got, err := os.ReadFile(filepath.Join(destDir, "data.bin"))
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+		}
+
+		// Trailing data after the tar entries is verified too
+		plainTar := filepath.Join(sb.TempFile(), "sample.tar")
+		require.NoError(t, Tar(sourceDir, plainTar, TarConfig{Prefix: "sample", Compression: CompressionNone}))
+		tarData, err := os.ReadFile(plainTar)
+		require.NoError(t, err)
+		padded := writeSplitFile(t, append(tarData, make([]byte, 512)...), 512)
+		require.NoError(t, Untar(padded+SplitIndexSuffix, sb.TempFile(), TarConfig{StripComponents: 1}))
+
+		index, err := ReadSplitIndex(padded + SplitIndexSuffix)
+		require.NoError(t, err)
+		lastPart := filepath.Join(filepath.Dir(padded), index.Parts[len(index.Parts)-1].Name)
+		require.NoError(t, os.WriteFile(lastPart, bytes.Repeat([]byte("x"), 512), 0644))
+		require.ErrorContains(t, Untar(padded+SplitIndexSuffix, sb.TempFile(), TarConfig{StripComponents: 1}), "digest mismatch")
+	})
+	t.Run("Only verifies the parts on archive errors", func(t *testing.T) {
+		sourceDir, err := sb.Mkdir(sb.TempFile(), 0755)
+		require.NoError(t, err)
+		_, err = sb.WriteFile(filepath.Join(sourceDir, "data.bin"), bytes.Repeat(data, 20), 0644)
+		require.NoError(t, err)
+
+		tarFile := filepath.Join(sb.TempFile(), "sample.wrap.tar")
+		require.NoError(t, Tar(sourceDir, tarFile, TarConfig{Prefix: "sample", Compression: CompressionNone, SplitSize: 512}))
+		index, err := ReadSplitIndex(tarFile + SplitIndexSuffix)
+		require.NoError(t, err)
+		lastPart := filepath.Join(filepath.Dir(tarFile), index.Parts[len(index.Parts)-1].Name)
+		require.NoError(t, os.WriteFile(lastPart, bytes.Repeat([]byte("x"), int(index.Parts[len(index.Parts)-1].Size)), 0644))
+
+		err = WalkTarFile(context.Background(), tarFile+SplitIndexSuffix, func(_ *tar.Reader, _ *tar.Header) error {
+			return errors.New("operation failed")
+		})
+		require.EqualError(t, err, "operation failed")
+
+		err = WalkTarFile(context.Background(), tarFile+SplitIndexSuffix, func(tr *tar.Reader, _ *tar.Header) error {
+			_, err := io.Copy(io.Discard, tr)
+			return err
+		})
+		require.ErrorContains(t, err, "digest mismatch")
+	})
+}
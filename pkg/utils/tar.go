@@ -2,6 +2,8 @@ package utils
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -10,6 +12,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // MaxDecompressionSize established a high enough maximum tar size to decompres
@@ -46,11 +53,174 @@ func tarFile(tarWriter *tar.Writer, source string, relativePath string, info os.
 	return nil
 }
 
+// Compression defines the compression format of a tar file
+type Compression string
+
+const (
+	// CompressionGzip compresses the tar file using gzip
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses the tar file using zstd
+	CompressionZstd Compression = "zstd"
+	// CompressionNone does not compress the tar file
+	CompressionNone Compression = "none"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	tarMagic  = []byte("ustar")
+)
+
+// ParseCompression returns the Compression matching the provided name
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(strings.ToLower(name)); c {
+	case CompressionGzip, CompressionZstd, CompressionNone:
+		return c, nil
+	case "":
+		return CompressionGzip, nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q (supported: %s, %s, %s)", name, CompressionGzip, CompressionZstd, CompressionNone)
+	}
+}
+
+// Extension returns the conventional file extension for tar files using the compression
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionZstd:
+		return ".tar.zst"
+	case CompressionNone:
+		return ".tar"
+	default:
+		return ".tgz"
+	}
+}
+
 // TarConfig defines the Tar char opts
 type TarConfig struct {
 	Prefix          string
 	StripComponents int
 	Skip            func(f string) bool
+	// Compression defaults to gzip
+	Compression Compression
+	// CompressionLevel is specific to the compression format. 0 selects its default level
+	CompressionLevel int
+	// SplitSize, when set, splits the tar into parts of at most SplitSize bytes
+	SplitSize int64
+}
+
+// openArchive opens filename for reading, transparently reassembling split files
+// when provided their index or first part
+func openArchive(filename string) (io.ReadCloser, error) {
+	indexFile, err := FindSplitIndex(filename)
+	if err != nil {
+		return nil, err
+	}
+	if indexFile != "" {
+		return OpenSplitFile(indexFile)
+	}
+	return os.Open(filename)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// ValidateCompression checks the compression level is valid for the provided compression
+func ValidateCompression(compression Compression, level int) error {
+	compression, err := ParseCompression(string(compression))
+	if err != nil {
+		return err
+	}
+	if level == 0 {
+		return nil
+	}
+	switch compression {
+	case CompressionZstd:
+		if level < 1 || level > 22 {
+			return fmt.Errorf("invalid zstd compression level %d (valid range: 1-22)", level)
+		}
+	case CompressionNone:
+		return fmt.Errorf("compression level cannot be set for uncompressed tar files")
+	default:
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return fmt.Errorf("invalid gzip compression level %d (valid range: %d-%d)", level, gzip.BestSpeed, gzip.BestCompression)
+		}
+	}
+	return nil
+}
+
+func newCompressor(w io.Writer, cfg TarConfig) (io.WriteCloser, error) {
+	if err := ValidateCompression(cfg.Compression, cfg.CompressionLevel); err != nil {
+		return nil, err
+	}
+	compression, _ := ParseCompression(string(cfg.Compression))
+	level := cfg.CompressionLevel
+	switch compression {
+	case CompressionZstd:
+		opts := []zstd.EOption{}
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(w, opts...)
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	default:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	}
+}
+
+func detectCompression(r *bufio.Reader) (Compression, error) {
+	head, err := r.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return CompressionGzip, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		return CompressionZstd, nil
+	case len(head) >= 257+len(tarMagic) && bytes.Equal(head[257:257+len(tarMagic)], tarMagic):
+		return CompressionNone, nil
+	}
+	return "", fmt.Errorf("unknown tar file format")
+}
+
+// DetectCompression returns the compression format of the provided tar file
+func DetectCompression(filename string) (Compression, error) {
+	fh, err := openArchive(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer fh.Close()
+	return detectCompression(bufio.NewReader(fh))
+}
+
+// newDecompressor returns a reader for the tar contents of r, detecting its compression format
+func newDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	compression, err := detectCompression(br)
+	if err != nil {
+		return nil, err
+	}
+	switch compression {
+	case CompressionZstd:
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case CompressionNone:
+		return io.NopCloser(br), nil
+	default:
+		return gzip.NewReader(br)
+	}
 }
 
 // Tar calls TarContext with a Background context
@@ -58,38 +228,79 @@ func Tar(sourceDir string, filename string, cfg TarConfig) error {
 	return TarContext(context.Background(), sourceDir, filename, cfg)
 }
 
-// TarContext compresses the provided sourceDir directory into the .tar.gz specified in filename,
-// adding prefix to the added files.
-func TarContext(parentCtx context.Context, sourceDir string, filename string, cfg TarConfig) error {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
+// TarContext compresses the provided sourceDir directory into the tar file specified in filename,
+// adding prefix to the added files. The tar is compressed using the configured compression
+func TarContext(ctx context.Context, sourceDir string, filename string, cfg TarConfig) (err error) {
+	ctx, span := telemetry.StartSpan(ctx, "Tar",
+		attribute.String("dt.file", filename), attribute.String("dt.compression", string(cfg.Compression)))
+	defer func() { telemetry.EndSpan(span, err) }()
 
-	prefix := cfg.Prefix
-	skip := cfg.Skip
-	if skip == nil {
-		skip = func(_ string) bool { return false }
+	tw, err := NewTarWriter(filename, cfg)
+	if err != nil {
+		return err
+	}
+	if err := tw.AddDir(ctx, sourceDir, cfg); err != nil {
+		_ = tw.Close()
+		return err
 	}
+	return tw.Close()
+}
+
+// TarWriter incrementally writes entries into a tar file
+type TarWriter struct {
+	filename   string
+	out        io.WriteCloser
+	compressor io.WriteCloser
+	tarWriter  *tar.Writer
+	closed     bool
+}
+
+// NewTarWriter creates the tar file specified in filename, compressed as configured in cfg,
+// and returns a TarWriter to populate it
+func NewTarWriter(filename string, cfg TarConfig) (*TarWriter, error) {
 	dir := filepath.Dir(filename)
 	if !FileExists(dir) {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create destination directory %q: %w", dir, err)
+			return nil, fmt.Errorf("failed to create destination directory %q: %w", dir, err)
 		}
 	}
 
-	fh, err := os.Create(filename)
+	if err := ValidateCompression(cfg.Compression, cfg.CompressionLevel); err != nil {
+		return nil, err
+	}
+
+	var out io.WriteCloser
+	var err error
+	if cfg.SplitSize > 0 {
+		out, err = NewSplitWriter(filename, cfg.SplitSize)
+	} else {
+		out, err = os.Create(filename)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create tar.gz filename %q: %w", filename, err)
+		return nil, fmt.Errorf("failed to create tar filename %q: %w", filename, err)
 	}
-	defer fh.Close()
+	w := &TarWriter{filename: filename, out: out}
+	if w.compressor, err = newCompressor(out, cfg); err != nil {
+		_ = w.Remove()
+		return nil, err
+	}
+	w.tarWriter = tar.NewWriter(w.compressor)
+	return w, nil
+}
 
-	gzWriter := gzip.NewWriter(fh)
-	defer gzWriter.Close()
+// AddDir adds the contents of sourceDir to the tar, adding the configured prefix to the added files
+func (w *TarWriter) AddDir(parentCtx context.Context, sourceDir string, cfg TarConfig) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
 
-	tarWriter := tar.NewWriter(gzWriter)
-	defer tarWriter.Close()
+	prefix := cfg.Prefix
+	skip := cfg.Skip
+	if skip == nil {
+		skip = func(_ string) bool { return false }
+	}
 
 	// Walk through the directory and add files to the tar
-	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("cancelled execution")
@@ -108,10 +319,61 @@ func TarContext(parentCtx context.Context, sourceDir string, filename string, cf
 			}
 			relPath := filepath.ToSlash(filepath.Join(prefix, trimmedPath))
 
-			return tarFile(tarWriter, path, relPath, info)
+			return tarFile(w.tarWriter, path, relPath, info)
 		}
 	})
-	return err
+}
+
+// AddReader adds a regular file named name to the tar, reading its size bytes from r.
+// r is read until EOF so readers verifying their contents on completion can do so
+func (w *TarWriter) AddReader(name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(name),
+		Size:     size,
+		Mode:     0644,
+		ModTime:  time.Now(),
+	}
+	if err := w.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	n, err := io.Copy(w.tarWriter, r)
+	if err != nil {
+		return fmt.Errorf("failed to write %q: %w", name, err)
+	}
+	if n != size {
+		return fmt.Errorf("only wrote %d bytes to %q; expected %d", n, name, size)
+	}
+	return nil
+}
+
+// AddData adds a regular file named name to the tar with the provided contents
+func (w *TarWriter) AddData(name string, data []byte) error {
+	return w.AddReader(name, int64(len(data)), bytes.NewReader(data))
+}
+
+// Close flushes the pending data and closes the underlying file
+func (w *TarWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return errors.Join(w.tarWriter.Close(), w.compressor.Close(), w.out.Close())
+}
+
+// Remove closes the TarWriter and deletes the files written so far
+func (w *TarWriter) Remove() error {
+	if sw, ok := w.out.(*SplitWriter); ok {
+		return sw.Remove()
+	}
+	if w.out != nil {
+		_ = w.out.Close()
+	}
+	w.closed = true
+	if err := os.Remove(w.filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 func stripPathComponents(filename string, stripComponents int) string {
@@ -175,7 +437,8 @@ func Untar(filename string, outputDir string, cfg TarConfig) error {
 // ErrEndTarWalk allows early stopping inspecting the tar file
 var ErrEndTarWalk = errors.New("end walking tar contents")
 
-// UntarContext decompresses the provided filename into the outputDir
+// UntarContext decompresses the provided filename into the outputDir. Entries for which
+// cfg.Skip returns true, given their path after stripping components, are not extracted
 // Simplified implementation taken from: golang.org/x/build/internal/untar (BSD license)
 func UntarContext(ctx context.Context, filename string, outputDir string, cfg TarConfig) error {
 	return WalkTarFile(ctx, filename, func(tr *tar.Reader, header *tar.Header) error {
@@ -185,6 +448,10 @@ func UntarContext(ctx context.Context, filename string, outputDir string, cfg Ta
 			return nil
 		}
 
+		if cfg.Skip != nil && cfg.Skip(filepath.ToSlash(rel)) {
+			return nil
+		}
+
 		abs := filepath.Join(outputDir, rel)
 
 		return untarFile(tr, abs, header)
@@ -210,20 +477,65 @@ func FindFileInTar(ctx context.Context, tarFilename string, path string, operati
 	})
 }
 
-// WalkTarFile iterates over the list of tar entries and applies the provided operation
+// archiveError is an error reading or decompressing an archive, as opposed to the errors of the
+// operations applied to its entries
+type archiveError struct {
+	err error
+}
+
+func (e *archiveError) Error() string {
+	return e.err.Error()
+}
+
+func (e *archiveError) Unwrap() error {
+	return e.err
+}
+
+// archiveReader flags the errors reading its wrapped reader as archiveErrors
+type archiveReader struct {
+	io.Reader
+}
+
+func (r archiveReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = &archiveError{err: err}
+	}
+	return n, err
+}
+
+// WalkTarFile iterates over the list of tar entries and applies the provided operation.
+// Split files are reassembled from their index or first part, verifying the parts as they are read
 func WalkTarFile(ctx context.Context, filename string, operation func(tr *tar.Reader, header *tar.Header) error) error {
-	fh, err := os.Open(filename)
+	fh, err := openArchive(filename)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer fh.Close()
-	gzr, err := gzip.NewReader(fh)
+
+	err = walkTar(ctx, fh, operation)
+	var archiveErr *archiveError
+	if errors.As(err, &archiveErr) && ctx.Err() == nil {
+		// Corrupted parts of split files surface as decompression errors before
+		// being fully read, report the offending part instead
+		if indexFile, _ := FindSplitIndex(filename); indexFile != "" {
+			if verifyErr := VerifySplitFile(indexFile); verifyErr != nil {
+				return verifyErr
+			}
+		}
+	}
+	return err
+}
+
+func walkTar(ctx context.Context, fh io.Reader, operation func(tr *tar.Reader, header *tar.Header) error) error {
+	r, err := newDecompressor(fh)
 	if err != nil {
-		return err
+		return &archiveError{err: err}
 	}
-	defer gzr.Close()
+	defer r.Close()
 
-	tr := tar.NewReader(gzr)
+	// Errors reading the entries data from the operations are archive errors too
+	tr := tar.NewReader(archiveReader{r})
 Loop:
 	for {
 		select {
@@ -233,10 +545,14 @@ Loop:
 			f, err := tr.Next()
 
 			if err == io.EOF {
+				// Consume any trailing padding so the whole file is read (and verified, if split)
+				if _, err := io.Copy(io.Discard, fh); err != nil {
+					return &archiveError{err: fmt.Errorf("failed to read tar file: %w", err)}
+				}
 				break Loop
 			}
 			if err != nil {
-				return fmt.Errorf("failed to read tar file: %w", err)
+				return &archiveError{err: fmt.Errorf("failed to read tar file: %w", err)}
 			}
 			if err := operation(tr, f); err != nil {
 				if err == ErrEndTarWalk {
@@ -249,7 +565,8 @@ Loop:
 	return nil
 }
 
-// IsTarFile checks if the specified filename is a tar.gz file
+// IsTarFile checks if the specified filename is a tar file, either uncompressed or
+// compressed using any of the supported compression formats
 func IsTarFile(filename string) (bool, error) {
 	fi, err := os.Stat(filename)
 	if err != nil {
@@ -258,15 +575,13 @@ func IsTarFile(filename string) (bool, error) {
 	if fi.Mode().IsDir() {
 		return false, nil
 	}
-	fh, err := os.Open(filename)
+	fh, err := openArchive(filename)
 	if err != nil {
 		return false, fmt.Errorf("fail to open file: %w", err)
 	}
 	defer fh.Close()
-	gzr, err := gzip.NewReader(fh)
-	if err != nil {
+	if _, err := detectCompression(bufio.NewReader(fh)); err != nil {
 		return false, nil
 	}
-	defer gzr.Close()
 	return true, nil
 }
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarCompression(t *testing.T) {
+	sourceDir, err := sb.Mkdir(sb.TempFile(), 0755)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "chart"), 0755))
+	_, err = sb.Write(filepath.Join(sourceDir, "chart/Images.lock"), "sample lock")
+	require.NoError(t, err)
+
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionNone} {
+		t.Run(string(compression), func(t *testing.T) {
+			tarFile := filepath.Join(sb.TempFile(), "sample"+compression.Extension())
+			require.NoError(t, Tar(sourceDir, tarFile, TarConfig{Prefix: "sample", Compression: compression}))
+
+			isTar, err := IsTarFile(tarFile)
+			require.NoError(t, err)
+			assert.True(t, isTar)
+
+			got, err := DetectCompression(tarFile)
+			require.NoError(t, err)
+			assert.Equal(t, compression, got)
+
+			destDir := sb.TempFile()
+			require.NoError(t, Untar(tarFile, destDir, TarConfig{StripComponents: 1}))
+			assert.FileExists(t, filepath.Join(destDir, "chart/Images.lock"))
+
+			var data []byte
+			require.NoError(t, FindFileInTar(context.Background(), tarFile, "Images.lock", func(tr *tar.Reader) error {
+				data, err = io.ReadAll(tr)
+				return err
+			}, TarConfig{StripComponents: 2}))
+			assert.Equal(t, "sample lock", string(data))
+		})
+	}
+	t.Run("Rejects invalid compression settings", func(t *testing.T) {
+		for _, tc := range []struct {
+			compression Compression
+			level       int
+			expectedErr string
+		}{
+			{compression: "lz4", expectedErr: `unsupported compression "lz4"`},
+			{compression: CompressionGzip, level: 10, expectedErr: "invalid gzip compression level 10"},
+			{compression: CompressionZstd, level: 23, expectedErr: "invalid zstd compression level 23"},
+			{compression: CompressionNone, level: 1, expectedErr: "compression level cannot be set"},
+		} {
+			tarFile := sb.TempFile()
+			err := Tar(sourceDir, tarFile, TarConfig{Compression: tc.compression, CompressionLevel: tc.level})
+			require.ErrorContains(t, err, tc.expectedErr)
+			assert.NoFileExists(t, tarFile)
+		}
+	})
+	t.Run("Does not detect regular files as tar files", func(t *testing.T) {
+		f, err := sb.Write(sb.TempFile(), "not a tar file")
+		require.NoError(t, err)
+		isTar, err := IsTarFile(f)
+		require.NoError(t, err)
+		assert.False(t, isTar)
+	})
+}
@@ -12,6 +12,13 @@ type Lockable interface {
 	GetImagesLock() (*imagelock.ImagesLock, error)
 }
 
+// Bundle defines the contents shared by all the kinds of wraps
+type Bundle interface {
+	Lockable
+	RootDir() string
+	ImageArtifactsDir() string
+}
+
 // Wrap defines the interface to implement a Helm chart wrap
 type Wrap interface {
 	Lockable
@@ -30,3 +37,16 @@ type WrapContainer interface {
 	RootDir() string
 	ImageArtifactsDir() string
 }
+
+// MultiWrap defines the interface to implement a wrap bundling several Helm charts
+// sharing a single images directory
+type MultiWrap interface {
+	Lockable
+
+	RootDir() string
+	ChartsDir() string
+	ImageArtifactsDir() string
+	Charts() []Wrap
+	AddChart(chartSrc string, opts ...chartutils.Option) (Wrap, error)
+	WriteImagesLock(name, version string) error
+}
@@ -0,0 +1,168 @@
+package wrapping
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/artifacts"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/chartutils"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/utils"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// multiWrap defines a wrap bundling several charts that share a single images directory
+type multiWrap struct {
+	rootDir string
+	charts  []Wrap
+}
+
+// RootDir returns the path to the Wrap root directory
+func (w *multiWrap) RootDir() string {
+	return w.rootDir
+}
+
+// ChartsDir returns the directory containing the wrapped charts
+func (w *multiWrap) ChartsDir() string {
+	return filepath.Join(w.rootDir, "charts")
+}
+
+// LockFilePath returns the absolute path to the aggregate Images.lock
+func (w *multiWrap) LockFilePath() string {
+	return filepath.Join(w.rootDir, imagelock.DefaultImagesLockFileName)
+}
+
+// ImagesDir returns the images directory shared by all the charts
+func (w *multiWrap) ImagesDir() string {
+	return filepath.Join(w.rootDir, "images")
+}
+
+// ImageArtifactsDir returns the images artifacts directory
+func (w *multiWrap) ImageArtifactsDir() string {
+	return filepath.Join(w.rootDir, artifacts.ArtifactsFolder, "images")
+}
+
+// GetImagesLock returns the aggregate ImagesLock object
+func (w *multiWrap) GetImagesLock() (*imagelock.ImagesLock, error) {
+	return imagelock.FromYAMLFile(w.LockFilePath())
+}
+
+// Charts returns the wrapped charts
+func (w *multiWrap) Charts() []Wrap {
+	return w.charts
+}
+
+// AddChart copies the Helm chart at chartSrc into the wrap
+func (w *multiWrap) AddChart(chartSrc string, opts ...chartutils.Option) (Wrap, error) {
+	c, err := loader.Load(chartSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Helm chart: %v", err)
+	}
+	chartDir := filepath.Join(w.ChartsDir(), c.Name())
+	if utils.FileExists(chartDir) {
+		return nil, fmt.Errorf("chart %q is already included in the wrap", c.Name())
+	}
+	if err := utils.CopyDir(chartSrc, chartDir); err != nil {
+		return nil, fmt.Errorf("failed to copy source chart: %w", err)
+	}
+	chart, err := chartutils.LoadChart(chartDir, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Helm chart: %w", err)
+	}
+	wrap := &wrap{rootDir: w.rootDir, chart: chart}
+	w.charts = append(w.charts, wrap)
+	return wrap, nil
+}
+
+// WriteImagesLock writes the aggregate Images.lock, merging the locks of all the wrapped charts.
+// Images referenced by several charts are only included once, with the digests of all their platforms
+func (w *multiWrap) WriteImagesLock(name, version string) error {
+	lock := imagelock.NewImagesLock()
+	lock.Chart.Name = name
+	lock.Chart.Version = version
+
+	seen := make(map[string]*imagelock.ChartImage)
+	for _, chartWrap := range w.charts {
+		chartLock, err := chartWrap.GetImagesLock()
+		if err != nil {
+			return fmt.Errorf("failed to load Images.lock of chart %q: %w", chartWrap.Chart().Name(), err)
+		}
+		for _, img := range chartLock.Images {
+			existing, found := seen[img.Image]
+			if !found {
+				seen[img.Image] = img
+				lock.Images = append(lock.Images, img)
+				continue
+			}
+			if err := mergeDigests(existing, img); err != nil {
+				return err
+			}
+		}
+	}
+
+	fh, err := os.Create(w.LockFilePath())
+	if err != nil {
+		return fmt.Errorf("failed to write Images.lock: %w", err)
+	}
+	defer fh.Close()
+	return lock.ToYAML(fh)
+}
+
+// mergeDigests adds to img the digests of other, an entry for the same image in another chart,
+// for the platforms img does not include yet
+func mergeDigests(img, other *imagelock.ChartImage) error {
+	for _, dgst := range other.Digests {
+		idx := slices.IndexFunc(img.Digests, func(d imagelock.DigestInfo) bool { return d.Arch == dgst.Arch })
+		if idx < 0 {
+			img.Digests = append(img.Digests, dgst)
+			continue
+		}
+		if current := img.Digests[idx]; current.Digest != dgst.Digest {
+			return fmt.Errorf("image %q is locked to different %s digests by charts %q (%s) and %q (%s)",
+				img.Image, dgst.Arch, img.Chart, current.Digest, other.Chart, dgst.Digest)
+		}
+	}
+	return nil
+}
+
+// IsMultiWrap returns true if dir contains a wrap bundling several charts
+func IsMultiWrap(dir string) bool {
+	return utils.FileExists(filepath.Join(dir, "charts")) && !utils.FileExists(filepath.Join(dir, "chart"))
+}
+
+// LoadMulti loads a directory containing several wrapped charts and returns a MultiWrap
+func LoadMulti(dir string, opts ...chartutils.Option) (MultiWrap, error) {
+	w := &multiWrap{rootDir: dir}
+	entries, err := os.ReadDir(w.ChartsDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wrapped charts: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		chart, err := chartutils.LoadChart(filepath.Join(w.ChartsDir(), entry.Name()), opts...)
+		if err != nil {
+			return nil, err
+		}
+		w.charts = append(w.charts, &wrap{rootDir: dir, chart: chart})
+	}
+	if len(w.charts) == 0 {
+		return nil, fmt.Errorf("the wrap does not contain any Helm chart")
+	}
+	return w, nil
+}
+
+// CreateMulti creates a new empty multi-chart wrap directory structure at destDir and returns a MultiWrap
+func CreateMulti(destDir string) (MultiWrap, error) {
+	w := &multiWrap{rootDir: destDir}
+	if err := os.MkdirAll(w.ChartsDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create wrap root directory: %w", err)
+	}
+	return w, nil
+}
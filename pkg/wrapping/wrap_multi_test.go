@@ -0,0 +1,136 @@
+package wrapping
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tu "github.com/vmware-labs/distribution-tooling-for-helm/internal/testutil"
+	"github.com/vmware-labs/distribution-tooling-for-helm/pkg/imagelock"
+)
+
+// newNamedChart renders a chart named name with an Images.lock listing images
+func newNamedChart(t *testing.T, name string, images ...string) string {
+	t.Helper()
+	digests := []imagelock.DigestInfo{
+		{Arch: "linux/amd64", Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+	}
+	lockedImages := make(map[string][]imagelock.DigestInfo)
+	for _, img := range images {
+		lockedImages[img] = digests
+	}
+	return newLockedChart(t, name, lockedImages)
+}
+
+// newLockedChart renders a chart named name with an Images.lock locking each image to its digests
+func newLockedChart(t *testing.T, name string, images map[string][]imagelock.DigestInfo) string {
+	t.Helper()
+	chartDir := sb.TempFile()
+	require.NoError(t, tu.RenderScenario("../../testdata/scenarios/plain-chart", chartDir, map[string]interface{}{}))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("name: "+name+"\nversion: 1.0.0\n"), 0644))
+
+	il := imagelock.NewImagesLock()
+	il.Chart.Name = name
+	for img, digests := range images {
+		il.Images = append(il.Images, &imagelock.ChartImage{
+			Name:    filepath.Base(img),
+			Image:   img,
+			Chart:   name,
+			Digests: digests,
+		})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, il.ToYAML(&buf))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, imagelock.DefaultImagesLockFileName), buf.Bytes(), 0644))
+	return chartDir
+}
+
+func TestMultiWrap(t *testing.T) {
+	t.Run("Bundles several charts sharing their images", func(t *testing.T) {
+		destDir := sb.TempFile()
+		w, err := CreateMulti(destDir)
+		require.NoError(t, err)
+
+		for name, images := range map[string][]string{
+			"wordpress": {"registry.io/bitnami/wordpress:6.0", "registry.io/bitnami/mariadb:11"},
+			"mariadb":   {"registry.io/bitnami/mariadb:11"},
+		} {
+			chartWrap, err := w.AddChart(newNamedChart(t, name, images...))
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(destDir, "charts", name), chartWrap.ChartDir())
+			assert.Equal(t, w.ImagesDir(), chartWrap.ImagesDir())
+		}
+		require.NoError(t, w.WriteImagesLock("platform", "1.0.0"))
+		assert.True(t, IsMultiWrap(destDir))
+
+		loaded, err := LoadMulti(destDir)
+		require.NoError(t, err)
+		require.Len(t, loaded.Charts(), 2)
+		assert.Equal(t, "mariadb", loaded.Charts()[0].Chart().Name())
+		assert.Equal(t, "wordpress", loaded.Charts()[1].Chart().Name())
+
+		lock, err := loaded.GetImagesLock()
+		require.NoError(t, err)
+		assert.Equal(t, "platform", lock.Chart.Name)
+		assert.Equal(t, "1.0.0", lock.Chart.Version)
+		assert.Len(t, lock.Images, 2)
+	})
+
+	t.Run("Merges the digests of images shared by several charts", func(t *testing.T) {
+		image := "registry.io/bitnami/mariadb:11"
+		amd64 := imagelock.DigestInfo{Arch: "linux/amd64", Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"}
+		arm64 := imagelock.DigestInfo{Arch: "linux/arm64", Digest: "sha256:2222222222222222222222222222222222222222222222222222222222222222"}
+
+		w, err := CreateMulti(sb.TempFile())
+		require.NoError(t, err)
+		_, err = w.AddChart(newLockedChart(t, "mariadb", map[string][]imagelock.DigestInfo{image: {amd64}}))
+		require.NoError(t, err)
+		_, err = w.AddChart(newLockedChart(t, "wordpress", map[string][]imagelock.DigestInfo{image: {amd64, arm64}}))
+		require.NoError(t, err)
+		require.NoError(t, w.WriteImagesLock("platform", "1.0.0"))
+
+		lock, err := w.GetImagesLock()
+		require.NoError(t, err)
+		require.Len(t, lock.Images, 1)
+		assert.Equal(t, image, lock.Images[0].Image)
+		assert.Equal(t, []imagelock.DigestInfo{amd64, arm64}, lock.Images[0].Digests)
+	})
+
+	t.Run("Fails when charts lock an image to different digests", func(t *testing.T) {
+		image := "registry.io/bitnami/mariadb:11"
+		w, err := CreateMulti(sb.TempFile())
+		require.NoError(t, err)
+		_, err = w.AddChart(newLockedChart(t, "mariadb", map[string][]imagelock.DigestInfo{image: {
+			{Arch: "linux/amd64", Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+		}}))
+		require.NoError(t, err)
+		_, err = w.AddChart(newLockedChart(t, "wordpress", map[string][]imagelock.DigestInfo{image: {
+			{Arch: "linux/amd64", Digest: "sha256:3333333333333333333333333333333333333333333333333333333333333333"},
+		}}))
+		require.NoError(t, err)
+		require.ErrorContains(t, w.WriteImagesLock("platform", "1.0.0"),
+			`image "registry.io/bitnami/mariadb:11" is locked to different linux/amd64 digests by charts "mariadb"`)
+	})
+
+	t.Run("Fails when the same chart is added twice", func(t *testing.T) {
+		w, err := CreateMulti(sb.TempFile())
+		require.NoError(t, err)
+		_, err = w.AddChart(newNamedChart(t, "wordpress"))
+		require.NoError(t, err)
+		_, err = w.AddChart(newNamedChart(t, "wordpress"))
+		require.ErrorContains(t, err, `chart "wordpress" is already included in the wrap`)
+	})
+
+	t.Run("Single chart wraps are not multi-chart wraps", func(t *testing.T) {
+		destDir := sb.TempFile()
+		_, err := Create(newPlainChart(t), destDir)
+		require.NoError(t, err)
+		assert.False(t, IsMultiWrap(destDir))
+
+		_, err = LoadMulti(destDir)
+		require.Error(t, err)
+	})
+}